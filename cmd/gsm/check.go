@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+	"github.com/NumeXx/gsm/pkg/uptime"
+)
+
+var (
+	checkSelector connSelector
+	checkParallel int
+	checkTimeout  time.Duration
+	checkJSON     bool
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [name...] [--tag t | --name pattern | --all]",
+	Short: "Check whether connections' listeners are online",
+	Long: `Probe connections with a short, non-interactive connect and report
+whether each listener is online, has no listener, can't reach the relay or
+timed out. Probes run concurrently. The result and time of the last probe are
+stored on each connection.
+
+gsm exits 0 only if every probed listener is online.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && checkSelector.isEmpty() {
+			fmt.Fprintf(os.Stderr, "%s%sError: name a connection or select some with --tag, --name, --query, --collection or --all.%s\n", ColorBold, ColorRed, ColorReset)
+			cmd.Usage() //nolint:errcheck
+			os.Exit(1)
+		}
+
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		cfg := config.GetCurrent()
+
+		var conns []config.Connection
+		for _, name := range args {
+			idx := config.IndexOfConnection(name)
+			if idx == -1 {
+				fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' not found.%s\n", ColorBold, ColorRed, name, ColorReset)
+				os.Exit(1)
+			}
+			conns = append(conns, cfg.Connections[idx])
+		}
+		if !checkSelector.isEmpty() {
+			selected, err := checkSelector.match(cfg.Connections)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			conns = appendUnique(conns, selected)
+		}
+		if len(conns) == 0 {
+			fmt.Fprintf(os.Stderr, "%s[ INFO ]%s No connections match %s.\n", ColorCyan, ColorReset, checkSelector.describe())
+			os.Exit(1)
+		}
+
+		results := runner.ProbeAll(context.Background(), sessionBackend, conns, checkParallel, checkTimeout)
+		if err := storeProbeResults(results); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError saving probe results: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+		}
+
+		if checkJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError encoding JSON: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSTATUS\tLATENCY\tDETAIL")
+			for _, res := range results {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Connection, res.Status, res.Latency.Round(time.Millisecond), res.Detail)
+			}
+			tw.Flush()
+		}
+
+		for _, res := range results {
+			if res.Status != config.ProbeOnline {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// storeProbeResults saves each result as LastProbe of its connection and
+// adds it to the connection's uptime history.
+func storeProbeResults(results []runner.ProbeResult) error {
+	if err := config.Load(); err != nil {
+		return err
+	}
+	for _, res := range results {
+		config.SetLastProbe(res.Connection, res.Record()) // Skips connections deleted while probing.
+	}
+	if err := config.Save(); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if err := uptime.Record(res.Connection, res.Record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendUnique appends the connections of more not already in conns.
+func appendUnique(conns, more []config.Connection) []config.Connection {
+	seen := map[string]bool{}
+	for _, c := range conns {
+		seen[c.Name] = true
+	}
+	for _, c := range more {
+		if !seen[c.Name] {
+			seen[c.Name] = true
+			conns = append(conns, c)
+		}
+	}
+	return conns
+}
+
+func init() {
+	checkSelector.addFlags(checkCmd)
+	checkCmd.Flags().IntVarP(&checkParallel, "parallel", "p", 8, "Maximum number of probes to run at once")
+	checkCmd.Flags().DurationVarP(&checkTimeout, "timeout", "t", runner.DefaultProbeTimeout, "Per-connection probe timeout")
+	checkCmd.Flags().BoolVar(&checkJSON, "json", false, "Print results as JSON")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+	"github.com/NumeXx/gsm/pkg/tui"
+)
+
+// sessionBackend runs every session started from the CLI. Tests swap it for
+// a runner.FakeBackend.
+var sessionBackend runner.Backend = runner.DefaultBackend
+
+// specFunc builds the session spec for a chosen connection.
+type specFunc func(conn config.Connection) runner.Spec
+
+// sessionConnector opens the sessions picked in the TUI on backend.
+type sessionConnector struct {
+	backend runner.Backend
+	newSpec specFunc
+}
+
+func (c sessionConnector) Connect(conn config.Connection, background bool, stdin io.Reader, stdout, stderr io.Writer) error {
+	if background {
+		return openBackgroundSession(conn)
+	}
+	spec := c.newSpec(conn)
+	spec.Stdin, spec.Stdout, spec.Stderr = stdin, stdout, stderr
+	return connectAndRecord(c.backend, spec)
+}
+
+// runTUI shows the TUI until the user quits. Sessions run from within it on
+// backend, and it resumes where it was when they end.
+func runTUI(backend runner.Backend, newSpec specFunc) error {
+	tui.ProbeBackend = backend
+	tui.Tunnels = tunnelControl{}
+	tui.Sessions = sessionStarter{}
+	tui.Connector = sessionConnector{backend: backend, newSpec: newSpec}
+	p := tea.NewProgram(tui.NewModel(config.GetCurrent()), tea.WithOutput(tui.Terminal))
+	final, err := p.Run()
+	if m, ok := final.(tui.Model); ok {
+		m.Cleanup()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Exiting GSM. Thanks for using! See you, bro! 👋")
+	return nil
+}
+
+// openBackgroundSession attaches to the latest live session of conn, starting
+// one in the session daemon first if there is none.
+func openBackgroundSession(conn config.Connection) error {
+	id, err := resolveSession(conn.Name)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		if id, err = startBackgroundSession(conn); err != nil {
+			return err
+		}
+	}
+	return attachSession(id, conn.Name)
+}
+
+// recordUsage bumps Usage and LastConnected for the connections and saves the config.
+func recordUsage(conns ...config.Connection) {
+	now := time.Now()
+	recorded := false
+	for _, conn := range conns {
+		if !config.RecordUsage(conn.Name, now) {
+			log.Printf("Warning: Could not find connection '%s' in config to update LastConnected/Usage time.", conn.Name)
+			continue
+		}
+		recorded = true
+	}
+	if !recorded {
+		return
+	}
+	if err := config.Save(); err != nil {
+		log.Printf("Error saving config after updating LastConnected/Usage: %v", err)
+	}
+}
+
+// connectAndRecord bumps Usage and LastConnected for the connection in spec,
+// saves the config and then runs the session on backend.
+func connectAndRecord(backend runner.Backend, spec runner.Spec) error {
+	selectedConnDetails := spec.Connection
+
+	recordUsage(selectedConnDetails)
+
+	if config.GetCurrent().RecordingEnabled(selectedConnDetails) {
+		rec, recPath, errRec := startRecording(selectedConnDetails)
+		if errRec != nil {
+			log.Printf("Error starting session recording for %s: %v", selectedConnDetails.Name, errRec)
+		} else {
+			spec.Tap = rec
+			defer func() {
+				if errClose := rec.Close(); errClose != nil {
+					log.Printf("Error finishing session recording %s: %v", recPath, errClose)
+					return
+				}
+				fmt.Fprintf(spec.Stdout, "[*] Session recorded to %s\n", recPath)
+			}()
+		}
+	}
+
+	var err error
+	if policy := selectedConnDetails.Reconnect; policy != nil {
+		err = runner.ExecuteWithReconnect(backend, spec, *policy, nil)
+	} else {
+		err = runner.ExecuteWith(backend, spec)
+	}
+	if err != nil {
+		keyPreview := selectedConnDetails.Key
+		if len(keyPreview) > 8 {
+			keyPreview = keyPreview[:8]
+		}
+		fmt.Fprintf(spec.Stderr, "Session for %s (%s...) ended with error: %v\n", selectedConnDetails.Name, keyPreview, err)
+	}
+	return err
+}
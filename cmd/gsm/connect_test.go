@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/recording"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+// testConnector returns a sessionConnector running sessions on backend
+// without the hooks of the configuration.
+func testConnector(backend runner.Backend) sessionConnector {
+	return sessionConnector{backend: backend, newSpec: func(conn config.Connection) runner.Spec {
+		return runner.Spec{Connection: conn, Args: runner.ClientArgs(conn)}
+	}}
+}
+
+func TestConnectRecordsUsage(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"}, config.Connection{Name: "web-2", Key: "k2"})
+	backend := runner.NewFakeBackend(func(spec runner.Spec, _ <-chan os.Signal) int {
+		io.Copy(spec.Stdout, spec.Stdin) //nolint:errcheck
+		return 0
+	})
+
+	var out bytes.Buffer
+	err := testConnector(backend).Connect(config.GetCurrent().Connections[1], false, strings.NewReader("id\n"), &out, &out)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if specs := backend.Specs(); len(specs) != 1 || specs[0].Connection.Name != "web-2" {
+		t.Fatalf("backend got specs %+v, want one for web-2", specs)
+	}
+	if !strings.Contains(out.String(), "id\n") {
+		t.Errorf("session output %q does not contain the echoed input", out.String())
+	}
+
+	// The usage must have been saved, not only changed in memory.
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	conn := config.GetCurrent().Connections[1]
+	if conn.Usage != 1 || conn.LastConnected == nil || time.Since(*conn.LastConnected) > time.Minute {
+		t.Errorf("web-2 after connecting = usage %d, last connected %v", conn.Usage, conn.LastConnected)
+	}
+	if other := config.GetCurrent().Connections[0]; other.Usage != 0 {
+		t.Errorf("web-1 usage = %d, want it untouched", other.Usage)
+	}
+}
+
+func TestConnectReportsFailure(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "RG9DNqW4WrbiIDlrYJawxj"})
+	var out bytes.Buffer
+	err := testConnector(runner.NewFakeBackend(runner.FakeExit(255))).Connect(config.GetCurrent().Connections[0], false, strings.NewReader(""), &out, &out)
+	if runner.ExitCodeOf(err) != 255 {
+		t.Fatalf("Connect = %v, want exit status 255", err)
+	}
+	if !strings.Contains(out.String(), "Session for web-1 (RG9DNqW4...) ended with error: exit status 255") {
+		t.Errorf("output %q does not report the failure", out.String())
+	}
+}
+
+func TestConnectReconnects(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1", Reconnect: &config.ReconnectPolicy{
+		MaxAttempts:  2,
+		InitialDelay: config.Duration(time.Millisecond),
+		MaxDelay:     config.Duration(time.Millisecond),
+	}})
+	var sessions atomic.Int32
+	backend := runner.NewFakeBackend(func(runner.Spec, <-chan os.Signal) int {
+		if sessions.Add(1) == 1 {
+			return 1
+		}
+		return 0
+	})
+	var out bytes.Buffer
+	if err := testConnector(backend).Connect(config.GetCurrent().Connections[0], false, strings.NewReader(""), &out, &out); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if n := sessions.Load(); n != 2 {
+		t.Errorf("ran %d sessions, want a dropped one and a reconnect", n)
+	}
+}
+
+func TestConnectRecordsSession(t *testing.T) {
+	enabled := true
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1", Record: &enabled})
+	backend := runner.NewFakeBackend(func(spec runner.Spec, _ <-chan os.Signal) int {
+		io.WriteString(spec.Stdout, "hello from web-1\n") //nolint:errcheck
+		return 0
+	})
+	var out bytes.Buffer
+	if err := testConnector(backend).Connect(config.GetCurrent().Connections[0], false, strings.NewReader(""), &out, &out); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(recording.Dir(), "*", "*"+recording.FileExt))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("recordings = %v, %v, want one", paths, err)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "hello from web-1") {
+		t.Errorf("recording %s does not contain the session output", paths[0])
+	}
+	if !strings.Contains(out.String(), "[*] Session recorded to "+paths[0]) {
+		t.Errorf("output %q does not name the recording", out.String())
+	}
+}
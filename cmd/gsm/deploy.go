@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/deploy"
+)
+
+var (
+	deployFormat string
+	deployOutput string
+	deployList   bool
+)
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy <name> [--format oneliner|systemd|cron|docker|rcd]",
+	Short: "Print what starts the listener of a connection on the target",
+	Long: `Render a ready-to-paste artifact that runs the listening side of a
+connection with its secret: a shell one-liner, a systemd unit, a cron entry,
+a Dockerfile or a FreeBSD rc.d script.
+
+Templates in ~/.gsm/templates/<format>.tmpl override the built-in ones and
+can add new formats. The output contains the secret key.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if deployList {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if deployList {
+			fmt.Println(strings.Join(deploy.Formats(), "\n"))
+			return
+		}
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		idx := config.IndexOfConnection(args[0])
+		if idx == -1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' not found.%s\n", ColorBold, ColorRed, args[0], ColorReset)
+			os.Exit(1)
+		}
+		conn := config.GetCurrent().Connections[idx]
+
+		var buf bytes.Buffer
+		if err := deploy.Render(&buf, deployFormat, conn); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		if deployOutput == "" {
+			os.Stdout.Write(buf.Bytes()) //nolint:errcheck
+		} else {
+			// The artifact contains the secret key.
+			if err := os.WriteFile(deployOutput, buf.Bytes(), 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError writing %s: %v%s\n", ColorBold, ColorRed, deployOutput, err, ColorReset)
+				os.Exit(1)
+			}
+			fmt.Printf("%s[ SUCCESS ]%s Wrote %s for '%s' to %s.\n", ColorGreen, ColorReset, deployFormat, conn.Name, deployOutput)
+		}
+	},
+}
+
+func init() {
+	deployCmd.Flags().StringVarP(&deployFormat, "format", "f", "oneliner", "Artifact to render: oneliner, systemd, cron, docker, rcd or a custom template")
+	deployCmd.Flags().StringVarP(&deployOutput, "output", "o", "", "Write to this file instead of stdout")
+	deployCmd.Flags().BoolVar(&deployList, "list", false, "List the available formats")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+const (
+	// exitCodeUnknown is used when the remote exit status can't be determined.
+	exitCodeUnknown = 255
+	// exitCodeTimeout matches timeout(1).
+	exitCodeTimeout = 124
+)
+
+var (
+	execTimeout time.Duration
+	execOutput  string
+	execNoStdin bool
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <name> -- <command> [args...]",
+	Short: "Run a single command on a connection's listener and print its output",
+	Long: `Run a single command non-interactively on the shell served by a
+connection's listener (gs-netcat -l -i). Remote stdout and stderr are streamed
+back separately and gsm exits with the remote command's exit status
+(255 if it can't be determined, 124 on timeout).
+
+Local stdin is forwarded to the remote command when it is not a terminal,
+e.g. 'cat script.sh | gsm exec box -- sh'. Use -n to disable this.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if dash := cmd.ArgsLenAtDash(); dash != -1 && dash != 1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: expected exactly one connection name before '--'.%s\n", ColorBold, ColorRed, ColorReset)
+			cmd.Usage() //nolint:errcheck
+			os.Exit(1)
+		}
+
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		idx := config.IndexOfConnection(args[0])
+		if idx == -1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' not found.%s\n", ColorBold, ColorRed, args[0], ColorReset)
+			os.Exit(1)
+		}
+		conn := config.GetCurrent().Connections[idx]
+
+		req := runner.ExecRequest{
+			Command: strings.Join(args[1:], " "),
+			Stdout:  os.Stdout,
+			Stderr:  os.Stderr,
+			Hooks:   config.GetCurrent().HooksFor(conn),
+		}
+		if !execNoStdin && !term.IsTerminal(os.Stdin.Fd()) {
+			req.Stdin = os.Stdin
+		}
+		var outFile *os.File
+		if execOutput != "" {
+			var err error
+			if outFile, err = os.Create(execOutput); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError creating output file '%s': %v%s\n", ColorBold, ColorRed, execOutput, err, ColorReset)
+				os.Exit(1)
+			}
+			req.Stdout = outFile
+		}
+
+		code := runExec(sessionBackend, conn, req, execTimeout)
+		if outFile != nil {
+			if err := outFile.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError writing output file '%s': %v%s\n", ColorBold, ColorRed, execOutput, err, ColorReset)
+				os.Exit(exitCodeUnknown)
+			}
+		}
+		os.Exit(code)
+	},
+}
+
+// runExec runs req on conn and returns the exit code gsm should exit with.
+func runExec(backend runner.Backend, conn config.Connection, req runner.ExecRequest, timeout time.Duration) int {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	recordUsage(conn)
+	res, err := runner.RemoteExec(ctx, backend, conn, req)
+	if errors.Is(err, runner.ErrExecTimeout) {
+		fmt.Fprintf(os.Stderr, "%s%sError: command on '%s' timed out after %v.%s\n", ColorBold, ColorRed, conn.Name, timeout, ColorReset)
+		return exitCodeTimeout
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%sError running command on '%s': %v%s\n", ColorBold, ColorRed, conn.Name, err, ColorReset)
+		return exitCodeUnknown
+	}
+	if !res.ExitKnown {
+		return exitCodeUnknown
+	}
+	return res.ExitCode
+}
+
+func init() {
+	execCmd.Flags().DurationVarP(&execTimeout, "timeout", "t", 0, "Abort the command after this long (e.g. 30s, 0 for no timeout)")
+	execCmd.Flags().StringVarP(&execOutput, "output", "o", "", "Write the command's stdout to this file instead of the terminal")
+	execCmd.Flags().BoolVarP(&execNoStdin, "no-stdin", "n", false, "Do not forward local stdin to the remote command")
+}
@@ -34,13 +34,14 @@ var importCmd = &cobra.Command{
 	Short: "Import GSocket connections from a key or file",
 	Long: `Import GSocket connections either from a single secret key 
 provided via --secret flag (format: KEY[#tag1,tag2]), or from a text file 
-containing a list of secret keys (one per line, format: KEY[#tag1,tag2] [optional comments]).
+containing a list of secret keys (one per line, format: KEY[#tag1,tag2] [name=NAME] [optional comments]).
 
 Lines in the file not starting with an alphanumeric character (A-Z, a-z, 0-9) will be skipped.
 For lines starting with an alphanumeric character, only the characters up to the first space or tab
-will be considered as the KEY[#tag] part. Anything after the first space/tab is ignored.
+will be considered as the KEY[#tag] part. It can be followed by name=NAME (double-quote a NAME
+with spaces); anything else after it is ignored. Files exported from the TUI use this format.
 
-If a name is not implicitly provided, a mnemonic name will be automatically 
+If a name is not provided, a mnemonic name will be automatically
 generated based on the secret key. Tags are optional.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if secretKeyForImport != "" && filePathForImport != "" {
@@ -107,7 +108,8 @@ generated based on the secret key. Tags are optional.`,
 			defer file.Close()
 
 			scanner := bufio.NewScanner(file)
-			linesToProcess := []string{}
+			type keyLine struct{ keyAndTags, name string }
+			linesToProcess := []keyLine{}
 			lineNum := 0
 			for scanner.Scan() {
 				lineNum++
@@ -123,19 +125,13 @@ generated based on the secret key. Tags are optional.`,
 					continue
 				}
 
-				keyCandidateWithPotentialTag := trimmedLine
-				endOfKeyIndex := strings.IndexFunc(trimmedLine, func(r rune) bool {
-					return r == ' ' || r == '\t'
-				})
-				if endOfKeyIndex != -1 {
-					keyCandidateWithPotentialTag = trimmedLine[:endOfKeyIndex]
-				}
+				keyCandidateWithPotentialTag, name := utils.ParseKeyLine(trimmedLine)
 
 				if keyCandidateWithPotentialTag == "" {
 					fmt.Fprintf(os.Stdout, "%s[ SKIPPED ]%s Line %d became empty after isolating key part: \"%s\"%s\n", ColorYellow, ColorReset, lineNum, короткий(originalLine, 30), ColorReset)
 					continue
 				}
-				linesToProcess = append(linesToProcess, keyCandidateWithPotentialTag)
+				linesToProcess = append(linesToProcess, keyLine{keyCandidateWithPotentialTag, name})
 			}
 			if err := scanner.Err(); err != nil {
 				fmt.Fprintf(os.Stderr, "%s%sError reading file '%s': %v%s\n", ColorBold, ColorRed, filePathForImport, err, ColorReset)
@@ -152,7 +148,8 @@ generated based on the secret key. Tags are optional.`,
 				existingGeneratedNames[existingConn.Name] = true
 			}
 
-			for i, keyCandidate := range linesToProcess {
+			for i, line := range linesToProcess {
+				keyCandidate := line.keyAndTags
 				actualKey, parsedTags := parseKeyAndTags(keyCandidate)
 
 				if actualKey == "" {
@@ -166,15 +163,22 @@ generated based on the secret key. Tags are optional.`,
 				}
 				uniqueKeysInBatch[actualKey] = true
 
-				mnemonicName, err := utils.GenerateMnemonic(actualKey, numWordsForMnemonic, dictionary)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "%s%sError generating mnemonic for key '%s...': %v. Skipping.%s\n", ColorBold, ColorRed, actualKey[:min(len(actualKey), 8)], err, ColorReset)
-					continue
-				}
-
-				if existingGeneratedNames[mnemonicName] {
-					fmt.Fprintf(os.Stderr, "%s%sError: Auto-generated name '%s' (for key '%s...') already exists. Skipping.%s\n", ColorBold, ColorRed, mnemonicName, actualKey[:min(len(actualKey), 8)], ColorReset)
-					continue
+				mnemonicName := line.name
+				if mnemonicName != "" {
+					if existingGeneratedNames[mnemonicName] {
+						fmt.Fprintf(os.Stderr, "%s%sError: Name '%s' (for key '%s...') already exists. Skipping.%s\n", ColorBold, ColorRed, mnemonicName, actualKey[:min(len(actualKey), 8)], ColorReset)
+						continue
+					}
+				} else {
+					mnemonicName, err = utils.GenerateMnemonic(actualKey, numWordsForMnemonic, dictionary)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "%s%sError generating mnemonic for key '%s...': %v. Skipping.%s\n", ColorBold, ColorRed, actualKey[:min(len(actualKey), 8)], err, ColorReset)
+						continue
+					}
+					if existingGeneratedNames[mnemonicName] {
+						fmt.Fprintf(os.Stderr, "%s%sError: Auto-generated name '%s' (for key '%s...') already exists. Skipping.%s\n", ColorBold, ColorRed, mnemonicName, actualKey[:min(len(actualKey), 8)], ColorReset)
+						continue
+					}
 				}
 				connectionsToAdd = append(connectionsToAdd, config.Connection{Name: mnemonicName, Key: actualKey, Tags: parsedTags, Usage: 0})
 				existingGeneratedNames[mnemonicName] = true
@@ -199,7 +203,7 @@ generated based on the secret key. Tags are optional.`,
 
 func init() {
 	importCmd.Flags().StringVarP(&secretKeyForImport, "secret", "s", "", "Single GSocket secret key to import (format: KEY[#tag1,tag2])")
-	importCmd.Flags().StringVarP(&filePathForImport, "file", "f", "", "Path to a file with GSocket secret keys (one per line, format: KEY[#tag1,tag2] [optional comments])")
+	importCmd.Flags().StringVarP(&filePathForImport, "file", "f", "", "Path to a file with GSocket secret keys (one per line, format: KEY[#tag1,tag2] [name=NAME] [optional comments])")
 	// rootCmd.AddCommand(importCmd) // This should be done in the main/root command setup
 }
 
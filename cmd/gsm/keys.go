@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/tui"
+)
+
+// applyKeymap sets up the TUI keys from the preset and overrides in the
+// config.
+func applyKeymap() error {
+	settings := config.GetCurrent().Settings
+	if err := tui.SetKeymap(settings.Keymap, settings.Keys); err != nil {
+		return fmt.Errorf("invalid key bindings in config: %w", err)
+	}
+	return nil
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Show the TUI key bindings",
+	Long: `Show the key bindings of the TUI after applying the key preset and the
+overrides from the config, and check them for conflicts.
+
+The preset is "keymap" in the settings: default, vim (ctrl+d/ctrl+u paging,
+v to select) or emacs (ctrl+n/ctrl+p, ctrl+v/alt+v, ctrl+g to cancel).
+"keys" rebinds actions on top of it; an empty list unbinds one:
+
+  "settings": {
+    "keymap": "vim",
+    "keys": {"delete": ["D"], "quit": ["q", "ctrl+q"], "export": []}
+  }
+
+Key names are as bubbletea reports them ("a", "A", "ctrl+x", "alt+v", "enter",
+"esc", "tab", "up", "pgdown", ...), plus "space". ctrl+c always quits.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		if err := applyKeymap(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ACTION\tKEYS\tSCOPE\tDESCRIPTION")
+		for _, k := range tui.Keys() {
+			keyNames := strings.Join(k.Keys, " ")
+			if keyNames == "" {
+				keyNames = "(unbound)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.Action, keyNames, k.Scope, k.Desc)
+		}
+		tw.Flush()
+	},
+}
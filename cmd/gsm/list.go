@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/query"
+)
+
+var (
+	listJSON        bool
+	listCollection  string
+	listCollections bool
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list [query]",
+	Short: "List connections, optionally filtered by a query",
+	Long: `List the configured connections. The optional query filters them; every
+term has to match, and "or" separates alternatives:
+
+  gsm list tag:prod usage>5 seen<7d
+  gsm list 'tag:prod seen>30d'          # prod boxes not used in 30 days
+  gsm list -- -tag:lab owner=alice or group:ops  # '--' before a leading '-'
+
+Fields: name, group and owner (":" contains or glob, "=", "!="), tag (":" has
+the tag, "!=" lacks it), status (online, no-listener, relay-unreachable,
+timeout, error, unknown), usage (= != < <= > >=) and seen and created (age,
+e.g. seen<7d or seen:never). A leading "-" or "not" negates a term; a bare
+word matches the name, group or tags. The same syntax works in the TUI after
+typing ':' and in --query of check and run.
+
+Queries used often can be saved as collections in the TUI query bar (ctrl+s)
+and used here with --collection, combined with the query argument if given,
+or in check and run with --collection. --collections lists them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		q, err := query.Parse(strings.Join(args, " "))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: invalid query: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		cfg := config.GetCurrent()
+		now := time.Now()
+		if listCollections {
+			printCollections(cfg, now)
+			return
+		}
+		conns := q.Filter(cfg.Connections, now)
+		if listCollection != "" {
+			col, ok := cfg.FindCollection(listCollection)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "%s%sError: unknown collection '%s'%s\n", ColorBold, ColorRed, listCollection, ColorReset)
+				os.Exit(1)
+			}
+			colQuery, err := query.Parse(col.Query)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError: invalid query in collection '%s': %v%s\n", ColorBold, ColorRed, col.Name, err, ColorReset)
+				os.Exit(1)
+			}
+			conns = colQuery.Filter(conns, now)
+		}
+
+		if listJSON {
+			// Keys stay out of listings; use the config file or gsm deploy for them.
+			for i := range conns {
+				conns[i].Key = ""
+				conns[i].PendingKey = ""
+			}
+			if conns == nil {
+				conns = []config.Connection{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(conns); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError encoding JSON: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(conns) == 0 {
+			fmt.Printf("%s[ INFO ]%s No connections match.\n", ColorCyan, ColorReset)
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tGROUP\tTAGS\tOWNER\tUSAGE\tLAST SEEN\tSTATUS")
+		for _, c := range conns {
+			seen, status := "never", "unknown"
+			if c.LastConnected != nil {
+				seen = c.LastConnected.Local().Format("2006-01-02 15:04")
+			}
+			if c.LastProbe != nil {
+				status = string(c.LastProbe.Status)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", c.Name, dashIfEmpty(c.Group), dashIfEmpty(strings.Join(c.Tags, ",")), dashIfEmpty(c.Owner), c.Usage, seen, status)
+		}
+		tw.Flush()
+	},
+}
+
+// printCollections lists the saved collections with the number of
+// connections in each.
+func printCollections(cfg config.Config, now time.Time) {
+	if len(cfg.Collections) == 0 {
+		fmt.Printf("%s[ INFO ]%s No saved collections. Save one from the TUI query bar with ctrl+s.\n", ColorCyan, ColorReset)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTION\tCOUNT\tQUERY")
+	for _, col := range cfg.Collections {
+		count := "invalid"
+		if q, err := query.Parse(col.Query); err == nil {
+			count = fmt.Sprint(len(q.Filter(cfg.Connections, now)))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", col.Name, count, col.Query)
+	}
+	tw.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print the matching connections as JSON (without keys)")
+	listCmd.Flags().StringVarP(&listCollection, "collection", "c", "", "Only list connections in this saved collection")
+	listCmd.Flags().BoolVar(&listCollections, "collections", false, "List the saved collections with their counts")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+var (
+	newName string
+	newTags string
+)
+
+var newCmd = &cobra.Command{
+	Use:   "new [--name name] [--tags t1,t2]",
+	Short: "Generate a new secret key and save it as a connection",
+	Long: `Generate a random GSocket secret (the same format as 'gs-netcat -g')
+without needing gs-netcat, save it as a new connection and print the command
+to run on the listening side.
+
+The connection is named after its key unless --name is given.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		name := strings.TrimSpace(newName)
+		if name != "" && config.IndexOfConnection(name) != -1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' already exists.%s\n", ColorBold, ColorRed, name, ColorReset)
+			os.Exit(1)
+		}
+
+		conn, err := config.GenerateConnection(name)
+		if errors.Is(err, config.ErrNoFreeName) {
+			err = fmt.Errorf("%w, use --name", err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		_, conn.Tags = parseKeyAndTags("#" + newTags)
+
+		config.AddConnection(conn)
+		if err := config.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError saving configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		listener := "gs-netcat " + strings.Join(runner.ListenerArgs(conn), " ")
+		fmt.Printf("%s[ SUCCESS ]%s Created connection %s%s%s | Key > \"%s\" | Tags > %v\n", ColorGreen, ColorReset, ColorBold, conn.Name, ColorReset, conn.Key, conn.Tags)
+		fmt.Println()
+		fmt.Println("Run this on the machine you want to reach:")
+		fmt.Printf("    %s\n", listener)
+		fmt.Println("Or keep it running in the background (restarted if it dies):")
+		fmt.Printf("    %s -D\n", listener)
+		fmt.Printf("More ways to deploy it: gsm deploy %s --format systemd|cron|docker|rcd\n", conn.Name)
+	},
+}
+
+func init() {
+	newCmd.Flags().StringVarP(&newName, "name", "n", "", "Name of the connection (default: generated from the key)")
+	newCmd.Flags().StringVarP(&newTags, "tags", "t", "", "Comma-separated tags")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/deploy"
+	"github.com/NumeXx/gsm/pkg/runner"
+	"github.com/NumeXx/gsm/pkg/utils"
+)
+
+// rotateWaitInterval is the pause between probes of the new listener while
+// waiting for it with --wait.
+const rotateWaitInterval = 5 * time.Second
+
+var (
+	rotateConfirm bool
+	rotateAbort   bool
+	rotateForce   bool
+	rotateHistory bool
+	rotateWait    time.Duration
+	rotateFormat  string
+	rotateTimeout time.Duration
+)
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate <name> [--confirm | --abort | --wait 10m]",
+	Short: "Rotate the secret key of a connection",
+	Long: `Rotate the secret key of a connection in two steps.
+
+'gsm rotate <name>' generates a new key, keeps it next to the old one as the
+pending key and prints how to deploy a listener for it. Sessions keep using
+the old key meanwhile. Once the new listener runs, 'gsm rotate <name>
+--confirm' probes it and, if it's online, retires the old key. --wait probes
+until the new listener comes up and then confirms on its own; --abort drops
+the pending key.
+
+Every finished rotation is kept in the connection's key history (keys are
+stored as fingerprints only), shown with --history.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if rotateConfirm && rotateAbort {
+			fmt.Fprintf(os.Stderr, "%s%sError: --confirm and --abort cannot be used together.%s\n", ColorBold, ColorRed, ColorReset)
+			os.Exit(1)
+		}
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		name := args[0]
+		idx := config.IndexOfConnection(name)
+		if idx == -1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' not found.%s\n", ColorBold, ColorRed, name, ColorReset)
+			os.Exit(1)
+		}
+		conn := config.GetCurrent().Connections[idx]
+
+		switch {
+		case rotateHistory:
+			printKeyHistory(conn)
+			return
+		case rotateAbort:
+			if err := finishRotation(name, false); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			fmt.Printf("%s[ INFO ]%s Rotation of '%s' aborted, it keeps its current key.\n", ColorCyan, ColorReset, name)
+			return
+		case rotateConfirm:
+			if conn.PendingKey == "" {
+				fmt.Fprintf(os.Stderr, "%s%sError: no rotation of '%s' is pending.%s\n", ColorBold, ColorRed, name, ColorReset)
+				os.Exit(1)
+			}
+			if !rotateForce {
+				res := probePendingKey(conn)
+				if res.Status != config.ProbeOnline {
+					fmt.Fprintf(os.Stderr, "%s%sError: the listener for the new key is %s (%s). Deploy it first or use --force.%s\n", ColorBold, ColorRed, res.Status, res.Detail, ColorReset)
+					os.Exit(1)
+				}
+			}
+			completeRotation(name)
+			return
+		}
+
+		if conn.PendingKey == "" {
+			newKey, err := utils.GenerateSecret()
+			if err == nil {
+				err = config.StartRotation(name, newKey, time.Now())
+			}
+			if err == nil {
+				err = config.Save()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError starting rotation: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			conn = config.GetCurrent().Connections[idx]
+			fmt.Printf("%s[ SUCCESS ]%s Generated a new key for '%s' (fingerprint %s). The old key stays in use until the rotation is confirmed.\n", ColorGreen, ColorReset, name, config.KeyFingerprint(conn.PendingKey))
+		} else {
+			fmt.Printf("%s[ PENDING ]%s A rotation of '%s' is pending since %s (new key fingerprint %s).\n", ColorYellow, ColorReset, name, conn.PendingSince.Local().Format("2006-01-02 15:04"), config.KeyFingerprint(conn.PendingKey))
+		}
+
+		pending := conn
+		pending.Key = conn.PendingKey
+		fmt.Println("\nStart a listener for the new key next to the old one:")
+		if err := deploy.Render(os.Stdout, rotateFormat, pending); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Println()
+
+		if rotateWait <= 0 {
+			fmt.Printf("Then run 'gsm rotate %s --confirm' (or --wait 10m to wait for it), and retire the old listener afterwards.\n", name)
+			return
+		}
+		fmt.Printf("%s[ INFO ]%s Waiting up to %s for the new listener...\n", ColorCyan, ColorReset, rotateWait)
+		deadline := time.Now().Add(rotateWait)
+		for {
+			res := probePendingKey(conn)
+			if res.Status == config.ProbeOnline {
+				completeRotation(name)
+				return
+			}
+			if time.Now().Add(rotateWaitInterval).After(deadline) {
+				fmt.Fprintf(os.Stderr, "%s%sError: the listener for the new key did not come up (last check: %s). The rotation stays pending.%s\n", ColorBold, ColorRed, res.Status, ColorReset)
+				os.Exit(1)
+			}
+			time.Sleep(rotateWaitInterval)
+		}
+	},
+}
+
+// probePendingKey checks whether a listener for the pending key of conn is up.
+func probePendingKey(conn config.Connection) runner.ProbeResult {
+	conn.Key = conn.PendingKey
+	return runner.Probe(context.Background(), sessionBackend, conn, rotateTimeout)
+}
+
+// completeRotation retires the old key of the connection called name and
+// tells the user, exiting on failure.
+func completeRotation(name string) {
+	if err := finishRotation(name, true); err != nil {
+		fmt.Fprintf(os.Stderr, "%s%sError completing rotation: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("%s[ SUCCESS ]%s The listener for the new key is online; '%s' now uses it. Stop the listener for the old key.\n", ColorGreen, ColorReset, name)
+}
+
+// finishRotation reloads the configuration, so changes made while probing
+// are kept, and finishes the pending rotation of the connection called name.
+func finishRotation(name string, completed bool) error {
+	if err := config.Load(); err != nil {
+		return err
+	}
+	if err := config.FinishRotation(name, completed, time.Now()); err != nil {
+		return err
+	}
+	return config.Save()
+}
+
+func printKeyHistory(conn config.Connection) {
+	if conn.PendingKey != "" {
+		fmt.Printf("%s[ PENDING ]%s since %s: %s -> %s\n", ColorYellow, ColorReset, conn.PendingSince.Local().Format("2006-01-02 15:04"), config.KeyFingerprint(conn.Key), config.KeyFingerprint(conn.PendingKey))
+	}
+	if len(conn.KeyHistory) == 0 {
+		fmt.Printf("%s[ INFO ]%s '%s' has no finished key rotations.\n", ColorCyan, ColorReset, conn.Name)
+		return
+	}
+	for _, rot := range conn.KeyHistory {
+		fmt.Printf("%s  %-9s  %s -> %s  (started %s)\n", rot.Finished.Local().Format("2006-01-02 15:04"), rot.Result, rot.OldKey, rot.NewKey, rot.Started.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func init() {
+	rotateCmd.Flags().BoolVar(&rotateConfirm, "confirm", false, "Probe the new listener and retire the old key if it's online")
+	rotateCmd.Flags().BoolVar(&rotateAbort, "abort", false, "Drop the pending key and keep the current one")
+	rotateCmd.Flags().BoolVar(&rotateForce, "force", false, "With --confirm, switch keys without probing the new listener")
+	rotateCmd.Flags().BoolVar(&rotateHistory, "history", false, "Show the key rotation history")
+	rotateCmd.Flags().DurationVarP(&rotateWait, "wait", "w", 0, "Wait up to this long for the new listener, then confirm")
+	rotateCmd.Flags().StringVarP(&rotateFormat, "format", "f", "oneliner", "Deploy snippet format (see gsm deploy)")
+	rotateCmd.Flags().DurationVarP(&rotateTimeout, "timeout", "t", runner.DefaultProbeTimeout, "Timeout of each probe")
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+const (
+	runStatusSuccess = "success"
+	runStatusFailure = "failure"
+	runStatusTimeout = "timeout"
+	runStatusError   = "error"
+)
+
+var (
+	runSelector connSelector
+	runParallel int
+	runTimeout  time.Duration
+	runGroup    bool
+	runJSON     bool
+	runNoStdin  bool
+)
+
+// runResult is the outcome of a command on one connection.
+type runResult struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	ExitCode *int          `json:"exit_code,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"duration_seconds"`
+	Stdout   string        `json:"stdout,omitempty"`
+	Stderr   string        `json:"stderr,omitempty"`
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [--tag t | --name pattern | --all] -- <command> [args...]",
+	Short: "Run a command on many connections in parallel",
+	Long: `Run the same command on every selected connection concurrently, like
+'gsm exec' fanned out. Output is streamed with a [name] prefix per line, or
+grouped per connection with --group, and a summary of successes, failures and
+timeouts is printed at the end. Piped stdin is sent to every connection.
+
+gsm exits 0 only if the command succeeded everywhere.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if dash := cmd.ArgsLenAtDash(); dash > 0 {
+			fmt.Fprintf(os.Stderr, "%s%sError: unexpected arguments before '--': %v%s\n", ColorBold, ColorRed, args[:dash], ColorReset)
+			os.Exit(1)
+		}
+		if runSelector.isEmpty() {
+			fmt.Fprintf(os.Stderr, "%s%sError: select connections with --tag, --name, --query, --collection or --all.%s\n", ColorBold, ColorRed, ColorReset)
+			cmd.Usage() //nolint:errcheck
+			os.Exit(1)
+		}
+		if runParallel < 1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: --parallel must be at least 1.%s\n", ColorBold, ColorRed, ColorReset)
+			os.Exit(1)
+		}
+
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		conns, err := runSelector.match(config.GetCurrent().Connections)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		if len(conns) == 0 {
+			fmt.Fprintf(os.Stderr, "%s[ INFO ]%s No connections match %s.\n", ColorCyan, ColorReset, runSelector.describe())
+			os.Exit(1)
+		}
+
+		var input []byte
+		if !runNoStdin && !term.IsTerminal(os.Stdin.Fd()) {
+			if input, err = io.ReadAll(os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError reading stdin: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+		}
+
+		results := runFanOut(sessionBackend, conns, strings.Join(args, " "), input, os.Stdout, os.Stderr)
+
+		if runJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError encoding JSON: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+		} else {
+			printRunSummary(os.Stdout, results)
+		}
+
+		if code := runExitCode(results); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+// runExitCode returns the exit code of gsm run: 0 only if the command
+// succeeded everywhere.
+func runExitCode(results []runResult) int {
+	for _, res := range results {
+		if res.Status != runStatusSuccess {
+			return 1
+		}
+	}
+	return 0
+}
+
+// runFanOut runs command on every connection with at most runParallel
+// sessions at a time, streaming their output to stdout and stderr, and
+// returns the results in the order of conns.
+func runFanOut(backend runner.Backend, conns []config.Connection, command string, input []byte, stdout, stderr io.Writer) []runResult {
+	recordUsage(conns...)
+
+	results := make([]runResult, len(conns))
+	out := &lockedWriter{w: stdout}
+	errOut := &lockedWriter{w: stderr}
+	nameWidth := 0
+	for _, conn := range conns {
+		nameWidth = max(nameWidth, len(conn.Name))
+	}
+
+	sem := make(chan struct{}, runParallel)
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var stdoutBuf, stderrBuf bytes.Buffer
+			req := runner.ExecRequest{Command: command, Hooks: config.GetCurrent().HooksFor(conn)}
+			if input != nil {
+				req.Stdin = bytes.NewReader(input)
+			}
+			switch {
+			case runJSON:
+				req.Stdout, req.Stderr = &stdoutBuf, &stderrBuf
+			case runGroup:
+				// Keep stdout and stderr interleaved in one block per connection.
+				req.Stdout, req.Stderr = &stdoutBuf, &stdoutBuf
+			default:
+				prefix := fmt.Sprintf("%s[%-*s]%s ", ColorCyan, nameWidth, conn.Name, ColorReset)
+				stdoutW := &prefixWriter{prefix: prefix, w: out}
+				stderrW := &prefixWriter{prefix: prefix, w: errOut}
+				defer stdoutW.Flush()
+				defer stderrW.Flush()
+				req.Stdout, req.Stderr = stdoutW, stderrW
+			}
+
+			results[i] = runOne(backend, conn, req)
+			if runJSON {
+				results[i].Stdout, results[i].Stderr = stdoutBuf.String(), stderrBuf.String()
+			}
+			if runGroup && !runJSON {
+				out.Write([]byte(fmt.Sprintf("%s%s=== %s (%s) ===%s\n%s", ColorBold, statusColor(results[i].Status), conn.Name, results[i].Status, ColorReset, stdoutBuf.String()))) //nolint:errcheck
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func runOne(backend runner.Backend, conn config.Connection, req runner.ExecRequest) runResult {
+	ctx := context.Background()
+	if runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
+
+	started := time.Now()
+	res, err := runner.RemoteExec(ctx, backend, conn, req)
+	result := runResult{Name: conn.Name, Duration: time.Since(started)}
+	result.Seconds = result.Duration.Seconds()
+
+	switch {
+	case errors.Is(err, runner.ErrExecTimeout):
+		result.Status = runStatusTimeout
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = runStatusError
+		result.Error = err.Error()
+	case !res.ExitKnown:
+		result.Status = runStatusError
+		result.Error = "exit status unknown"
+	default:
+		code := res.ExitCode
+		result.ExitCode = &code
+		result.Status = runStatusSuccess
+		if code != 0 {
+			result.Status = runStatusFailure
+		}
+	}
+	return result
+}
+
+func printRunSummary(w io.Writer, results []runResult) {
+	counts := map[string]int{}
+	fmt.Fprintf(w, "\n%sSummary%s\n", ColorBold, ColorReset)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tEXIT\tDURATION\tERROR")
+	for _, res := range results {
+		counts[res.Status]++
+		exit := "-"
+		if res.ExitCode != nil {
+			exit = fmt.Sprint(*res.ExitCode)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", res.Name, res.Status, exit, res.Duration.Round(10*time.Millisecond), res.Error)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%s%d success%s, %s%d failure%s, %s%d timeout%s, %s%d error%s\n",
+		ColorGreen, counts[runStatusSuccess], ColorReset,
+		ColorRed, counts[runStatusFailure], ColorReset,
+		ColorYellow, counts[runStatusTimeout], ColorReset,
+		ColorRed, counts[runStatusError], ColorReset)
+}
+
+func statusColor(status string) string {
+	switch status {
+	case runStatusSuccess:
+		return ColorGreen
+	case runStatusTimeout:
+		return ColorYellow
+	}
+	return ColorRed
+}
+
+// lockedWriter serialises writes from concurrent sessions.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// prefixWriter writes complete lines to w, each starting with prefix.
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		if _, err := p.w.Write([]byte(p.prefix + string(p.buf[:i+1]))); err != nil {
+			return len(data), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a trailing partial line, if any.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.w.Write([]byte(p.prefix + string(p.buf) + "\n")) //nolint:errcheck
+		p.buf = nil
+	}
+}
+
+func init() {
+	runSelector.addFlags(runCmd)
+	runCmd.Flags().IntVarP(&runParallel, "parallel", "p", 8, "Maximum number of connections to run on at once")
+	runCmd.Flags().DurationVarP(&runTimeout, "timeout", "t", time.Minute, "Per-connection timeout (0 for none)")
+	runCmd.Flags().BoolVarP(&runGroup, "group", "g", false, "Print each connection's output as one block when it finishes instead of prefixed lines")
+	runCmd.Flags().BoolVar(&runJSON, "json", false, "Print results (including output) as JSON instead of streaming")
+	runCmd.Flags().BoolVarP(&runNoStdin, "no-stdin", "n", false, "Do not forward local stdin to the remote commands")
+}
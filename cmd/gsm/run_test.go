@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"one line", []string{"hello\n"}, "[a] hello\n"},
+		{"lines in one write", []string{"one\ntwo\n"}, "[a] one\n[a] two\n"},
+		{"line split across writes", []string{"hel", "lo\nwor", "ld\n"}, "[a] hello\n[a] world\n"},
+		{"partial last line", []string{"done\npartial"}, "[a] done\n[a] partial\n"},
+		{"empty line", []string{"\n"}, "[a] \n"},
+		{"carriage returns kept", []string{"a\r\n"}, "[a] a\r\n"},
+		{"nothing", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := &prefixWriter{prefix: "[a] ", w: &out}
+			for _, data := range tt.writes {
+				if n, err := w.Write([]byte(data)); n != len(data) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", data, n, err)
+				}
+			}
+			w.Flush()
+			w.Flush()
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     int
+	}{
+		{nil, 0},
+		{[]string{runStatusSuccess, runStatusSuccess}, 0},
+		{[]string{runStatusSuccess, runStatusFailure}, 1},
+		{[]string{runStatusTimeout, runStatusSuccess}, 1},
+		{[]string{runStatusError}, 1},
+	}
+	for _, tt := range tests {
+		var results []runResult
+		for _, status := range tt.statuses {
+			results = append(results, runResult{Status: status})
+		}
+		if got := runExitCode(results); got != tt.want {
+			t.Errorf("runExitCode(%v) = %d, want %d", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+// shellPerConn plays every listener's shell with a local sh that has the
+// connection's name in $NAME. The connection "broken" never runs the command.
+func shellPerConn(t *testing.T) *runner.FakeBackend {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the exec script with")
+	}
+	shell := runner.FakeShell()
+	return runner.NewFakeBackend(func(spec runner.Spec, signals <-chan os.Signal) int {
+		if spec.Connection.Name == "broken" {
+			return 255
+		}
+		spec.Stdin = io.MultiReader(strings.NewReader("NAME="+spec.Connection.Name+"\n"), spec.Stdin)
+		return shell(spec, signals)
+	})
+}
+
+// useRunFlags sets the flags of gsm run for the test.
+func useRunFlags(t *testing.T, parallel int, timeout time.Duration, group, json bool) {
+	t.Helper()
+	savedParallel, savedTimeout, savedGroup, savedJSON := runParallel, runTimeout, runGroup, runJSON
+	t.Cleanup(func() {
+		runParallel, runTimeout, runGroup, runJSON = savedParallel, savedTimeout, savedGroup, savedJSON
+	})
+	runParallel, runTimeout, runGroup, runJSON = parallel, timeout, group, json
+}
+
+const runTestCommand = `echo "out $NAME"; echo "err $NAME" >&2; [ "$NAME" = slow ] && sleep 5; printf "partial $NAME"; [ "$NAME" != db-1 ]`
+
+func TestRunFanOut(t *testing.T) {
+	conns := []config.Connection{
+		{Name: "web-1", Key: "k1"},
+		{Name: "db-1", Key: "k2"},
+		{Name: "slow", Key: "k3"},
+		{Name: "broken", Key: "k4"},
+	}
+	useConfig(t, conns...)
+	useRunFlags(t, 2, 2*time.Second, false, false)
+
+	var stdout, stderr bytes.Buffer
+	results := runFanOut(shellPerConn(t), conns, runTestCommand, nil, &stdout, &stderr)
+
+	wantStatus := map[string]string{"web-1": runStatusSuccess, "db-1": runStatusFailure, "slow": runStatusTimeout, "broken": runStatusError}
+	for i, res := range results {
+		if res.Name != conns[i].Name {
+			t.Fatalf("result %d is for %s, want %s", i, res.Name, conns[i].Name)
+		}
+		if res.Status != wantStatus[res.Name] {
+			t.Errorf("%s: status %s (%s), want %s", res.Name, res.Status, res.Error, wantStatus[res.Name])
+		}
+	}
+	if code := results[0].ExitCode; code == nil || *code != 0 {
+		t.Errorf("web-1 exit code = %v, want 0", code)
+	}
+	if code := results[1].ExitCode; code == nil || *code != 1 {
+		t.Errorf("db-1 exit code = %v, want 1", code)
+	}
+	if got := runExitCode(results); got != 1 {
+		t.Errorf("runExitCode = %d, want 1", got)
+	}
+
+	// Every line is prefixed with the padded name; the partial last line is
+	// flushed on its own line.
+	for _, want := range []string{
+		"[web-1 ]" + ColorReset + " out web-1\n",
+		"[web-1 ]" + ColorReset + " partial web-1\n",
+		"[db-1  ]" + ColorReset + " partial db-1\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout %q does not contain %q", stdout.String(), want)
+		}
+	}
+	if strings.Contains(stdout.String(), "err ") {
+		t.Errorf("stdout %q contains stderr output", stdout.String())
+	}
+	if want := "[slow  ]" + ColorReset + " err slow\n"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr %q does not contain %q", stderr.String(), want)
+	}
+
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range config.GetCurrent().Connections {
+		if conn.Usage != 1 || conn.LastConnected.IsZero() {
+			t.Errorf("%s: usage %d, last connected %v; want the run recorded and saved", conn.Name, conn.Usage, conn.LastConnected)
+		}
+	}
+}
+
+func TestRunFanOutGroupedAndJSON(t *testing.T) {
+	conns := []config.Connection{{Name: "web-1", Key: "k1"}, {Name: "db-1", Key: "k2"}}
+	useConfig(t, conns...)
+
+	useRunFlags(t, 8, time.Minute, true, false)
+	var stdout, stderr bytes.Buffer
+	runFanOut(shellPerConn(t), conns, runTestCommand, nil, &stdout, &stderr)
+	// stdout and stderr reach the group on separate streams, so only the
+	// block each lands in is fixed, not their order within it.
+	for _, tt := range []struct{ name, status string }{{"web-1", runStatusSuccess}, {"db-1", runStatusFailure}} {
+		header := "=== " + tt.name + " (" + tt.status + ") ===" + ColorReset + "\n"
+		_, block, ok := strings.Cut(stdout.String(), header)
+		if !ok {
+			t.Errorf("grouped output %q has no header %q", stdout.String(), header)
+			continue
+		}
+		block, _, _ = strings.Cut(block, "=== ")
+		for _, want := range []string{"out " + tt.name, "err " + tt.name + "\n", "partial " + tt.name} {
+			if !strings.Contains(block, want) {
+				t.Errorf("output of %s %q does not contain %q", tt.name, block, want)
+			}
+		}
+	}
+
+	useRunFlags(t, 8, time.Minute, false, true)
+	stdout.Reset()
+	results := runFanOut(shellPerConn(t), conns, "cat", []byte("input\n"), &stdout, &stderr)
+	if stdout.Len() != 0 {
+		t.Errorf("JSON mode streamed %q", stdout.String())
+	}
+	for _, res := range results {
+		if res.Status != runStatusSuccess || res.Stdout != "input\n" {
+			t.Errorf("%s: status %s, stdout %q; want stdin sent to every connection", res.Name, res.Status, res.Stdout)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/query"
+)
+
+// connSelector picks connections for commands that work on several at once.
+type connSelector struct {
+	tags        []string
+	names       []string
+	query       string
+	collections []string
+	all         bool
+}
+
+func (s *connSelector) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&s.tags, "tag", nil, "Select connections having any of these tags (repeatable or comma-separated)")
+	cmd.Flags().StringSliceVar(&s.names, "name", nil, "Select connections whose name matches any of these glob patterns (e.g. 'web-*')")
+	cmd.Flags().StringVar(&s.query, "query", "", "Only select connections matching this query (e.g. 'tag:prod seen<7d', see gsm list --help)")
+	cmd.Flags().StringSliceVar(&s.collections, "collection", nil, "Only select connections in any of these saved collections (repeatable or comma-separated)")
+	cmd.Flags().BoolVar(&s.all, "all", false, "Select all connections")
+}
+
+// isEmpty reports whether no selection flag was given.
+func (s *connSelector) isEmpty() bool {
+	return !s.all && len(s.tags) == 0 && len(s.names) == 0 && s.query == "" && len(s.collections) == 0
+}
+
+// describe returns a short human readable form of the selection.
+func (s *connSelector) describe() string {
+	var parts []string
+	switch {
+	case s.all:
+		parts = append(parts, "all connections")
+	case len(s.tags) > 0 && len(s.names) > 0:
+		parts = append(parts, fmt.Sprintf("tags %v or names %v", s.tags, s.names))
+	case len(s.tags) > 0:
+		parts = append(parts, fmt.Sprintf("tags %v", s.tags))
+	case len(s.names) > 0:
+		parts = append(parts, fmt.Sprintf("names %v", s.names))
+	}
+	if s.query != "" {
+		parts = append(parts, fmt.Sprintf("query '%s'", s.query))
+	}
+	if len(s.collections) > 0 {
+		parts = append(parts, fmt.Sprintf("collections %v", s.collections))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// match returns the connections of conns picked by the selector, in config
+// order. --tag and --name pick alternatives; --query and --collection narrow
+// that down (or all connections if neither was given), like gsm list does.
+func (s *connSelector) match(conns []config.Connection) ([]config.Connection, error) {
+	for _, pattern := range s.names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern '%s': %w", pattern, err)
+		}
+	}
+
+	var q *query.Query
+	if s.query != "" {
+		var err error
+		if q, err = query.Parse(s.query); err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+	}
+	var collections []*query.Query
+	cfg := config.GetCurrent()
+	for _, name := range s.collections {
+		col, ok := cfg.FindCollection(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown collection '%s'", name)
+		}
+		colQuery, err := query.Parse(col.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query in collection '%s': %w", name, err)
+		}
+		collections = append(collections, colQuery)
+	}
+
+	byTagOrName := !s.all && (len(s.tags) > 0 || len(s.names) > 0)
+	now := time.Now()
+	var selected []config.Connection
+	for _, conn := range conns {
+		if byTagOrName && !s.matchTags(conn) && !s.matchNames(conn) {
+			continue
+		}
+		if q != nil && !q.Match(conn, now) {
+			continue
+		}
+		if len(collections) > 0 && !matchAny(collections, conn, now) {
+			continue
+		}
+		selected = append(selected, conn)
+	}
+	return selected, nil
+}
+
+func matchAny(queries []*query.Query, conn config.Connection, now time.Time) bool {
+	for _, q := range queries {
+		if q.Match(conn, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *connSelector) matchTags(conn config.Connection) bool {
+	for _, tag := range s.tags {
+		if slices.Contains(conn.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *connSelector) matchNames(conn config.Connection) bool {
+	for _, pattern := range s.names {
+		if ok, _ := path.Match(pattern, conn.Name); ok {
+			return true
+		}
+	}
+	return false
+}
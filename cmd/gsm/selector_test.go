@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// useConfig points the config package at an empty file in a temporary
+// directory and adds conns to it.
+func useConfig(t *testing.T, conns ...config.Connection) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config.DefaultConfigFilePath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range conns {
+		config.AddConnection(conn)
+	}
+}
+
+func TestConnSelectorMatch(t *testing.T) {
+	useConfig(t,
+		config.Connection{Name: "web-1", Key: "k1", Tags: []string{"prod", "web"}},
+		config.Connection{Name: "web-2", Key: "k2", Tags: []string{"staging", "web"}},
+		config.Connection{Name: "db-1", Key: "k3", Tags: []string{"prod", "db"}},
+		config.Connection{Name: "lab", Key: "k4", Tags: []string{"lab"}},
+	)
+	config.SetCollection("prod", "tag:prod")
+	config.SetCollection("lab", "tag:lab")
+
+	tests := []struct {
+		name     string
+		selector connSelector
+		want     []string
+		describe string
+	}{
+		{"all", connSelector{all: true}, []string{"web-1", "web-2", "db-1", "lab"}, "all connections"},
+		{"tags or names", connSelector{tags: []string{"lab"}, names: []string{"db-*"}}, []string{"db-1", "lab"}, "tags [lab] or names [db-*]"},
+		{"query", connSelector{query: "tag:web"}, []string{"web-1", "web-2"}, "query 'tag:web'"},
+		{"collections", connSelector{collections: []string{"prod", "lab"}}, []string{"web-1", "db-1", "lab"}, "collections [prod lab]"},
+		{"query and collection", connSelector{query: "tag:web", collections: []string{"prod"}}, []string{"web-1"}, "query 'tag:web' and collections [prod]"},
+		{"names and collection", connSelector{names: []string{"web-*"}, collections: []string{"prod"}}, []string{"web-1"}, "names [web-*] and collections [prod]"},
+		{"all and query", connSelector{all: true, query: "tag:db"}, []string{"db-1"}, "all connections and query 'tag:db'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := tt.selector.match(config.GetCurrent().Connections)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, conn := range selected {
+				got = append(got, conn.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+			if d := tt.selector.describe(); d != tt.describe {
+				t.Errorf("describe = %q, want %q", d, tt.describe)
+			}
+		})
+	}
+}
+
+func TestConnSelectorMatchErrors(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"})
+	for _, s := range []connSelector{
+		{names: []string{"["}},
+		{query: "usage>"},
+		{collections: []string{"missing"}},
+	} {
+		if _, err := s.match(config.GetCurrent().Connections); err == nil {
+			t.Errorf("match(%+v) succeeded, want an error", s)
+		}
+	}
+}
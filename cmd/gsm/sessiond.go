@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+	"github.com/NumeXx/gsm/pkg/sessiond"
+)
+
+// sessiondIdleTimeout stops the session daemon once it had no sessions this long.
+const sessiondIdleTimeout = time.Minute
+
+var attachNew bool
+
+var sessiondCmd = &cobra.Command{
+	Use:    "sessiond",
+	Short:  "Run the background session daemon (started automatically)",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		srv := &sessiond.Server{
+			Backend:     sessionBackend,
+			NewTap:      daemonRecordingTap,
+			Hooks:       daemonHooks,
+			IdleTimeout: sessiondIdleTimeout,
+		}
+		log.Printf("Session daemon listening on %s", sessiond.SocketPath())
+		if err := srv.ListenAndServe(); err != nil {
+			log.Printf("Session daemon error: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// daemonRecordingTap records background sessions when recording is enabled
+// for their connection.
+func daemonRecordingTap(conn config.Connection) (runner.Tap, func()) {
+	if err := config.Load(); err != nil || !config.GetCurrent().RecordingEnabled(conn) {
+		return nil, nil
+	}
+	rec, path, err := startRecording(conn)
+	if err != nil {
+		log.Printf("Error starting session recording for %s: %v", conn.Name, err)
+		return nil, nil
+	}
+	return rec, func() {
+		if err := rec.Close(); err != nil {
+			log.Printf("Error finishing session recording %s: %v", path, err)
+		}
+	}
+}
+
+// daemonHooks returns the hooks of conn from the current configuration,
+// which the long-running daemon reloads for every session.
+func daemonHooks(conn config.Connection) config.Hooks {
+	if err := config.Load(); err != nil {
+		log.Printf("Error loading configuration for the hooks of %s: %v", conn.Name, err)
+		return config.Hooks{}
+	}
+	return config.GetCurrent().HooksFor(conn)
+}
+
+// spawnSessiond starts the session daemon detached from this process.
+func spawnSessiond() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logPath := filepath.Join(filepath.Dir(config.DefaultConfigFilePath), "sessiond.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "sessiond")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// resolveSession finds the live session for ref, which is a session id or a
+// connection name. It returns "" if there is none.
+func resolveSession(ref string) (string, error) {
+	infos, err := sessiond.List()
+	if err != nil {
+		return "", err
+	}
+	latest := ""
+	for _, info := range infos {
+		if info.ID == ref {
+			return info.ID, nil
+		}
+		if info.Connection == ref {
+			latest = info.ID
+		}
+	}
+	return latest, nil
+}
+
+// startBackgroundSession starts a daemon session for conn and returns its id.
+func startBackgroundSession(conn config.Connection) (string, error) {
+	if err := sessiond.EnsureRunning(spawnSessiond); err != nil {
+		return "", err
+	}
+	recordUsage(conn)
+	return sessiond.Start(conn)
+}
+
+// sessionStarter starts background sessions for the TUI's bulk connect,
+// which records their usage itself.
+type sessionStarter struct{}
+
+func (sessionStarter) Start(conn config.Connection) (string, error) {
+	if err := sessiond.EnsureRunning(spawnSessiond); err != nil {
+		return "", err
+	}
+	return sessiond.Start(conn)
+}
+
+// attachSession attaches the terminal to session id and reports how it ended.
+func attachSession(id, name string) error {
+	fmt.Printf("[+] Attached to session %s (%s). Press %s to detach.\n", id, name, sessiond.DetachKeyName)
+	detached, err := sessiond.Attach(id, os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if detached {
+		fmt.Printf("\r\n[<] Detached from session %s; it keeps running in the background. Reattach with: gsm attach %s\n", id, id)
+	} else {
+		fmt.Printf("\r\n[<] Session %s (%s) ended.\n", id, name)
+	}
+	return nil
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <id|name>",
+	Short: "Attach to a background session, starting one for a connection if needed",
+	Long: `Attach the terminal to a live background session.
+
+<id|name> is a session id from 'gsm sessions' or a connection name. For a
+connection name, its most recent live session is used, or a new background
+session is started if there is none (or if --new is given).
+Press ` + sessiond.DetachKeyName + ` to detach and leave the session running.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := args[0]
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		id := ""
+		if !attachNew {
+			var err error
+			if id, err = resolveSession(ref); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError listing sessions: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+		}
+
+		name := ref
+		if id == "" {
+			idx := config.IndexOfConnection(ref)
+			if idx == -1 {
+				fmt.Fprintf(os.Stderr, "%s%sError: no live session or connection named '%s'.%s\n", ColorBold, ColorRed, ref, ColorReset)
+				os.Exit(1)
+			}
+			var err error
+			if id, err = startBackgroundSession(config.GetCurrent().Connections[idx]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError starting background session: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+		} else if infos, err := sessiond.List(); err == nil {
+			for _, info := range infos {
+				if info.ID == id {
+					name = info.Connection
+				}
+			}
+		}
+
+		if err := attachSession(id, name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError attaching to session %s: %v%s\n", ColorBold, ColorRed, id, err, ColorReset)
+			os.Exit(1)
+		}
+	},
+}
+
+var sessionsKillCmd = &cobra.Command{
+	Use:   "kill <id>",
+	Short: "Terminate a live background session",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := sessiond.Kill(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError killing session %s: %v%s\n", ColorBold, ColorRed, args[0], err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("%s[ SUCCESS ]%s Session %s terminated.\n", ColorGreen, ColorReset, args[0])
+	},
+}
+
+func init() {
+	attachCmd.Flags().BoolVar(&attachNew, "new", false, "Always start a new background session for the connection")
+	sessionsCmd.AddCommand(sessionsKillCmd)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/recording"
+	"github.com/NumeXx/gsm/pkg/sessiond"
+)
+
+var (
+	replaySpeed          float64
+	replayMaxIdle        time.Duration
+	sessionsLiveOnly     bool
+	sessionsRecordedOnly bool
+)
+
+// startRecording opens a new .cast file for a session to conn.
+func startRecording(conn config.Connection) (*recording.Recorder, string, error) {
+	width, height := 80, 24
+	if w, h, err := term.GetSize(os.Stdout.Fd()); err == nil {
+		width, height = w, h
+	}
+	path := recording.NewPath(conn.Name, time.Now())
+	rec, err := recording.Create(path, recording.Header{
+		Width:  width,
+		Height: height,
+		Title:  conn.Name,
+		Env:    map[string]string{"SHELL": os.Getenv("SHELL"), "TERM": os.Getenv("TERM")},
+	})
+	return rec, path, err
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions [name]",
+	Short: "List live background sessions and recorded sessions",
+	Long: `List live background sessions (see 'gsm attach') and recorded sessions
+(see 'gsm replay'), optionally only those of one connection.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		showLive, showRecorded := !sessionsRecordedOnly, !sessionsLiveOnly
+
+		if showLive {
+			infos, err := sessiond.List()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError listing live sessions: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			printLiveSessions(infos, name)
+		}
+		if showLive && showRecorded {
+			fmt.Println()
+		}
+		if showRecorded {
+			infos, err := recording.List(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError listing recordings: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			printRecordings(infos)
+		}
+	},
+}
+
+func printLiveSessions(infos []sessiond.Info, name string) {
+	fmt.Printf("%sLive sessions%s\n", ColorBold, ColorReset)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	count := 0
+	for _, info := range infos {
+		if name != "" && info.Connection != name {
+			continue
+		}
+		if count == 0 {
+			fmt.Fprintln(tw, "ID\tCONNECTION\tSTARTED\tUPTIME\tATTACHED")
+		}
+		count++
+		attached := "no"
+		if info.Attached {
+			attached = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			info.ID,
+			info.Connection,
+			info.Started.Format("2006-01-02 15:04:05"),
+			time.Since(info.Started).Round(time.Second),
+			attached,
+		)
+	}
+	tw.Flush()
+	if count == 0 {
+		fmt.Printf("%s[ INFO ]%s No live background sessions.\n", ColorCyan, ColorReset)
+	}
+}
+
+func printRecordings(infos []recording.Info) {
+	fmt.Printf("%sRecorded sessions%s\n", ColorBold, ColorReset)
+	if len(infos) == 0 {
+		fmt.Printf("%s[ INFO ]%s No recorded sessions found in '%s'.\n", ColorCyan, ColorReset, recording.Dir())
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTION\tSTARTED\tDURATION\tSIZE\tFILE")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			info.Connection,
+			info.Started.Format("2006-01-02 15:04:05"),
+			info.Duration.Round(time.Second),
+			humanSize(info.Size),
+			info.Path,
+		)
+	}
+	tw.Flush()
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <session>",
+	Short: "Play back a recorded session",
+	Long: `Play back a recorded session in the terminal.
+
+<session> can be the path of a .cast file, a path relative to ~/.gsm/sessions,
+or a connection name, in which case its latest recording is played.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := recording.Resolve(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		if replaySpeed <= 0 {
+			fmt.Fprintf(os.Stderr, "%s%sError: --speed must be greater than 0.%s\n", ColorBold, ColorRed, ColorReset)
+			os.Exit(1)
+		}
+		if err := recording.Replay(path, os.Stdout, replaySpeed, replayMaxIdle); err != nil {
+			fmt.Fprintf(os.Stderr, "\n%s%sError replaying '%s': %v%s\n", ColorBold, ColorRed, path, err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("\n%s[ DONE ]%s Replay of '%s' finished.\n", ColorGreen, ColorReset, path)
+	},
+}
+
+func init() {
+	sessionsCmd.Flags().BoolVar(&sessionsLiveOnly, "live", false, "Only list live background sessions")
+	sessionsCmd.Flags().BoolVar(&sessionsRecordedOnly, "recorded", false, "Only list recorded sessions")
+	sessionsCmd.MarkFlagsMutuallyExclusive("live", "recorded")
+	replayCmd.Flags().Float64VarP(&replaySpeed, "speed", "S", 1, "Playback speed multiplier (e.g. 2 for twice as fast)")
+	replayCmd.Flags().DurationVarP(&replayMaxIdle, "max-idle", "i", 0, "Cap pauses between output to this duration (e.g. 2s, 0 keeps original timing)")
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
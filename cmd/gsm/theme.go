@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/theme"
+	"github.com/NumeXx/gsm/pkg/tui"
+)
+
+// themeName is the --theme flag; it overrides the theme in the config.
+var themeName string
+
+// applyTheme sets up the TUI colors from --theme, GSM_THEME or the config,
+// detecting a theme when none is set.
+func applyTheme() error {
+	name := themeName
+	if name == "" {
+		name = config.GetCurrent().Settings.Theme
+	}
+	t, err := theme.Resolve(name)
+	if err != nil {
+		return err
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		// lipgloss drops all styling under NO_COLOR. Keep bold and reverse
+		// text so the list stays usable, and the colors of a theme that
+		// was picked explicitly.
+		profile := termenv.ANSI
+		if t.Name != theme.Mono.Name {
+			profile = termenv.NewOutput(os.Stdout).ColorProfile()
+		}
+		lipgloss.SetColorProfile(profile)
+	}
+	tui.SetTheme(t)
+	return nil
+}
+
+var themeCmd = &cobra.Command{
+	Use:   "theme [name]",
+	Short: "List the TUI color themes or pick one",
+	Long: `Without a name, list the built-in and custom color themes. With a name,
+save it as the theme of the TUI; "auto" picks mono when NO_COLOR is set and
+dark or light to match the terminal background otherwise.
+
+Custom themes are JSON files in ~/.gsm/themes, named <theme>.json. Colors are
+ANSI numbers ("32") or hex values ("#005f87"); the ones a theme leaves out
+come from its "base" theme (default dark):
+
+  {"base": "light", "selection": "#005f87", "error": "160"}
+
+--theme or GSM_THEME picks a theme for one run.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		if len(args) == 0 {
+			current := config.GetCurrent().Settings.Theme
+			if current == "" {
+				current = theme.Auto
+			}
+			for _, name := range append([]string{theme.Auto}, theme.Names()...) {
+				mark := "  "
+				if name == current {
+					mark = "* "
+				}
+				note := ""
+				if name == theme.Auto {
+					note = fmt.Sprintf(" (%s here)", theme.Detect())
+				}
+				fmt.Printf("%s%s%s\n", mark, name, note)
+			}
+			return
+		}
+
+		name := args[0]
+		if name != theme.Auto {
+			if _, err := theme.Load(name); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+		}
+		config.SetTheme(name)
+		if err := config.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError saving configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("%s[ SUCCESS ]%s The TUI now uses the %s theme.\n", ColorGreen, ColorReset, name)
+	},
+}
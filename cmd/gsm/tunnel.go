@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/tunnel"
+)
+
+// tunnelStartTimeout bounds waiting for a new supervisor to report in.
+const tunnelStartTimeout = 5 * time.Second
+
+// tunnelStopTimeout bounds waiting for a supervisor to shut down.
+const tunnelStopTimeout = 5 * time.Second
+
+var (
+	tunnelPort    int
+	tunnelDownAll bool
+	tunnelJSON    bool
+)
+
+var tunnelCmd = &cobra.Command{
+	Use:   "tunnel",
+	Short: "Manage background port-forward and SOCKS tunnels",
+	Long: `Keep connections to port-forward or SOCKS listeners running in the
+background. 'gsm tunnel up' starts a supervisor that runs gs-netcat on a local
+port (the connection's local_port or --port) and restarts it when it dies.`,
+}
+
+var tunnelUpCmd = &cobra.Command{
+	Use:   "up <name>...",
+	Short: "Start tunnels in the background",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if tunnelPort != 0 && len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "%s%sError: --port can only be used with a single connection.%s\n", ColorBold, ColorRed, ColorReset)
+			os.Exit(1)
+		}
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		failed := false
+		for _, name := range args {
+			idx := config.IndexOfConnection(name)
+			if idx == -1 {
+				fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' not found.%s\n", ColorBold, ColorRed, name, ColorReset)
+				failed = true
+				continue
+			}
+			conn := config.GetCurrent().Connections[idx]
+			port := conn.LocalPort
+			if tunnelPort != 0 {
+				port = tunnelPort
+			}
+			state, err := startTunnel(conn, port)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError starting tunnel '%s': %v%s\n", ColorBold, ColorRed, name, err, ColorReset)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s[+]%s Tunnel '%s' is %s on localhost:%d (supervisor pid %d).\n", ColorGreen, ColorReset, name, state.Status(), state.Port, state.SupervisorPID)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var tunnelDownCmd = &cobra.Command{
+	Use:   "down [name...] [--all]",
+	Short: "Stop background tunnels",
+	Run: func(cmd *cobra.Command, args []string) {
+		names := args
+		if tunnelDownAll {
+			states, err := tunnel.List()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError listing tunnels: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			names = nil
+			for _, s := range states {
+				names = append(names, s.Name)
+			}
+		} else if len(names) == 0 {
+			fmt.Fprintf(os.Stderr, "%s%sError: name a tunnel or use --all.%s\n", ColorBold, ColorRed, ColorReset)
+			os.Exit(1)
+		}
+
+		failed := false
+		for _, name := range names {
+			state, err := tunnel.Load(name)
+			if err == nil && state == nil {
+				fmt.Printf("%s[ INFO ]%s No tunnel for '%s' is running.\n", ColorCyan, ColorReset, name)
+				continue
+			}
+			if err == nil {
+				err = tunnel.Stop(name, tunnelStopTimeout)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError stopping tunnel '%s': %v%s\n", ColorBold, ColorRed, name, err, ColorReset)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s[-]%s Tunnel '%s' stopped.\n", ColorGreen, ColorReset, name)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var tunnelStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show background tunnels",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		states, err := tunnel.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError listing tunnels: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		if tunnelJSON {
+			type tunnelStatus struct {
+				tunnel.State
+				Status        string  `json:"status"`
+				UptimeSeconds float64 `json:"uptime_seconds"`
+			}
+			out := make([]tunnelStatus, 0, len(states))
+			for _, s := range states {
+				out = append(out, tunnelStatus{State: s, Status: s.Status(), UptimeSeconds: s.Uptime().Seconds()})
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(out); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError encoding JSON: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(states) == 0 {
+			fmt.Printf("%s[ INFO ]%s No tunnels running. Start one with 'gsm tunnel up <name>'.\n", ColorCyan, ColorReset)
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tPORT\tSTATUS\tPID\tUPTIME\tRESTARTS\tLAST EXIT")
+		for _, s := range states {
+			uptime, pid := "-", "-"
+			if s.Status() == tunnel.StatusUp {
+				uptime = s.Uptime().Round(time.Second).String()
+				if s.PID > 0 {
+					pid = strconv.Itoa(s.PID)
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%s\n", s.Name, s.Port, s.Status(), pid, uptime, s.Restarts, s.LastExit)
+		}
+		tw.Flush()
+	},
+}
+
+var tunnelSuperviseCmd = &cobra.Command{
+	Use:    "supervise <name>",
+	Short:  "Run the supervisor of a tunnel (started by 'gsm tunnel up')",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.Load(); err != nil {
+			log.Printf("Error loading configuration: %v", err)
+			os.Exit(1)
+		}
+		idx := config.IndexOfConnection(args[0])
+		if idx == -1 {
+			log.Printf("Connection '%s' not found.", args[0])
+			os.Exit(1)
+		}
+		conn := config.GetCurrent().Connections[idx]
+
+		sup := &tunnel.Supervisor{
+			Backend: sessionBackend,
+			Conn:    conn,
+			Port:    tunnelPort,
+			Output:  os.Stdout,
+			Hooks:   config.GetCurrent().HooksFor(conn),
+		}
+		if conn.Reconnect != nil {
+			sup.Policy = *conn.Reconnect
+		}
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		if err := sup.Run(stop); err != nil {
+			log.Printf("Tunnel %s: %v", conn.Name, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// startTunnel starts a supervisor for a tunnel through conn on port and
+// waits until the tunnel accepts connections. A tunnel that is already up is left alone.
+func startTunnel(conn config.Connection, port int) (*tunnel.State, error) {
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("no local port; set local_port on the connection or use --port")
+	}
+	if state, err := tunnel.Load(conn.Name); err != nil {
+		return nil, err
+	} else if state != nil {
+		if state.Status() != tunnel.StatusDead {
+			return state, nil
+		}
+		tunnel.Remove(conn.Name) //nolint:errcheck
+	}
+
+	if err := spawnTunnelSupervisor(conn.Name, port); err != nil {
+		return nil, err
+	}
+	// The supervisor marks the tunnel running once gs-netcat accepts
+	// connections on the port.
+	deadline := time.Now().Add(tunnelStartTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		state, err := tunnel.Load(conn.Name)
+		if err != nil || state == nil {
+			continue
+		}
+		if state.Running {
+			return state, nil
+		}
+		if state.LastExit != "" {
+			return nil, fmt.Errorf("gs-netcat exited while starting (%s) and will be retried, see %s", state.LastExit, tunnel.LogPath(conn.Name))
+		}
+	}
+	return nil, fmt.Errorf("tunnel did not come up, see %s", tunnel.LogPath(conn.Name))
+}
+
+// spawnTunnelSupervisor starts 'gsm tunnel supervise' detached from this
+// process, logging to the tunnel's log file.
+func spawnTunnelSupervisor(name string, port int) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tunnel.Dir(), 0700); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(tunnel.LogPath(name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "tunnel", "supervise", name, "--port", strconv.Itoa(port))
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the supervisor if it exits while gsm (e.g. the TUI) still runs.
+	go cmd.Wait() //nolint:errcheck
+	return nil
+}
+
+// tunnelControl starts and stops tunnels for the TUI's tunnels view.
+type tunnelControl struct{}
+
+func (tunnelControl) Up(conn config.Connection) error {
+	_, err := startTunnel(conn, conn.LocalPort)
+	return err
+}
+
+func (tunnelControl) Down(name string) error {
+	return tunnel.Stop(name, tunnelStopTimeout)
+}
+
+func init() {
+	tunnelUpCmd.Flags().IntVarP(&tunnelPort, "port", "p", 0, "Local port to listen on (default: the connection's local_port)")
+	tunnelSuperviseCmd.Flags().IntVarP(&tunnelPort, "port", "p", 0, "Local port to listen on")
+	tunnelDownCmd.Flags().BoolVarP(&tunnelDownAll, "all", "a", false, "Stop all tunnels")
+	tunnelStatusCmd.Flags().BoolVar(&tunnelJSON, "json", false, "Print tunnel state as JSON")
+	tunnelCmd.AddCommand(tunnelUpCmd, tunnelDownCmd, tunnelStatusCmd, tunnelSuperviseCmd)
+}
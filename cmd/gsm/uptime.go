@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/uptime"
+	"github.com/NumeXx/gsm/pkg/utils"
+)
+
+// uptimeSparkWidth is the width of the history sparklines.
+const uptimeSparkWidth = 24
+
+var (
+	uptimeRange string
+	uptimeJSON  bool
+)
+
+var uptimeCmd = &cobra.Command{
+	Use:   "uptime [name]",
+	Short: "Report listener availability from past checks",
+	Long: `Report how reliable listeners have been, based on the history of
+liveness checks made by 'gsm check' and the TUI. Without a name every
+connection is summarised; with a name its outage windows are listed too.
+
+Checks older than a week are kept as hourly aggregates and dropped after 90
+days.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		span, err := utils.ParseDuration(uptimeRange)
+		if err == nil && span == 0 {
+			err = fmt.Errorf("invalid range '%s' (use e.g. 24h, 7d or 2w)", uptimeRange)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		if err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%sError loading configuration: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+
+		names := []string{}
+		if len(args) == 1 {
+			if config.IndexOfConnection(args[0]) == -1 {
+				fmt.Fprintf(os.Stderr, "%s%sError: connection '%s' not found.%s\n", ColorBold, ColorRed, args[0], ColorReset)
+				os.Exit(1)
+			}
+			names = append(names, args[0])
+		} else {
+			for _, conn := range config.GetCurrent().Connections {
+				names = append(names, conn.Name)
+			}
+		}
+
+		to := time.Now()
+		from := to.Add(-span)
+		reports := make([]uptime.Report, 0, len(names))
+		sparks := make([]string, 0, len(names))
+		for _, name := range names {
+			samples, err := uptime.Load(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError reading history of '%s': %v%s\n", ColorBold, ColorRed, name, err, ColorReset)
+				os.Exit(1)
+			}
+			reports = append(reports, uptime.Analyze(name, samples, from, to))
+			sparks = append(sparks, uptime.Sparkline(samples, from, to, uptimeSparkWidth))
+		}
+
+		if uptimeJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(reports); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%sError encoding JSON: %v%s\n", ColorBold, ColorRed, err, ColorReset)
+				os.Exit(1)
+			}
+			return
+		}
+		if len(args) == 1 {
+			printUptimeReport(reports[0], sparks[0])
+			return
+		}
+		printUptimeSummary(reports, sparks)
+	},
+}
+
+func printUptimeSummary(reports []uptime.Report, sparks []string) {
+	if len(reports) == 0 {
+		fmt.Printf("%s[ INFO ]%s No connections configured.\n", ColorCyan, ColorReset)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tAVAILABILITY\tCHECKS\tOUTAGES\tFLAPPING\tHISTORY")
+	for i, r := range reports {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\n", r.Name, formatAvailability(r), r.Checks, len(r.Outages), yesNo(r.Flapping), sparks[i])
+	}
+	tw.Flush()
+}
+
+func printUptimeReport(r uptime.Report, spark string) {
+	fmt.Printf("%s%s%s, last %s\n\n", ColorBold, r.Name, ColorReset, uptimeRange)
+	if r.Checks == 0 {
+		fmt.Printf("%s[ INFO ]%s No checks in this range. Run 'gsm check %s' or keep the TUI open.\n", ColorCyan, ColorReset, r.Name)
+		return
+	}
+	fmt.Printf("Availability: %s (%d of %d checks online)\n", formatAvailability(r), r.Online, r.Checks)
+	fmt.Printf("History:      %s\n", spark)
+	fmt.Printf("Flapping:     %s (%d status changes)\n", yesNo(r.Flapping), r.Flaps)
+	if r.LastCheck != nil {
+		fmt.Printf("Last check:   %s (%s)\n", r.LastCheck.Local().Format("2006-01-02 15:04"), r.LastStatus)
+	}
+
+	if len(r.Outages) == 0 {
+		fmt.Println("\nNo outages.")
+		return
+	}
+	fmt.Printf("\n%sOutages%s\n", ColorBold, ColorReset)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "START\tEND\tDURATION\tSTATUS\tCHECKS")
+	for _, o := range r.Outages {
+		end := o.End.Local().Format("2006-01-02 15:04")
+		if o.Ongoing {
+			end = "ongoing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", o.Start.Local().Format("2006-01-02 15:04"), end, o.Duration().Round(time.Second), o.Status, o.Checks)
+	}
+	tw.Flush()
+}
+
+func formatAvailability(r uptime.Report) string {
+	if r.Checks == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", r.Availability)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	uptimeCmd.Flags().StringVarP(&uptimeRange, "range", "r", "24h", "Time range to report on, e.g. 1h, 24h, 7d, 30d")
+	uptimeCmd.Flags().BoolVar(&uptimeJSON, "json", false, "Print reports as JSON")
+}
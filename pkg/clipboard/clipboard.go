@@ -0,0 +1,88 @@
+// Package clipboard puts text on the clipboard with the OSC 52 terminal
+// escape, which reaches the local clipboard over SSH and through tmux or
+// screen, and optionally with local clipboard tools.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	local "github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// Modes of Copy and Clear.
+const (
+	// ModeOSC52 writes the OSC 52 escape to the terminal. It is the default.
+	ModeOSC52 = "osc52"
+	// ModeLocal uses the clipboard of the machine GSM runs on: pbcopy,
+	// xclip, xsel, wl-copy, termux or the Windows clipboard.
+	ModeLocal = "local"
+	// ModeBoth does both, for terminals that ignore OSC 52.
+	ModeBoth = "both"
+)
+
+// ErrNoTool is returned in ModeLocal when no clipboard tool is installed.
+var ErrNoTool = errors.New("no clipboard tool found (install xclip, xsel or wl-clipboard)")
+
+// CheckMode returns an error for an unknown mode. "" is ModeOSC52.
+func CheckMode(mode string) error {
+	switch mode {
+	case "", ModeOSC52, ModeLocal, ModeBoth:
+		return nil
+	}
+	return fmt.Errorf("unknown clipboard mode '%s' (use %s, %s or %s)", mode, ModeOSC52, ModeLocal, ModeBoth)
+}
+
+// Copy puts text on the clipboard, writing the OSC 52 escape to term and/or
+// running a local tool depending on mode. In ModeBoth a missing tool is not
+// an error.
+func Copy(term io.Writer, mode, text string) error {
+	return apply(term, mode, osc52.New(text), text)
+}
+
+// Clear empties the clipboard the same way Copy filled it.
+func Clear(term io.Writer, mode string) error {
+	return apply(term, mode, osc52.Clear(), "")
+}
+
+func apply(term io.Writer, mode string, seq osc52.Sequence, text string) error {
+	if err := CheckMode(mode); err != nil {
+		return err
+	}
+	if mode != ModeLocal {
+		if _, err := wrap(seq).WriteTo(term); err != nil {
+			return err
+		}
+	}
+	if mode == ModeLocal || mode == ModeBoth {
+		err := copyLocal(text)
+		if errors.Is(err, ErrNoTool) && mode == ModeBoth {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// wrap passes seq through tmux or screen to the outer terminal.
+func wrap(seq osc52.Sequence) osc52.Sequence {
+	switch {
+	case os.Getenv("TMUX") != "":
+		return seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		return seq.Screen()
+	}
+	return seq
+}
+
+// copyLocal puts text on the clipboard with a local tool.
+func copyLocal(text string) error {
+	if local.Unsupported {
+		return ErrNoTool
+	}
+	return local.WriteAll(text)
+}
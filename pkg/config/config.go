@@ -1,11 +1,18 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
+
+	"github.com/NumeXx/gsm/pkg/utils"
+	"github.com/NumeXx/gsm/pkg/wordlist"
 )
 
 // DefaultConfigFileName is the standard name for the configuration file.
@@ -19,19 +26,266 @@ var DefaultConfigFilePath string
 
 // Connection struct holds all data for a single GSocket connection entry.
 type Connection struct {
-	Name          string     `json:"name"`
-	Key           string     `json:"key"`
-	Tags          []string   `json:"tags,omitempty"`
-	Usage         int        `json:"usage,omitempty"`
-	LastConnected *time.Time `json:"last_connected,omitempty"`
+	Name          string           `json:"name"`
+	Key           string           `json:"key"`
+	Tags          []string         `json:"tags,omitempty"`
+	Usage         int              `json:"usage,omitempty"`
+	LastConnected *time.Time       `json:"last_connected,omitempty"`
+	CreatedAt     *time.Time       `json:"created_at,omitempty"`
+	Group         string           `json:"group,omitempty"`
+	Owner         string           `json:"owner,omitempty"`
+	Reconnect     *ReconnectPolicy `json:"reconnect,omitempty"`
+	// Record overrides Settings.RecordSessions for this connection when set.
+	Record *bool `json:"record,omitempty"`
+	// LastProbe is the result of the most recent liveness check.
+	LastProbe *ProbeRecord `json:"last_probe,omitempty"`
+	// Hooks run around sessions to this connection, after global and tag hooks.
+	Hooks *Hooks `json:"hooks,omitempty"`
+	// LocalPort is the local port a managed tunnel through this connection
+	// listens on (see gsm tunnel).
+	LocalPort int `json:"local_port,omitempty"`
+	// PendingKey is the new key of a rotation that hasn't been confirmed
+	// yet (see gsm rotate). Sessions keep using Key until then.
+	PendingKey string `json:"pending_key,omitempty"`
+	// PendingSince is when the pending rotation was started.
+	PendingSince *time.Time `json:"pending_since,omitempty"`
+	// KeyHistory records finished key rotations, oldest first.
+	KeyHistory []KeyRotation `json:"key_history,omitempty"`
+}
+
+// Key rotation results as stored in KeyRotation.Result.
+const (
+	RotationCompleted = "completed"
+	RotationAborted   = "aborted"
+)
+
+// KeyRotation is a finished key rotation. Keys are stored as fingerprints
+// only, so the history doesn't keep retired secrets around.
+type KeyRotation struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	OldKey   string    `json:"old_key"`
+	NewKey   string    `json:"new_key"`
+	Result   string    `json:"result"`
+}
+
+// KeyFingerprint returns a short, non-secret identifier of key.
+func KeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Hook is a local command run before or after a session.
+type Hook struct {
+	// Command is run by the shell (sh -c, or cmd /C on Windows).
+	Command string `json:"command"`
+	// Timeout bounds the command (default 30s).
+	Timeout Duration `json:"timeout,omitempty"`
+	// AbortOnFailure makes a failing pre-connect hook cancel the session.
+	AbortOnFailure bool `json:"abort_on_failure,omitempty"`
+}
+
+// Hooks are the commands run before a session starts and after it ends.
+type Hooks struct {
+	Pre  []Hook `json:"pre,omitempty"`
+	Post []Hook `json:"post,omitempty"`
+}
+
+// add appends the hooks of h, if any.
+func (h *Hooks) add(more *Hooks) {
+	if more != nil {
+		h.Pre = append(h.Pre, more.Pre...)
+		h.Post = append(h.Post, more.Post...)
+	}
+}
+
+// ProbeStatus classifies the result of a liveness probe.
+type ProbeStatus string
+
+const (
+	ProbeOnline           ProbeStatus = "online"
+	ProbeNoListener       ProbeStatus = "no-listener"
+	ProbeRelayUnreachable ProbeStatus = "relay-unreachable"
+	ProbeTimeout          ProbeStatus = "timeout"
+	ProbeError            ProbeStatus = "error"
+)
+
+// ProbeRecord is a stored liveness probe result.
+type ProbeRecord struct {
+	Status  ProbeStatus `json:"status"`
+	At      time.Time   `json:"at"`
+	Latency Duration    `json:"latency,omitempty"`
+	Detail  string      `json:"detail,omitempty"`
+}
+
+// ReconnectPolicy controls whether a dropped session is started again.
+// Zero values fall back to the defaults documented on each field.
+type ReconnectPolicy struct {
+	// MaxAttempts is the number of reconnects tried in a row before giving up (default 5).
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// InitialDelay is the wait before the first reconnect (default 2s).
+	InitialDelay Duration `json:"initial_delay,omitempty"`
+	// MaxDelay caps the exponential backoff (default 1m).
+	MaxDelay Duration `json:"max_delay,omitempty"`
+	// Multiplier grows the delay after each failed attempt (default 2).
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// Jitter randomises each delay by up to this fraction, 0 to 1 (default 0.2).
+	Jitter float64 `json:"jitter,omitempty"`
+	// StableAfter resets the attempt counter once a session stayed up this long (default 1m).
+	StableAfter Duration `json:"stable_after,omitempty"`
+	// RetryExitCodes, when set, limits reconnects to these gs-netcat exit codes.
+	RetryExitCodes []int `json:"retry_exit_codes,omitempty"`
+	// GiveUpExitCodes are exit codes that never trigger a reconnect.
+	GiveUpExitCodes []int `json:"give_up_exit_codes,omitempty"`
+}
+
+// Duration is a time.Duration stored in JSON as a string such as "1m30s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Plain numbers are read as seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var secs float64
+		if errNum := json.Unmarshal(data, &secs); errNum != nil {
+			return fmt.Errorf("invalid duration %s", string(data))
+		}
+		*d = Duration(secs * float64(time.Second))
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Settings holds global, connection independent options.
+type Settings struct {
+	// RecordSessions records every session to ~/.gsm/sessions unless a
+	// connection opts out.
+	RecordSessions bool `json:"record_sessions,omitempty"`
+	// ProbeInterval is how often the TUI re-checks listeners in the
+	// background (default 5m). A negative value turns background checks off.
+	ProbeInterval Duration `json:"probe_interval,omitempty"`
+	// ProbeParallel caps concurrent background checks (default 4).
+	ProbeParallel int `json:"probe_parallel,omitempty"`
+	// SortBy is the field the TUI sorts connections by: name, usage, last,
+	// created or status. Empty keeps the order of the config file.
+	SortBy string `json:"sort_by,omitempty"`
+	// SortDesc reverses the order of SortBy.
+	SortDesc bool `json:"sort_desc,omitempty"`
+	// Theme is the TUI color theme: dark, light, high-contrast, mono, a
+	// custom theme in ~/.gsm/themes or auto (the default).
+	Theme string `json:"theme,omitempty"`
+	// Keymap is the TUI key preset: default, vim or emacs.
+	Keymap string `json:"keymap,omitempty"`
+	// Keys rebinds TUI actions on top of the preset, e.g.
+	// {"delete": ["D"], "quit": ["q", "ctrl+q"]}. An empty list unbinds one.
+	Keys map[string][]string `json:"keys,omitempty"`
+	// Clipboard is how the TUI copies: osc52 (the default), local for
+	// clipboard tools such as xclip or pbcopy, or both.
+	Clipboard string `json:"clipboard,omitempty"`
+	// ClipboardClear is how long a copied key or command stays on the
+	// clipboard (default 30s). A negative value keeps it.
+	ClipboardClear Duration `json:"clipboard_clear,omitempty"`
+}
+
+// Defaults for background liveness checks.
+const (
+	DefaultProbeInterval = 5 * time.Minute
+	DefaultProbeParallel = 4
+)
+
+// BackgroundProbeInterval returns the interval between background checks,
+// or 0 if they are turned off.
+func (s Settings) BackgroundProbeInterval() time.Duration {
+	switch {
+	case s.ProbeInterval < 0:
+		return 0
+	case s.ProbeInterval == 0:
+		return DefaultProbeInterval
+	}
+	return time.Duration(s.ProbeInterval)
+}
+
+// BackgroundProbeParallel returns how many background checks may run at once.
+func (s Settings) BackgroundProbeParallel() int {
+	if s.ProbeParallel < 1 {
+		return DefaultProbeParallel
+	}
+	return s.ProbeParallel
+}
+
+// DefaultClipboardClear is how long copied secrets stay on the clipboard.
+const DefaultClipboardClear = 30 * time.Second
+
+// ClipboardClearAfter returns how long a copied secret stays on the
+// clipboard, or 0 if it is never cleared.
+func (s Settings) ClipboardClearAfter() time.Duration {
+	switch {
+	case s.ClipboardClear < 0:
+		return 0
+	case s.ClipboardClear == 0:
+		return DefaultClipboardClear
+	}
+	return time.Duration(s.ClipboardClear)
 }
 
 // Config struct holds all connections and global settings.
-// Currently, only connections are stored.
 type Config struct {
+	Settings Settings `json:"settings"`
+	// Hooks run around every session.
+	Hooks *Hooks `json:"hooks,omitempty"`
+	// TagHooks run around sessions to connections with the tag.
+	TagHooks map[string]*Hooks `json:"tag_hooks,omitempty"`
+	// Collections are saved queries, shown as virtual groups in the TUI.
+	Collections []Collection `json:"collections,omitempty"`
 	Connections []Connection `json:"connections"`
 }
 
+// Collection is a named query, e.g. "stale" for "seen>90d".
+type Collection struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// FindCollection returns the collection called name.
+func (c Config) FindCollection(name string) (Collection, bool) {
+	for _, col := range c.Collections {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Collection{}, false
+}
+
+// HooksFor returns the hooks for sessions to conn: global hooks first, then
+// those of its tags in tag order, then its own.
+func (c Config) HooksFor(conn Connection) Hooks {
+	var h Hooks
+	h.add(c.Hooks)
+	for _, tag := range conn.Tags {
+		h.add(c.TagHooks[tag])
+	}
+	h.add(conn.Hooks)
+	return h
+}
+
+// RecordingEnabled reports whether sessions to conn should be recorded.
+func (c Config) RecordingEnabled(conn Connection) bool {
+	if conn.Record != nil {
+		return *conn.Record
+	}
+	return c.Settings.RecordSessions
+}
+
 var currentConfig Config
 
 func init() {
@@ -71,10 +325,14 @@ func Load() error {
 		return nil
 	}
 
-	err = json.Unmarshal(data, &currentConfig)
+	// Decode into a fresh Config so nothing of a previous Load survives,
+	// e.g. hooks removed from the file while the session daemon runs.
+	var cfg Config
+	err = json.Unmarshal(data, &cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse config file '%s': %w", DefaultConfigFilePath, err)
 	}
+	currentConfig = cfg
 	return nil
 }
 
@@ -100,12 +358,72 @@ func Save() error {
 	return os.WriteFile(DefaultConfigFilePath, data, 0600)
 }
 
-// AddConnection adds a new connection to the current configuration.
+// AddConnection adds a new connection to the current configuration,
+// setting CreatedAt if it isn't set yet.
 // It does not automatically save; Save() must be called separately.
 func AddConnection(conn Connection) {
+	if conn.CreatedAt == nil {
+		now := time.Now()
+		conn.CreatedAt = &now
+	}
 	currentConfig.Connections = append(currentConfig.Connections, conn)
 }
 
+// SetSort stores the sort order of the TUI.
+// It does not automatically save; Save() must be called separately.
+func SetSort(by string, desc bool) {
+	currentConfig.Settings.SortBy = by
+	currentConfig.Settings.SortDesc = desc
+}
+
+// SetTheme sets the TUI color theme.
+// It does not automatically save; Save() must be called separately.
+func SetTheme(name string) {
+	currentConfig.Settings.Theme = name
+}
+
+// IndexOfConnection returns the index of the connection called name, or -1.
+func IndexOfConnection(name string) int {
+	for i, conn := range currentConfig.Connections {
+		if conn.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// generateAttempts bounds how often GenerateConnection draws a new key when
+// the name derived from it is already taken.
+const generateAttempts = 10
+
+// ErrNoFreeName is returned by GenerateConnection when every generated name
+// was taken.
+var ErrNoFreeName = errors.New("could not find a free name")
+
+// GenerateConnection returns a connection with a fresh secret called name.
+// Without a name it is named after its key, drawing new keys until the name
+// is free in the current configuration. It does not add the connection.
+func GenerateConnection(name string) (Connection, error) {
+	dictionary := wordlist.GetWords()
+	for range generateAttempts {
+		key, err := utils.GenerateSecret()
+		if err != nil {
+			return Connection{}, fmt.Errorf("generating key: %w", err)
+		}
+		if name != "" {
+			return Connection{Name: name, Key: key}, nil
+		}
+		mnemonic, err := utils.GenerateMnemonic(key, 3, dictionary)
+		if err != nil {
+			return Connection{}, fmt.Errorf("generating name: %w", err)
+		}
+		if IndexOfConnection(mnemonic) == -1 {
+			return Connection{Name: mnemonic, Key: key}, nil
+		}
+	}
+	return Connection{}, ErrNoFreeName
+}
+
 // UpdateConnectionByIndex updates an existing connection at a specific index.
 // It returns an error if the index is out of bounds.
 // It does not automatically save; Save() must be called separately.
@@ -117,6 +435,153 @@ func UpdateConnectionByIndex(index int, conn Connection) error {
 	return nil
 }
 
+// SetLastProbe records rec as the last probe of the connection called name.
+// It reports whether such a connection exists.
+// It does not automatically save; Save() must be called separately.
+func SetLastProbe(name string, rec ProbeRecord) bool {
+	idx := IndexOfConnection(name)
+	if idx == -1 {
+		return false
+	}
+	currentConfig.Connections[idx].LastProbe = &rec
+	return true
+}
+
+// RecordUsage bumps Usage and sets LastConnected of the connection called
+// name. It reports whether such a connection exists.
+// It does not automatically save; Save() must be called separately.
+func RecordUsage(name string, now time.Time) bool {
+	idx := IndexOfConnection(name)
+	if idx == -1 {
+		return false
+	}
+	currentConfig.Connections[idx].Usage++
+	currentConfig.Connections[idx].LastConnected = &now
+	return true
+}
+
+// QueryTagRenamer rewrites the references to the tag from in query q to to.
+// RenameTag uses it for collection queries; the query package can't be
+// imported here, so cmd/gsm sets it to query.RenameTag.
+var QueryTagRenamer func(q, from, to string) string
+
+// RenameTag replaces the tag from with to on every connection, merging it
+// into to where a connection already has both. Hooks of the tag move to to
+// and collection queries are rewritten with QueryTagRenamer. It returns the
+// number of connections changed.
+// It does not automatically save; Save() must be called separately.
+func RenameTag(from, to string) int {
+	if from == to {
+		return 0
+	}
+	if h, ok := currentConfig.TagHooks[from]; ok {
+		delete(currentConfig.TagHooks, from)
+		merged := currentConfig.TagHooks[to]
+		if merged == nil {
+			merged = &Hooks{}
+		}
+		merged.add(h)
+		currentConfig.TagHooks[to] = merged
+	}
+	if QueryTagRenamer != nil {
+		for i := range currentConfig.Collections {
+			c := &currentConfig.Collections[i]
+			c.Query = QueryTagRenamer(c.Query, from, to)
+		}
+	}
+
+	changed := 0
+	for i := range currentConfig.Connections {
+		conn := &currentConfig.Connections[i]
+		idx := slices.Index(conn.Tags, from)
+		if idx == -1 {
+			continue
+		}
+		if slices.Contains(conn.Tags, to) {
+			conn.Tags = slices.Delete(conn.Tags, idx, idx+1)
+		} else {
+			conn.Tags[idx] = to
+		}
+		changed++
+	}
+	return changed
+}
+
+// SetCollection saves q as the collection called name, replacing the query
+// of an existing one.
+// It does not automatically save; Save() must be called separately.
+func SetCollection(name, q string) {
+	for i := range currentConfig.Collections {
+		if currentConfig.Collections[i].Name == name {
+			currentConfig.Collections[i].Query = q
+			return
+		}
+	}
+	currentConfig.Collections = append(currentConfig.Collections, Collection{Name: name, Query: q})
+}
+
+// DeleteCollection removes the collection called name and reports whether
+// it existed.
+// It does not automatically save; Save() must be called separately.
+func DeleteCollection(name string) bool {
+	for i := range currentConfig.Collections {
+		if currentConfig.Collections[i].Name == name {
+			currentConfig.Collections = slices.Delete(currentConfig.Collections, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// StartRotation stages newKey as the pending key of the connection called
+// name. It fails if a rotation is already pending.
+// It does not automatically save; Save() must be called separately.
+func StartRotation(name, newKey string, now time.Time) error {
+	idx := IndexOfConnection(name)
+	if idx == -1 {
+		return fmt.Errorf("connection '%s' not found", name)
+	}
+	conn := &currentConfig.Connections[idx]
+	if conn.PendingKey != "" {
+		return fmt.Errorf("a rotation of '%s' is already pending", name)
+	}
+	conn.PendingKey = newKey
+	conn.PendingSince = &now
+	return nil
+}
+
+// FinishRotation ends the pending rotation of the connection called name
+// and records it in the key history. A completed rotation replaces Key
+// with the pending key; an aborted one drops the pending key.
+// It does not automatically save; Save() must be called separately.
+func FinishRotation(name string, completed bool, now time.Time) error {
+	idx := IndexOfConnection(name)
+	if idx == -1 {
+		return fmt.Errorf("connection '%s' not found", name)
+	}
+	conn := &currentConfig.Connections[idx]
+	if conn.PendingKey == "" {
+		return fmt.Errorf("no rotation of '%s' is pending", name)
+	}
+	rot := KeyRotation{
+		Finished: now,
+		OldKey:   KeyFingerprint(conn.Key),
+		NewKey:   KeyFingerprint(conn.PendingKey),
+		Result:   RotationAborted,
+	}
+	if conn.PendingSince != nil {
+		rot.Started = *conn.PendingSince
+	}
+	if completed {
+		conn.Key = conn.PendingKey
+		rot.Result = RotationCompleted
+	}
+	conn.KeyHistory = append(conn.KeyHistory, rot)
+	conn.PendingKey = ""
+	conn.PendingSince = nil
+	return nil
+}
+
 // DeleteConnectionByIndex removes a connection at a specific index.
 // It returns an error if the index is out of bounds.
 // It does not automatically save; Save() must be called separately.
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useConfig points DefaultConfigFilePath at a temporary file holding data
+// and loads it.
+func useConfig(t *testing.T, data string) {
+	t.Helper()
+	saved := DefaultConfigFilePath
+	t.Cleanup(func() { DefaultConfigFilePath = saved })
+	DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(DefaultConfigFilePath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tagsOf(name string) []string {
+	return currentConfig.Connections[IndexOfConnection(name)].Tags
+}
+
+func TestRenameTag(t *testing.T) {
+	useConfig(t, `{
+		"tag_hooks": {
+			"prod": {"pre": [{"command": "echo prod"}]},
+			"live": {"post": [{"command": "echo live"}]}
+		},
+		"collections": [{"name": "prod", "query": "tag:prod seen<7d"}],
+		"connections": [
+			{"name": "web-1", "key": "k1", "tags": ["prod", "web"]},
+			{"name": "web-2", "key": "k2", "tags": ["live", "prod"]},
+			{"name": "lab", "key": "k3", "tags": ["lab"]}
+		]
+	}`)
+	saved := QueryTagRenamer
+	t.Cleanup(func() { QueryTagRenamer = saved })
+	QueryTagRenamer = func(q, from, to string) string {
+		return strings.ReplaceAll(q, "tag:"+from, "tag:"+to)
+	}
+
+	if n := RenameTag("prod", "live"); n != 2 {
+		t.Errorf("RenameTag changed %d connections, want 2", n)
+	}
+	if got := tagsOf("web-1"); !slices.Equal(got, []string{"live", "web"}) {
+		t.Errorf("web-1 tags = %v, want [live web]", got)
+	}
+	if got := tagsOf("web-2"); !slices.Equal(got, []string{"live"}) {
+		t.Errorf("web-2 tags = %v, want the tags merged into [live]", got)
+	}
+	if got := tagsOf("lab"); !slices.Equal(got, []string{"lab"}) {
+		t.Errorf("lab tags = %v, want them unchanged", got)
+	}
+
+	if _, ok := currentConfig.TagHooks["prod"]; ok {
+		t.Error("the hooks of prod were not moved")
+	}
+	h := currentConfig.TagHooks["live"]
+	if h == nil || len(h.Pre) != 1 || h.Pre[0].Command != "echo prod" || len(h.Post) != 1 {
+		t.Errorf("live hooks = %+v, want those of prod merged in", h)
+	}
+	if col, _ := GetCurrent().FindCollection("prod"); col.Query != "tag:live seen<7d" {
+		t.Errorf("collection query = %q, want it rewritten", col.Query)
+	}
+
+	if n := RenameTag("live", "live"); n != 0 {
+		t.Errorf("renaming a tag to itself changed %d connections", n)
+	}
+}
+
+func TestRenameTagWithoutHooks(t *testing.T) {
+	useConfig(t, `{"connections": [{"name": "web-1", "key": "k1", "tags": ["prod"]}]}`)
+	if n := RenameTag("prod", "live"); n != 1 {
+		t.Errorf("RenameTag changed %d connections, want 1", n)
+	}
+	if currentConfig.TagHooks != nil {
+		t.Errorf("TagHooks = %v, want none", currentConfig.TagHooks)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	useConfig(t, "")
+	SetCollection("stale", "seen>90d")
+	SetCollection("prod", "tag:prod")
+	SetCollection("stale", "seen>30d")
+
+	cfg := GetCurrent()
+	if len(cfg.Collections) != 2 {
+		t.Fatalf("collections = %+v, want 2", cfg.Collections)
+	}
+	if col, ok := cfg.FindCollection("stale"); !ok || col.Query != "seen>30d" {
+		t.Errorf("FindCollection(stale) = %+v, %v, want the updated query", col, ok)
+	}
+
+	if !DeleteCollection("stale") {
+		t.Error("DeleteCollection(stale) = false, want true")
+	}
+	if DeleteCollection("stale") {
+		t.Error("deleting stale twice reported true")
+	}
+	if _, ok := GetCurrent().FindCollection("stale"); ok {
+		t.Error("stale is still there after DeleteCollection")
+	}
+
+	if err := Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cols := GetCurrent().Collections; len(cols) != 1 || cols[0] != (Collection{Name: "prod", Query: "tag:prod"}) {
+		t.Errorf("collections after reload = %+v, want only prod", cols)
+	}
+}
+
+func TestHooksFor(t *testing.T) {
+	useConfig(t, `{
+		"hooks": {"pre": [{"command": "global"}]},
+		"tag_hooks": {
+			"prod": {"pre": [{"command": "prod"}]},
+			"web": {"pre": [{"command": "web"}], "post": [{"command": "web-post"}]}
+		},
+		"connections": [
+			{"name": "web-1", "key": "k1", "tags": ["web", "prod"], "hooks": {"pre": [{"command": "own"}]}}
+		]
+	}`)
+	h := GetCurrent().HooksFor(currentConfig.Connections[0])
+	var pre []string
+	for _, hook := range h.Pre {
+		pre = append(pre, hook.Command)
+	}
+	if !slices.Equal(pre, []string{"global", "web", "prod", "own"}) {
+		t.Errorf("pre hooks = %v, want global, tag then own hooks", pre)
+	}
+	if len(h.Post) != 1 || h.Post[0].Command != "web-post" {
+		t.Errorf("post hooks = %+v, want web-post", h.Post)
+	}
+}
+
+func TestGenerateConnection(t *testing.T) {
+	useConfig(t, "")
+	conn, err := GenerateConnection("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Name == "" || conn.Key == "" {
+		t.Fatalf("GenerateConnection = %+v, want a name and a key", conn)
+	}
+	AddConnection(conn)
+	if GetCurrent().Connections[0].CreatedAt == nil {
+		t.Error("AddConnection did not set CreatedAt")
+	}
+
+	named, err := GenerateConnection("LabBox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if named.Name != "LabBox" || named.Key == "" || named.Key == conn.Key {
+		t.Errorf("GenerateConnection(LabBox) = %+v, want LabBox with a new key", named)
+	}
+}
+
+func TestConnectionMutations(t *testing.T) {
+	useConfig(t, `{"connections": [{"name": "a", "key": "k1"}, {"name": "b", "key": "k2"}]}`)
+	if IndexOfConnection("b") != 1 || IndexOfConnection("c") != -1 {
+		t.Fatal("IndexOfConnection did not find the connections")
+	}
+	if err := UpdateConnectionByIndex(2, Connection{}); err == nil {
+		t.Error("UpdateConnectionByIndex out of bounds succeeded")
+	}
+	if err := DeleteConnectionByIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := GetCurrent(); len(cfg.Connections) != 1 || cfg.Connections[0].Name != "b" {
+		t.Errorf("connections after delete = %+v, want only b", cfg.Connections)
+	}
+	if err := DeleteConnectionByIndex(1); err == nil {
+		t.Error("DeleteConnectionByIndex out of bounds succeeded")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	useConfig(t, `{"connections": [{"name": "a", "key": "old-key"}]}`)
+	now := time.Now()
+	if err := FinishRotation("a", true, now); err == nil {
+		t.Error("FinishRotation without a pending rotation succeeded")
+	}
+	if err := StartRotation("a", "new-key", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := StartRotation("a", "other-key", now); err == nil {
+		t.Error("a second StartRotation succeeded")
+	}
+	if err := FinishRotation("a", true, now); err != nil {
+		t.Fatal(err)
+	}
+	conn := GetCurrent().Connections[0]
+	if conn.Key != "new-key" || conn.PendingKey != "" || len(conn.KeyHistory) != 1 || conn.KeyHistory[0].Result != RotationCompleted {
+		t.Errorf("connection after rotation = %+v", conn)
+	}
+	if err := StartRotation("missing", "k", now); err == nil {
+		t.Error("StartRotation of a missing connection succeeded")
+	}
+
+	if err := StartRotation("a", "unused-key", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := FinishRotation("a", false, now); err != nil {
+		t.Fatal(err)
+	}
+	conn = GetCurrent().Connections[0]
+	if conn.Key != "new-key" || conn.PendingKey != "" || len(conn.KeyHistory) != 2 || conn.KeyHistory[1].Result != RotationAborted {
+		t.Errorf("connection after an aborted rotation = %+v", conn)
+	}
+}
+
+func TestLoadReplacesPreviousConfig(t *testing.T) {
+	useConfig(t, `{"hooks": {"pre": [{"command": "echo pre"}]}, "connections": [{"name": "a", "key": "k1"}]}`)
+	if err := os.WriteFile(DefaultConfigFilePath, []byte(`{"connections": [{"name": "b", "key": "k2"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetCurrent()
+	if cfg.Hooks != nil || len(cfg.Connections) != 1 || cfg.Connections[0].Name != "b" {
+		t.Errorf("config after reload = %+v, want only what the file holds", cfg)
+	}
+}
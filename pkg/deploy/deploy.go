@@ -0,0 +1,174 @@
+// Package deploy renders the artifacts that start the listening side of a
+// connection on a target machine: a shell one-liner, a systemd unit, a cron
+// entry, a Dockerfile or a FreeBSD rc.d script.
+//
+// The built-in templates can be overridden, and new formats added, by
+// placing <format>.tmpl files in ~/.gsm/templates. Templates use text/template
+// with a Data value.
+package deploy
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+// TemplateDirName is the directory under the config dir holding custom templates.
+const TemplateDirName = "templates"
+
+// templateExt is the file extension of template files.
+const templateExt = ".tmpl"
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// Data is what templates are rendered with.
+type Data struct {
+	Name string
+	Key  string
+	Tags []string
+	// Args are the gs-netcat arguments of the listener.
+	Args []string
+	// ArgLine is Args joined for a shell, quoted where needed.
+	ArgLine string
+	// Command is the full gs-netcat command line of the listener.
+	Command string
+	// Service is a name for the service or container, e.g. "gsm-labbox".
+	Service string
+	// RCName is Service in a form usable as an rc.d variable, e.g. "gsm_labbox".
+	RCName string
+}
+
+// NewData returns the template data for the listener of conn.
+func NewData(conn config.Connection) Data {
+	args := runner.ListenerArgs(conn)
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	argLine := strings.Join(quoted, " ")
+	service := "gsm-" + slug(conn.Name)
+	return Data{
+		Name:    conn.Name,
+		Key:     conn.Key,
+		Tags:    conn.Tags,
+		Args:    args,
+		ArgLine: argLine,
+		Command: "gs-netcat " + argLine,
+		Service: service,
+		RCName:  strings.ReplaceAll(service, "-", "_"),
+	}
+}
+
+// TemplateDir returns the directory holding custom templates.
+func TemplateDir() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigFilePath), TemplateDirName)
+}
+
+// Formats returns the names of the built-in and custom formats, sorted.
+func Formats() []string {
+	seen := map[string]bool{}
+	entries, _ := builtin.ReadDir("templates")
+	for _, e := range entries {
+		seen[strings.TrimSuffix(e.Name(), templateExt)] = true
+	}
+	custom, _ := filepath.Glob(filepath.Join(TemplateDir(), "*"+templateExt))
+	for _, path := range custom {
+		seen[strings.TrimSuffix(filepath.Base(path), templateExt)] = true
+	}
+	formats := make([]string, 0, len(seen))
+	for f := range seen {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Render writes the format artifact for the listener of conn to w. A
+// template in TemplateDir takes precedence over the built-in one.
+func Render(w io.Writer, format string, conn config.Connection) error {
+	text, err := loadTemplate(format)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(format).Funcs(template.FuncMap{
+		"join":    strings.Join,
+		"quote":   shellQuote,
+		"cron":    cronEscape,
+		"systemd": systemdEscape,
+	}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", format, err)
+	}
+	return tmpl.Execute(w, NewData(conn))
+}
+
+func loadTemplate(format string) (string, error) {
+	if format == "" || strings.ContainsAny(format, `/\`) || format == "." || format == ".." {
+		return "", fmt.Errorf("invalid format %q", format)
+	}
+	data, err := os.ReadFile(filepath.Join(TemplateDir(), format+templateExt))
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	data, err = builtin.ReadFile("templates/" + format + templateExt)
+	if err != nil {
+		return "", fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return string(data), nil
+}
+
+// shellQuote quotes s for a POSIX shell unless it only has safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@%+", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cronEscape escapes the % signs in s, which cron would otherwise turn into
+// newlines in the command field of a crontab entry.
+func cronEscape(s string) string {
+	return strings.ReplaceAll(s, "%", `\%`)
+}
+
+// systemdEscape escapes the % signs in s, which systemd reads as specifiers
+// in unit files.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// slug lowercases name and replaces everything but letters and digits with
+// dashes.
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	s := strings.TrimSuffix(b.String(), "-")
+	if s == "" {
+		s = "listener"
+	}
+	return s
+}
@@ -0,0 +1,166 @@
+package deploy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// useTemplateDir keeps custom templates in a temporary config directory.
+func useTemplateDir(t *testing.T) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"abcXYZ019":     "abcXYZ019",
+		"-s":            "-s",
+		"a.b/c=d:e,f@g": "a.b/c=d:e,f@g",
+		"50%+1":         "50%+1",
+		"":              "''",
+		"two words":     "'two words'",
+		"$HOME":         "'$HOME'",
+		"it's":          `'it'\''s'`,
+		"a;b":           "'a;b'",
+		"new\nline":     "'new\nline'",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for in, want := range map[string]string{
+		"labbox":        "labbox",
+		"Web Server 1":  "web-server-1",
+		"db--prod__eu":  "db-prod-eu",
+		"  (client) X ": "client-x",
+		"ünïcode":       "n-code",
+		"!!!":           "listener",
+		"":              "listener",
+	} {
+		if got := slug(in); got != want {
+			t.Errorf("slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	if got := cronEscape(`gs-netcat -s 'a%b%' -l`); got != `gs-netcat -s 'a\%b\%' -l` {
+		t.Errorf("cronEscape = %q", got)
+	}
+	if got := systemdEscape(`-s 'a%b'`); got != `-s 'a%%b'` {
+		t.Errorf("systemdEscape = %q", got)
+	}
+}
+
+func TestNewData(t *testing.T) {
+	d := NewData(config.Connection{Name: "Lab Box", Key: "my key", Tags: []string{"lab"}})
+	if want := []string{"-s", "my key", "-l", "-i"}; !slices.Equal(d.Args, want) {
+		t.Errorf("Args = %q, want %q", d.Args, want)
+	}
+	if d.ArgLine != "-s 'my key' -l -i" || d.Command != "gs-netcat -s 'my key' -l -i" {
+		t.Errorf("ArgLine = %q, Command = %q", d.ArgLine, d.Command)
+	}
+	if d.Service != "gsm-lab-box" || d.RCName != "gsm_lab_box" {
+		t.Errorf("Service = %q, RCName = %q", d.Service, d.RCName)
+	}
+}
+
+func TestRenderBuiltin(t *testing.T) {
+	useTemplateDir(t)
+	conn := config.Connection{Name: "labbox", Key: "ab%cd"}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"oneliner", []string{"gs-netcat -s ab%cd -l -i -D\n"}},
+		{"cron", []string{`@reboot gs-netcat -s ab\%cd -l -i -D >/dev/null 2>&1`}},
+		{"systemd", []string{"ExecStart=/usr/bin/gs-netcat -s ab%%cd -l -i\n", "systemctl enable --now gsm-labbox"}},
+		{"docker", []string{`CMD ["gs-netcat", "-s", "ab%cd", "-l", "-i"]`}},
+		{"rcd", []string{`name="gsm_labbox"`, "/usr/local/bin/gs-netcat -s ab%cd -l -i\""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Render(&out, tt.format, conn); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q does not contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCustom(t *testing.T) {
+	useTemplateDir(t)
+	if err := os.MkdirAll(TemplateDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range map[string]string{
+		"oneliner": "custom {{.Service}}",
+		"tmux":     `tmux new -d -s {{quote .Name}} {{quote .Command}} # {{join .Tags ","}}`,
+		"broken":   "{{.Name",
+	} {
+		if err := os.WriteFile(filepath.Join(TemplateDir(), name+templateExt), []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn := config.Connection{Name: "lab box", Key: "k1", Tags: []string{"lab", "eu"}}
+
+	for format, want := range map[string]string{
+		"oneliner": "custom gsm-lab-box",
+		"tmux":     `tmux new -d -s 'lab box' 'gs-netcat -s k1 -l -i' # lab,eu`,
+	} {
+		var out bytes.Buffer
+		if err := Render(&out, format, conn); err != nil {
+			t.Fatalf("Render(%s): %v", format, err)
+		}
+		if out.String() != want {
+			t.Errorf("Render(%s) = %q, want %q", format, out.String(), want)
+		}
+	}
+
+	want := []string{"broken", "cron", "docker", "oneliner", "rcd", "systemd", "tmux"}
+	if got := Formats(); !slices.Equal(got, want) {
+		t.Errorf("Formats = %q, want %q", got, want)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	useTemplateDir(t)
+	if err := os.MkdirAll(TemplateDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(TemplateDir(), "broken"+templateExt), []byte("{{.Name"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "invalid format"},
+		{"..", "invalid format"},
+		{"../config", "invalid format"},
+		{"ansible", "unknown format \"ansible\" (available: broken, cron, docker, oneliner, rcd, systemd)"},
+		{"broken", "template broken"},
+	}
+	for _, tt := range tests {
+		err := Render(&bytes.Buffer{}, tt.format, config.Connection{Name: "labbox", Key: "k1"})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Render(%q) = %v, want an error containing %q", tt.format, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package hooks runs user-defined local commands before a session starts and
+// after it ends, e.g. to log to a tracker, set the terminal title or send a
+// notification. Hooks learn about the session through GSM_* environment
+// variables.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// DefaultTimeout bounds hooks that don't set their own timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Phases, passed to hooks as GSM_HOOK_PHASE.
+const (
+	PhasePre  = "pre"
+	PhasePost = "post"
+)
+
+// AbortError is returned when a pre-connect hook with AbortOnFailure failed.
+type AbortError struct {
+	Command string
+	Err     error
+}
+
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("pre-connect hook %q failed: %v", e.Command, e.Err)
+}
+
+func (e *AbortError) Unwrap() error { return e.Err }
+
+// Env returns the variables describing a session to conn for a hook.
+// Duration and exitStatus are only meaningful after the session.
+func Env(phase string, conn config.Connection, duration time.Duration, exitStatus int) []string {
+	env := []string{
+		"GSM_HOOK_PHASE=" + phase,
+		"GSM_CONN_NAME=" + conn.Name,
+		"GSM_CONN_TAGS=" + strings.Join(conn.Tags, ","),
+	}
+	if phase == PhasePost {
+		env = append(env,
+			"GSM_SESSION_DURATION="+strconv.Itoa(int(duration.Seconds())),
+			"GSM_EXIT_STATUS="+strconv.Itoa(exitStatus),
+		)
+	}
+	return env
+}
+
+// RunPre runs the pre-connect hooks for conn. It stops at and returns an
+// *AbortError for the first failing hook with AbortOnFailure set; other
+// failures are reported on stderr.
+func RunPre(conn config.Connection, hooks []config.Hook, stdout, stderr io.Writer) error {
+	return run(hooks, Env(PhasePre, conn, 0, 0), stdout, stderr, true)
+}
+
+// RunPost runs the post-session hooks for conn. Failures are reported on
+// stderr.
+func RunPost(conn config.Connection, hooks []config.Hook, duration time.Duration, exitStatus int, stdout, stderr io.Writer) {
+	run(hooks, Env(PhasePost, conn, duration, exitStatus), stdout, stderr, false) //nolint:errcheck
+}
+
+func run(hooks []config.Hook, env []string, stdout, stderr io.Writer, canAbort bool) error {
+	for _, hook := range hooks {
+		if strings.TrimSpace(hook.Command) == "" {
+			continue
+		}
+		err := runOne(hook, env, stdout, stderr)
+		if err == nil {
+			continue
+		}
+		if canAbort && hook.AbortOnFailure {
+			return &AbortError{Command: hook.Command, Err: err}
+		}
+		fmt.Fprintf(stderr, "[!] Hook %q failed: %v\n", hook.Command, err)
+	}
+	return nil
+}
+
+func runOne(hook config.Hook, env []string, stdout, stderr io.Writer) error {
+	timeout := time.Duration(hook.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", hook.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+	return err
+}
@@ -0,0 +1,407 @@
+// Package query parses and evaluates the small query language used to
+// filter connections, e.g. `tag:prod usage>5 seen<7d` or
+// `-tag:lab or owner=alice`.
+//
+// A query is a list of terms that all have to match. The keyword "or"
+// separates alternatives, each a list of terms. A term is a field predicate
+// such as tag:prod or usage>=3, or a bare word that matches the name, group
+// or tags. A leading "-" or "!", or the keyword "not", negates a term.
+//
+// Fields:
+//
+//	name, group, owner  ":" contains (or glob with * and ?), "=" and "!=" compare whole values
+//	tag                 ":" and "=" have the tag (globs allowed), "!=" lacks it
+//	status              online, no-listener, relay-unreachable, timeout, error or unknown
+//	usage               number of sessions, compared with = != < <= > >=
+//	seen, created       age of the last session or of the connection, e.g. seen<7d;
+//	                    seen:never matches connections never used
+//
+// Durations are Go durations (90m, 12h) or days and weeks (7d, 2w).
+// String comparisons ignore case. Values with spaces can be double-quoted.
+package query
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/utils"
+)
+
+// Query is a parsed query. The zero Query matches every connection.
+type Query struct {
+	// alternatives are OR-ed; the terms of each are AND-ed.
+	alternatives [][]term
+	text         string
+}
+
+type term struct {
+	negate bool
+	match  func(conn config.Connection, now time.Time) bool
+}
+
+// Operators, longest first so that ">=" isn't read as ">".
+var operators = []string{"!=", ">=", "<=", ":", "=", ">", "<"}
+
+// Fields lists the fields a query can test.
+var Fields = []string{"name", "tag", "group", "owner", "status", "usage", "seen", "created"}
+
+// Parse parses s. An empty s gives a query that matches everything.
+func Parse(s string) (*Query, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{text: strings.TrimSpace(s)}
+	var current []term
+	negateNext := false
+	for _, tok := range tokens {
+		if !tok.quoted {
+			switch strings.ToLower(tok.text) {
+			case "or":
+				if negateNext || len(current) == 0 {
+					return nil, fmt.Errorf("'or' needs a term on both sides")
+				}
+				q.alternatives = append(q.alternatives, current)
+				current = nil
+				continue
+			case "and":
+				continue
+			case "not":
+				negateNext = !negateNext
+				continue
+			}
+		}
+		t, err := parseTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		t.negate = t.negate != negateNext
+		negateNext = false
+		current = append(current, t)
+	}
+	if negateNext {
+		return nil, fmt.Errorf("'not' needs a term after it")
+	}
+	if len(current) == 0 && len(q.alternatives) > 0 {
+		return nil, fmt.Errorf("'or' needs a term on both sides")
+	}
+	if len(current) > 0 {
+		q.alternatives = append(q.alternatives, current)
+	}
+	return q, nil
+}
+
+// String returns the text q was parsed from.
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.text
+}
+
+// Empty reports whether q matches everything.
+func (q *Query) Empty() bool {
+	return q == nil || len(q.alternatives) == 0
+}
+
+// Match reports whether conn matches q at time now.
+func (q *Query) Match(conn config.Connection, now time.Time) bool {
+	if q.Empty() {
+		return true
+	}
+	for _, terms := range q.alternatives {
+		all := true
+		for _, t := range terms {
+			if t.match(conn, now) == t.negate {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the connections of conns that match q, in order.
+func (q *Query) Filter(conns []config.Connection, now time.Time) []config.Connection {
+	var matched []config.Connection
+	for _, conn := range conns {
+		if q.Match(conn, now) {
+			matched = append(matched, conn)
+		}
+	}
+	return matched
+}
+
+// RenameTag returns s with the terms that test for the tag from (tag:from,
+// tag=from, tag!=from, negated or not) testing for to instead. Globs and
+// everything else are left as written. A query that doesn't parse is
+// returned unchanged.
+func RenameTag(s, from, to string) string {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, tok := range tokens {
+		text := tok.text
+		prefix := ""
+		if len(text) > 1 && (text[0] == '-' || text[0] == '!') {
+			prefix, text = text[:1], text[1:]
+		}
+		field, op, value, ok := splitPredicate(text)
+		if !ok || field != "tag" || (op != ":" && op != "=" && op != "!=") || !strings.EqualFold(value, from) {
+			continue
+		}
+		b.WriteString(s[last:tok.start])
+		b.WriteString(prefix + text[:len(text)-len(value)] + quote(to))
+		last = tok.end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// quote double-quotes value if it contains spaces.
+func quote(value string) string {
+	if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+type token struct {
+	text string
+	// quoted is set if any part of the token was quoted, so "or" in quotes
+	// isn't a keyword.
+	quoted bool
+	// start and end are the byte offsets of the token in the query text.
+	start, end int
+}
+
+// tokenize splits s at spaces outside double quotes and removes the quotes.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	var cur strings.Builder
+	inQuotes, quoted, started := false, false, false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			if !started {
+				start = i
+			}
+			inQuotes = !inQuotes
+			quoted, started = true, true
+		case unicode.IsSpace(r) && !inQuotes:
+			if started {
+				tokens = append(tokens, token{text: cur.String(), quoted: quoted, start: start, end: i})
+				cur.Reset()
+				quoted, started = false, false
+			}
+		default:
+			if !started {
+				start = i
+			}
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if started {
+		tokens = append(tokens, token{text: cur.String(), quoted: quoted, start: start, end: len(s)})
+	}
+	return tokens, nil
+}
+
+func parseTerm(tok token) (term, error) {
+	text := tok.text
+	var t term
+	if len(text) > 1 && (text[0] == '-' || text[0] == '!') {
+		t.negate = true
+		text = text[1:]
+	}
+
+	field, op, value, ok := splitPredicate(text)
+	if !ok {
+		word := strings.ToLower(text)
+		t.match = func(conn config.Connection, _ time.Time) bool {
+			return strings.Contains(strings.ToLower(conn.Name), word) ||
+				strings.Contains(strings.ToLower(conn.Group), word) ||
+				slices.ContainsFunc(conn.Tags, func(tag string) bool { return strings.Contains(strings.ToLower(tag), word) })
+		}
+		return t, nil
+	}
+
+	var err error
+	switch field {
+	case "name":
+		t.match, err = stringPredicate(field, op, value, func(c config.Connection) string { return c.Name })
+	case "group":
+		t.match, err = stringPredicate(field, op, value, func(c config.Connection) string { return c.Group })
+	case "owner":
+		t.match, err = stringPredicate(field, op, value, func(c config.Connection) string { return c.Owner })
+	case "tag":
+		t.match, err = tagPredicate(op, value)
+	case "status":
+		t.match, err = statusPredicate(op, value)
+	case "usage":
+		t.match, err = usagePredicate(op, value)
+	case "seen":
+		t.match, err = agePredicate(field, op, value, func(c config.Connection) *time.Time { return c.LastConnected })
+	case "created":
+		t.match, err = agePredicate(field, op, value, func(c config.Connection) *time.Time { return c.CreatedAt })
+	default:
+		err = fmt.Errorf("unknown field '%s' (use %s)", field, strings.Join(Fields, ", "))
+	}
+	return t, err
+}
+
+// splitPredicate splits "field<op>value". It reports false for bare words.
+func splitPredicate(text string) (field, op, value string, ok bool) {
+	end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end <= 0 {
+		return "", "", "", false
+	}
+	for _, candidate := range operators {
+		if strings.HasPrefix(text[end:], candidate) {
+			return strings.ToLower(text[:end]), candidate, text[end+len(candidate):], true
+		}
+	}
+	return "", "", "", false
+}
+
+func stringPredicate(field, op, value string, get func(config.Connection) string) (func(config.Connection, time.Time) bool, error) {
+	want := strings.ToLower(value)
+	switch op {
+	case ":":
+		if strings.ContainsAny(want, "*?[") {
+			if _, err := path.Match(want, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s'", value)
+			}
+			return func(c config.Connection, _ time.Time) bool {
+				ok, _ := path.Match(want, strings.ToLower(get(c)))
+				return ok
+			}, nil
+		}
+		return func(c config.Connection, _ time.Time) bool {
+			return strings.Contains(strings.ToLower(get(c)), want)
+		}, nil
+	case "=":
+		return func(c config.Connection, _ time.Time) bool { return strings.ToLower(get(c)) == want }, nil
+	case "!=":
+		return func(c config.Connection, _ time.Time) bool { return strings.ToLower(get(c)) != want }, nil
+	}
+	return nil, fmt.Errorf("%s can't be compared with '%s'", field, op)
+}
+
+func tagPredicate(op, value string) (func(config.Connection, time.Time) bool, error) {
+	want := strings.ToLower(value)
+	if _, err := path.Match(want, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s'", value)
+	}
+	has := func(c config.Connection, _ time.Time) bool {
+		return slices.ContainsFunc(c.Tags, func(tag string) bool {
+			ok, _ := path.Match(want, strings.ToLower(tag))
+			return ok
+		})
+	}
+	switch op {
+	case ":", "=":
+		return has, nil
+	case "!=":
+		return func(c config.Connection, now time.Time) bool { return !has(c, now) }, nil
+	}
+	return nil, fmt.Errorf("tag can't be compared with '%s'", op)
+}
+
+// statusUnknown matches connections that were never checked.
+const statusUnknown = "unknown"
+
+func statusPredicate(op, value string) (func(config.Connection, time.Time) bool, error) {
+	want := strings.ToLower(value)
+	valid := []string{statusUnknown, string(config.ProbeOnline), string(config.ProbeNoListener), string(config.ProbeRelayUnreachable), string(config.ProbeTimeout), string(config.ProbeError)}
+	if !slices.Contains(valid, want) {
+		return nil, fmt.Errorf("unknown status '%s' (use %s)", value, strings.Join(valid, ", "))
+	}
+	is := func(c config.Connection, _ time.Time) bool {
+		if c.LastProbe == nil {
+			return want == statusUnknown
+		}
+		return string(c.LastProbe.Status) == want
+	}
+	switch op {
+	case ":", "=":
+		return is, nil
+	case "!=":
+		return func(c config.Connection, now time.Time) bool { return !is(c, now) }, nil
+	}
+	return nil, fmt.Errorf("status can't be compared with '%s'", op)
+}
+
+func usagePredicate(op, value string) (func(config.Connection, time.Time) bool, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("usage needs a number, not '%s'", value)
+	}
+	return func(c config.Connection, _ time.Time) bool {
+		return compare(op, float64(c.Usage), float64(n))
+	}, nil
+}
+
+func agePredicate(field, op, value string, get func(config.Connection) *time.Time) (func(config.Connection, time.Time) bool, error) {
+	if strings.EqualFold(value, "never") {
+		switch op {
+		case ":", "=":
+			return func(c config.Connection, _ time.Time) bool { return get(c) == nil }, nil
+		case "!=":
+			return func(c config.Connection, _ time.Time) bool { return get(c) != nil }, nil
+		}
+		return nil, fmt.Errorf("%s can't be compared with '%s' never", field, op)
+	}
+	if op == ":" {
+		// An age is practically never equal to a duration.
+		return nil, fmt.Errorf("%s:%s takes only never; compare ages with < or >, e.g. %s<%s", field, value, field, value)
+	}
+	d, err := utils.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s needs a duration such as 7d: %w", field, err)
+	}
+	return func(c config.Connection, now time.Time) bool {
+		// A connection never used is older than any duration.
+		age := math.Inf(1)
+		if t := get(c); t != nil {
+			age = float64(now.Sub(*t))
+		}
+		return compare(op, age, float64(d))
+	}, nil
+}
+
+func compare(op string, a, b float64) bool {
+	switch op {
+	case ":", "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func ago(d time.Duration) *time.Time {
+	t := now.Add(-d)
+	return &t
+}
+
+var testConns = []config.Connection{
+	{Name: "web-1", Tags: []string{"prod", "web"}, Group: "Frontend", Owner: "alice", Usage: 12, LastConnected: ago(2 * time.Hour), CreatedAt: ago(90 * 24 * time.Hour),
+		LastProbe: &config.ProbeRecord{Status: config.ProbeOnline}},
+	{Name: "web-2", Tags: []string{"staging", "web"}, Group: "Frontend", Owner: "bob", Usage: 3, LastConnected: ago(10 * 24 * time.Hour), CreatedAt: ago(24 * time.Hour),
+		LastProbe: &config.ProbeRecord{Status: config.ProbeNoListener}},
+	{Name: "db primary", Tags: []string{"prod", "db"}, Owner: "alice", Usage: 0, CreatedAt: ago(40 * 24 * time.Hour)},
+	{Name: "lab", Tags: []string{"lab"}},
+}
+
+func names(conns []config.Connection) []string {
+	var out []string
+	for _, c := range conns {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"web-1", "web-2", "db primary", "lab"}},
+		{"tag:prod", []string{"web-1", "db primary"}},
+		{"TAG=PROD", []string{"web-1", "db primary"}},
+		{"tag!=prod", []string{"web-2", "lab"}},
+		{"tag:st*", []string{"web-2"}},
+		{"-tag:prod", []string{"web-2", "lab"}},
+		{"not tag:prod", []string{"web-2", "lab"}},
+		{"tag:prod tag:web", []string{"web-1"}},
+		{"tag:prod and tag:web", []string{"web-1"}},
+		{"tag:db or tag:lab", []string{"db primary", "lab"}},
+		{"web", []string{"web-1", "web-2"}},
+		{"front", []string{"web-1", "web-2"}},
+		{"name:web-*", []string{"web-1", "web-2"}},
+		{`name="db primary"`, []string{"db primary"}},
+		{"group=frontend owner!=bob", []string{"web-1"}},
+		{"owner:ali", []string{"web-1", "db primary"}},
+		{"status:online", []string{"web-1"}},
+		{"status=unknown", []string{"db primary", "lab"}},
+		{"status!=no-listener", []string{"web-1", "db primary", "lab"}},
+		{"usage>5", []string{"web-1"}},
+		{"usage<=3", []string{"web-2", "db primary", "lab"}},
+		{"usage=0", []string{"db primary", "lab"}},
+		{"seen<7d", []string{"web-1"}},
+		{"seen>1w", []string{"web-2", "db primary", "lab"}},
+		{"seen:never", []string{"db primary", "lab"}},
+		{"seen!=never", []string{"web-1", "web-2"}},
+		{"created<2d", []string{"web-2"}},
+		{"created>=30d tag:prod", []string{"web-1", "db primary"}},
+		{`"or"`, nil},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := names(q.Filter(testConns, now)); !slices.Equal(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		`name:"web`,
+		"or tag:prod",
+		"tag:prod or",
+		"tag:prod not",
+		"color:red",
+		"usage>many",
+		"status:sleeping",
+		"seen<soon",
+		"seen:7d",
+		"seen>never",
+		"tag<prod",
+		"name>web",
+		"tag:[",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestEmptyAndString(t *testing.T) {
+	q, err := Parse("  tag:prod  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Empty() || q.String() != "tag:prod" {
+		t.Errorf("Parse gave Empty=%v String=%q, want a query for tag:prod", q.Empty(), q.String())
+	}
+	var zero *Query
+	if !zero.Empty() || !zero.Match(testConns[0], now) {
+		t.Error("the nil Query should be empty and match everything")
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	tests := []struct {
+		query, from, to, want string
+	}{
+		{"tag:prod seen<7d", "prod", "production", "tag:production seen<7d"},
+		{"-tag:PROD or tag=prod", "prod", "live", "-tag:live or tag=live"},
+		{"!tag!=prod", "prod", "live", "!tag!=live"},
+		{"tag:prod", "prod", "live ops", `tag:"live ops"`},
+		{`tag:"old tag" usage>1`, "old tag", "new", "tag:new usage>1"},
+		{"tag:pro* prod name:prod", "prod", "live", "tag:pro* prod name:prod"},
+		{`tag:"unterminated`, "unterminated", "x", `tag:"unterminated`},
+	}
+	for _, tt := range tests {
+		if got := RenameTag(tt.query, tt.from, tt.to); got != tt.want {
+			t.Errorf("RenameTag(%q, %q, %q) = %q, want %q", tt.query, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
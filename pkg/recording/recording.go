@@ -0,0 +1,309 @@
+// Package recording writes and plays back gs-netcat session transcripts in
+// the asciinema v2 (.cast) format.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// SessionsDirName is the directory under the config dir holding recordings.
+const SessionsDirName = "sessions"
+
+// FileExt is the extension used for recording files.
+const FileExt = ".cast"
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a single timed chunk of input ("i") or output ("o").
+type Event struct {
+	Time float64
+	Type string
+	Data string
+}
+
+// Recorder appends events to a .cast file. It implements runner.Tap.
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	start   time.Time
+	pending map[string][]byte
+	err     error
+}
+
+// Dir returns the directory where recordings are stored.
+func Dir() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigFilePath), SessionsDirName)
+}
+
+// NewPath returns the path of a new recording for connName started at t.
+func NewPath(connName string, t time.Time) string {
+	return filepath.Join(Dir(), dirName(connName), t.Format("20060102-150405")+FileExt)
+}
+
+// dirName makes a connection name safe to use as a directory name.
+func dirName(connName string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator || r < 32 {
+			return '_'
+		}
+		return r
+	}, connName)
+	if safe == "" || safe == "." || safe == ".." {
+		safe = "_"
+	}
+	return safe
+}
+
+// Create starts a new recording at path, creating parent directories as needed.
+func Create(path string, header Header) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording '%s': %w", path, err)
+	}
+
+	start := time.Now()
+	header.Version = 2
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+	if header.Width <= 0 {
+		header.Width = 80
+	}
+	if header.Height <= 0 {
+		header.Height = 24
+	}
+
+	r := &Recorder{file: f, w: bufio.NewWriter(f), start: start, pending: map[string][]byte{}}
+	line, err := json.Marshal(header)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.w.Write(line)
+	r.w.WriteByte('\n')
+	return r, nil
+}
+
+// Input records bytes typed by the user.
+func (r *Recorder) Input(p []byte) { r.write("i", p) }
+
+// Output records bytes shown to the user.
+func (r *Recorder) Output(p []byte) { r.write("o", p) }
+
+func (r *Recorder) write(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil || len(p) == 0 {
+		return
+	}
+
+	// Hold back an incomplete UTF-8 sequence at the end of p until the rest
+	// of it arrives, so multi-byte characters are not mangled in the JSON.
+	data := append(r.pending[kind], p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending[kind] = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	line, err := json.Marshal([]any{elapsed, kind, string(data[:cut])})
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.w.Write(line)
+	if err := r.w.WriteByte('\n'); err != nil {
+		r.err = err
+	}
+}
+
+// Close flushes the recording to disk.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Read parses a .cast file.
+func Read(path string) (Header, []Event, error) {
+	var header Header
+	f, err := os.Open(path)
+	if err != nil {
+		return header, nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		return header, nil, fmt.Errorf("recording '%s' is empty", path)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid recording header in '%s': %w", path, err)
+	}
+	if header.Version != 2 {
+		return header, nil, fmt.Errorf("unsupported asciicast version %d in '%s'", header.Version, path)
+	}
+
+	var events []Event
+	for scanner.Scan() {
+		var raw []any
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil || len(raw) != 3 {
+			continue // Tolerate a truncated last line from an interrupted session.
+		}
+		t, okT := raw[0].(float64)
+		kind, okK := raw[1].(string)
+		data, okD := raw[2].(string)
+		if okT && okK && okD {
+			events = append(events, Event{Time: t, Type: kind, Data: data})
+		}
+	}
+	return header, events, scanner.Err()
+}
+
+// Replay writes the output events of a recording to w, keeping the original
+// timing divided by speed. Pauses longer than maxIdle are shortened to maxIdle
+// when maxIdle is positive.
+func Replay(path string, w io.Writer, speed float64, maxIdle time.Duration) error {
+	_, events, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+
+	last := 0.0
+	for _, ev := range events {
+		if ev.Type != "o" {
+			continue
+		}
+		wait := time.Duration((ev.Time - last) / speed * float64(time.Second))
+		if maxIdle > 0 && wait > maxIdle {
+			wait = maxIdle
+		}
+		time.Sleep(wait)
+		last = ev.Time
+		if _, err := io.WriteString(w, ev.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Info describes a recording on disk.
+type Info struct {
+	Connection string
+	Path       string
+	Started    time.Time
+	Duration   time.Duration
+	Size       int64
+}
+
+// List returns the recordings for connName, or for all connections when
+// connName is empty, oldest first.
+func List(connName string) ([]Info, error) {
+	dirs := []string{}
+	if connName != "" {
+		dirs = append(dirs, dirName(connName))
+	} else {
+		entries, err := os.ReadDir(Dir())
+		if os.IsNotExist(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, e.Name())
+			}
+		}
+	}
+
+	var infos []Info
+	for _, d := range dirs {
+		files, err := filepath.Glob(filepath.Join(Dir(), d, "*"+FileExt))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			info := Info{Connection: d, Path: path}
+			if st, err := os.Stat(path); err == nil {
+				info.Size = st.Size()
+				info.Started = st.ModTime()
+			}
+			if header, events, err := Read(path); err == nil {
+				if header.Title != "" {
+					info.Connection = header.Title
+				}
+				info.Started = time.Unix(header.Timestamp, 0)
+				if len(events) > 0 {
+					info.Duration = time.Duration(events[len(events)-1].Time * float64(time.Second))
+				}
+			}
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Started.Before(infos[j].Started) })
+	return infos, nil
+}
+
+// Resolve turns a user supplied session reference into a recording path.
+// ref may be a file path, a path relative to Dir(), or a connection name, in
+// which case that connection's latest recording is used.
+func Resolve(ref string) (string, error) {
+	candidates := []string{ref, filepath.Join(Dir(), ref), filepath.Join(Dir(), ref+FileExt)}
+	for _, c := range candidates {
+		if st, err := os.Stat(c); err == nil && !st.IsDir() {
+			return c, nil
+		}
+	}
+
+	infos, err := List(ref)
+	if err != nil {
+		return "", err
+	}
+	if len(infos) == 0 {
+		return "", fmt.Errorf("no recording found for '%s'", ref)
+	}
+	return infos[len(infos)-1].Path, nil
+}
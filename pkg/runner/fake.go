@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// FakeScript plays the part of gs-netcat for a FakeBackend session. It can
+// read spec.Stdin and write spec.Stdout/Stderr like the real process would,
+// receives signals sent to the Runner on signals, and returns the exit code.
+type FakeScript func(spec Spec, signals <-chan os.Signal) int
+
+// FakeBackend is a scriptable Backend that never spawns a process.
+// It records every Spec it was asked to run so callers can inspect them.
+type FakeBackend struct {
+	Script FakeScript
+
+	mu    sync.Mutex
+	specs []Spec
+}
+
+// NewFakeBackend returns a FakeBackend that runs script for every session.
+// A nil script makes every session exit 0 immediately.
+func NewFakeBackend(script FakeScript) *FakeBackend {
+	return &FakeBackend{Script: script}
+}
+
+// FakeExit returns a FakeScript that exits with code straight away.
+func FakeExit(code int) FakeScript {
+	return func(Spec, <-chan os.Signal) int { return code }
+}
+
+// FakeShell returns a FakeScript that plays the listener's shell with a
+// local sh, without a PTY, so that RemoteExec can be driven end to end. The
+// session exits -1 if sh can't be started.
+func FakeShell() FakeScript {
+	return func(spec Spec, signals <-chan os.Signal) int {
+		cmd := exec.Command("sh")
+		cmd.Stdout, cmd.Stderr = spec.Stdout, spec.Stderr
+		// Don't wait for children of a killed sh still holding its output.
+		cmd.WaitDelay = stopGrace
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return -1
+		}
+		if err := cmd.Start(); err != nil {
+			return -1
+		}
+		go func() {
+			io.Copy(stdin, spec.Stdin) //nolint:errcheck
+			stdin.Close()
+		}()
+		exited := make(chan struct{})
+		defer close(exited)
+		go func() {
+			select {
+			case <-signals:
+				cmd.Process.Kill() //nolint:errcheck
+			case <-exited:
+			}
+		}()
+		cmd.Wait() //nolint:errcheck
+		return cmd.ProcessState.ExitCode()
+	}
+}
+
+func (b *FakeBackend) New(spec Spec) Runner {
+	b.mu.Lock()
+	b.specs = append(b.specs, spec)
+	b.mu.Unlock()
+
+	script := b.Script
+	if script == nil {
+		script = FakeExit(0)
+	}
+	return &fakeRunner{spec: spec, script: script, exitCode: -1}
+}
+
+// Specs returns the sessions requested so far, in order.
+func (b *FakeBackend) Specs() []Spec {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Spec(nil), b.specs...)
+}
+
+type fakeRunner struct {
+	spec    Spec
+	script  FakeScript
+	signals chan os.Signal
+	done    chan struct{}
+
+	mu       sync.Mutex
+	exitCode int
+}
+
+func (r *fakeRunner) Start() error {
+	if r.done != nil {
+		return fmt.Errorf("session already started")
+	}
+	r.signals = make(chan os.Signal, 8)
+	r.done = make(chan struct{})
+	spec := r.spec
+	spec.Stdin, spec.Stdout, spec.Stderr = tapped(spec)
+	go func() {
+		code := r.script(spec, r.signals)
+		r.mu.Lock()
+		r.exitCode = code
+		r.mu.Unlock()
+		close(r.done)
+	}()
+	return nil
+}
+
+func (r *fakeRunner) Wait() error {
+	if r.done == nil {
+		return fmt.Errorf("session not started")
+	}
+	<-r.done
+	if code := r.ExitCode(); code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+func (r *fakeRunner) Signal(sig os.Signal) error {
+	if r.done == nil {
+		return fmt.Errorf("session not started")
+	}
+	// Check done first: with room in signals both cases would be ready.
+	select {
+	case <-r.done:
+		return os.ErrProcessDone
+	default:
+	}
+	select {
+	case <-r.done:
+		return os.ErrProcessDone
+	case r.signals <- sig:
+		return nil
+	}
+}
+
+func (r *fakeRunner) ExitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exitCode
+}
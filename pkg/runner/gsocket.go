@@ -4,27 +4,133 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"time"
 
 	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/hooks"
 )
 
 const gsNetcatCommand = "gs-netcat"
 
+// ExecBackend runs sessions through an external gs-netcat binary.
+type ExecBackend struct {
+	Command string
+}
+
+// NewExecBackend returns a Backend that spawns command for every session.
+func NewExecBackend(command string) *ExecBackend {
+	return &ExecBackend{Command: command}
+}
+
+func (b *ExecBackend) New(spec Spec) Runner {
+	cmd := exec.Command(b.Command, spec.Args...)
+	// Don't let a child that outlives gs-netcat and holds its output open
+	// block Wait.
+	cmd.WaitDelay = waitDelay
+	return &execRunner{cmd: cmd, spec: spec}
+}
+
+// waitDelay bounds how long Wait copies output after the process exited.
+const waitDelay = 2 * time.Second
+
+type execRunner struct {
+	cmd  *exec.Cmd
+	spec Spec
+	pty  *ptySession
+}
+
+func (r *execRunner) Start() error {
+	if r.spec.PTY {
+		p, err := startPTY(r.cmd, r.spec)
+		if err == nil {
+			r.pty = p
+			return nil
+		}
+		if err != errPTYUnsupported {
+			return err
+		}
+		if r.spec.Stderr != nil {
+			fmt.Fprintln(r.spec.Stderr, "[!] No pseudo-terminal support on this platform; the session runs without one (no line editing, full-screen programs or resizing).")
+		}
+	}
+	r.cmd.Stdin, r.cmd.Stdout, r.cmd.Stderr = tapped(r.spec)
+	return r.cmd.Start()
+}
+
+func (r *execRunner) Wait() error {
+	err := r.cmd.Wait()
+	if r.pty != nil {
+		r.pty.finish()
+	}
+	return err
+}
+
+func (r *execRunner) Resize(cols, rows int) error {
+	if r.pty == nil {
+		return errPTYUnsupported
+	}
+	return setPTYSize(r.pty.master, cols, rows)
+}
+
+func (r *execRunner) Signal(sig os.Signal) error {
+	if r.cmd.Process == nil {
+		return fmt.Errorf("session not started")
+	}
+	return r.cmd.Process.Signal(sig)
+}
+
+func (r *execRunner) PID() int {
+	if r.cmd.Process == nil {
+		return 0
+	}
+	return r.cmd.Process.Pid
+}
+
+func (r *execRunner) ExitCode() int {
+	if r.cmd.ProcessState == nil {
+		return -1
+	}
+	return r.cmd.ProcessState.ExitCode()
+}
+
+// Execute opens an interactive session to conn on the default backend.
 func Execute(conn config.Connection) error {
-	fmt.Printf("[+] Attempting to connect to: %s (Key: %s)\n", conn.Name, conn.Key)
-	fmt.Println("    (Press Ctrl+C in the GSocket session to disconnect and return to GSM)")
+	return ExecuteWith(DefaultBackend, NewSpec(conn))
+}
 
-	cmd := exec.Command(gsNetcatCommand, "-i", "-s", conn.Key)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// ExecuteWith runs the session described by spec on backend and reports
+// progress on spec.Stdout. The hooks in spec run around the session.
+func ExecuteWith(backend Backend, spec Spec) error {
+	return WithHooks(spec, func() (int, error) {
+		conn := spec.Connection
+		fmt.Fprintf(spec.Stdout, "[+] Attempting to connect to: %s (Key: %s)\n", conn.Name, conn.Key)
+		fmt.Fprintln(spec.Stdout, "    (Press Ctrl+C in the GSocket session to disconnect and return to GSM)")
 
-	runErr := cmd.Run()
+		r := backend.New(spec)
+		if err := r.Start(); err != nil {
+			fmt.Fprintf(spec.Stdout, "[<] Disconnected from %s (session ended, possibly with error: %v)\n", conn.Name, err)
+			return -1, err
+		}
+		if err := r.Wait(); err != nil {
+			fmt.Fprintf(spec.Stdout, "[<] Disconnected from %s (session ended, possibly with error: %v)\n", conn.Name, err)
+			return r.ExitCode(), err
+		}
+		fmt.Fprintf(spec.Stdout, "[<] Disconnected from %s successfully.\n", conn.Name)
+		return r.ExitCode(), nil
+	})
+}
 
-	if runErr != nil {
-		fmt.Printf("[<] Disconnected from %s (session ended, possibly with error: %v)\n", conn.Name, runErr)
-		return runErr
+// WithHooks runs the pre-connect hooks of spec, then session and then the
+// post-session hooks with the session's duration and exit status (-1 if it
+// never started). An aborting pre-connect hook cancels the session. Hook
+// output goes to spec.Stdout and spec.Stderr.
+func WithHooks(spec Spec, session func() (int, error)) error {
+	if err := hooks.RunPre(spec.Connection, spec.Hooks.Pre, spec.Stdout, spec.Stderr); err != nil {
+		fmt.Fprintf(spec.Stdout, "[!] Not connecting to %s: %v\n", spec.Connection.Name, err)
+		return err
 	}
-	fmt.Printf("[<] Disconnected from %s successfully.\n", conn.Name)
-	return nil
+	started := time.Now()
+	exitCode, err := session()
+	hooks.RunPost(spec.Connection, spec.Hooks.Post, time.Since(started), exitCode, spec.Stdout, spec.Stderr)
+	return err
 }
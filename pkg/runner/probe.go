@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// DefaultProbeTimeout is used when Probe is called without a timeout.
+const DefaultProbeTimeout = 10 * time.Second
+
+// ProbeResult is the outcome of a liveness probe for one connection.
+type ProbeResult struct {
+	Connection string             `json:"name"`
+	Status     config.ProbeStatus `json:"status"`
+	At         time.Time          `json:"at"`
+	Latency    time.Duration      `json:"-"`
+	LatencyMS  int64              `json:"latency_ms"`
+	Detail     string             `json:"detail,omitempty"`
+}
+
+// Record converts r into the form stored on the connection.
+func (r ProbeResult) Record() config.ProbeRecord {
+	return config.ProbeRecord{Status: r.Status, At: r.At, Latency: config.Duration(r.Latency), Detail: r.Detail}
+}
+
+// noListenerHints and relayHints are lower-cased fragments of gs-netcat
+// error messages used to classify a failed probe.
+var (
+	noListenerHints = []string{"no server", "not listening", "connection refused", "refused"}
+	relayHints      = []string{"gsrn", "relay", "resolve", "unreachable", "network", "timed out", "no route"}
+)
+
+// ClassifyProbe maps a gs-netcat exit code and its output to a probe status.
+func ClassifyProbe(exitCode int, output string) config.ProbeStatus {
+	if exitCode == 0 {
+		return config.ProbeOnline
+	}
+	lower := strings.ToLower(output)
+	for _, hint := range noListenerHints {
+		if strings.Contains(lower, hint) {
+			return config.ProbeNoListener
+		}
+	}
+	for _, hint := range relayHints {
+		if strings.Contains(lower, hint) {
+			return config.ProbeRelayUnreachable
+		}
+	}
+	return config.ProbeError
+}
+
+// ProbeArgs returns the gs-netcat arguments for a short, non-interactive
+// connect to conn's listener. It leaves out -q: ClassifyProbe needs the
+// error messages.
+func ProbeArgs(conn config.Connection) []string {
+	return []string{"-s", conn.Key}
+}
+
+// Probe tries a short non-interactive connect to conn and classifies the
+// result. With no stdin gs-netcat connects, sends EOF and exits 0 when a
+// listener answered; otherwise its error output tells what went wrong.
+func Probe(ctx context.Context, backend Backend, conn config.Connection, timeout time.Duration) ProbeResult {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output := &lockedBuffer{}
+
+	result := ProbeResult{Connection: conn.Name, At: time.Now()}
+	r := backend.New(Spec{
+		Connection: conn,
+		Args:       ProbeArgs(conn),
+		Stdin:      bytes.NewReader(nil),
+		Stdout:     output,
+		Stderr:     output,
+	})
+	started := time.Now()
+	if err := r.Start(); err != nil {
+		result.Status = config.ProbeError
+		result.Detail = err.Error()
+		return result
+	}
+
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- r.Wait() }()
+	select {
+	case <-waitErr:
+		result.Latency = time.Since(started)
+		text := strings.TrimSpace(output.String())
+		result.Status = ClassifyProbe(r.ExitCode(), text)
+		if result.Status != config.ProbeOnline {
+			result.Detail = lastLine(text)
+		}
+	case <-ctx.Done():
+		stopRunner(r, waitErr) //nolint:errcheck
+		result.Latency = time.Since(started)
+		result.Status = config.ProbeTimeout
+		result.Detail = "no answer within " + timeout.String()
+	}
+	result.LatencyMS = result.Latency.Milliseconds()
+	return result
+}
+
+// ProbeAll probes conns with at most parallel probes at a time and returns
+// the results in the order of conns.
+func ProbeAll(ctx context.Context, backend Backend, conns []config.Connection, parallel int, timeout time.Duration) []ProbeResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]ProbeResult, len(conns))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = Probe(ctx, backend, conn, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[i+1:])
+	}
+	return s
+}
+
+// lockedBuffer collects stdout and stderr, which are written concurrently.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
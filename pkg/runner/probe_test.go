@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+func TestClassifyProbe(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		output   string
+		want     config.ProbeStatus
+	}{
+		{0, "", config.ProbeOnline},
+		{0, "ERROR: Connection refused", config.ProbeOnline},
+		{255, "=Secret: ...\nERROR: Connection refused (no server listening)", config.ProbeNoListener},
+		{1, "Server not listening", config.ProbeNoListener},
+		{255, "ERROR: Failed to resolve gs.thc.org", config.ProbeRelayUnreachable},
+		{255, "GSRN connection timed out", config.ProbeRelayUnreachable},
+		{1, "Network is unreachable", config.ProbeRelayUnreachable},
+		{2, "segmentation fault", config.ProbeError},
+		{-1, "", config.ProbeError},
+	}
+	for _, tt := range tests {
+		if got := ClassifyProbe(tt.exitCode, tt.output); got != tt.want {
+			t.Errorf("ClassifyProbe(%d, %q) = %s, want %s", tt.exitCode, tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	conn := config.Connection{Name: "web-1", Key: "RG9DNqW4WrbiIDlrYJawxj"}
+	tests := []struct {
+		name       string
+		script     FakeScript
+		wantStatus config.ProbeStatus
+		wantDetail string
+	}{
+		{"online", FakeExit(0), config.ProbeOnline, ""},
+		{"no listener", func(spec Spec, _ <-chan os.Signal) int {
+			fmt.Fprintln(spec.Stderr, "=Secret         : RG9DNqW4WrbiIDlrYJawxj")
+			fmt.Fprintln(spec.Stderr, "ERROR: Connection refused (no server listening)")
+			return 255
+		}, config.ProbeNoListener, "ERROR: Connection refused (no server listening)"},
+		{"timeout", func(_ Spec, signals <-chan os.Signal) int {
+			<-signals
+			return -1
+		}, config.ProbeTimeout, "no answer within 20ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewFakeBackend(tt.script)
+			result := Probe(context.Background(), backend, conn, 20*time.Millisecond)
+			if result.Status != tt.wantStatus || result.Detail != tt.wantDetail {
+				t.Errorf("Probe = %s %q, want %s %q", result.Status, result.Detail, tt.wantStatus, tt.wantDetail)
+			}
+			if result.Connection != "web-1" {
+				t.Errorf("Probe connection = %q, want web-1", result.Connection)
+			}
+			if specs := backend.Specs(); len(specs) != 1 || !slices.Equal(specs[0].Args, ProbeArgs(conn)) {
+				t.Errorf("backend got specs %+v, want one with the probe arguments", specs)
+			}
+		})
+	}
+}
+
+func TestProbeAllKeepsOrder(t *testing.T) {
+	backend := NewFakeBackend(func(spec Spec, _ <-chan os.Signal) int {
+		if spec.Connection.Name == "down" {
+			fmt.Fprintln(spec.Stderr, "ERROR: Connection refused (no server listening)")
+			return 255
+		}
+		return 0
+	})
+	conns := []config.Connection{{Name: "up-1"}, {Name: "down"}, {Name: "up-2"}}
+	results := ProbeAll(context.Background(), backend, conns, 2, time.Second)
+	want := []config.ProbeStatus{config.ProbeOnline, config.ProbeNoListener, config.ProbeOnline}
+	for i, result := range results {
+		if result.Connection != conns[i].Name || result.Status != want[i] {
+			t.Errorf("result %d = %s %s, want %s %s", i, result.Connection, result.Status, conns[i].Name, want[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/cancelreader"
+)
+
+var errPTYUnsupported = errors.New("pseudo-terminals are not supported on this platform")
+
+// ptyDrainTimeout bounds how long Wait keeps reading output after the child
+// exited, in case something else still holds the terminal open.
+const ptyDrainTimeout = 2 * time.Second
+
+// ptySession is the parent side of a child process running under a PTY.
+type ptySession struct {
+	master    *os.File
+	input     cancelreader.CancelReader
+	termFd    uintptr
+	termState *term.State
+	stopWinch func()
+	outDone   chan struct{}
+}
+
+// startPTY starts cmd with a new pseudo-terminal as its controlling terminal
+// and copies spec's streams to and from it. If spec.Stdin is a terminal it is
+// put into raw mode and its size is mirrored onto the PTY.
+func startPTY(cmd *exec.Cmd, spec Spec) (*ptySession, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer slave.Close()
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	setControllingTTY(cmd)
+
+	p := &ptySession{master: master, outDone: make(chan struct{}), stopWinch: func() {}}
+	if in, ok := spec.Stdin.(*os.File); ok && term.IsTerminal(in.Fd()) {
+		p.termFd = in.Fd()
+		resizePTY(master, p.termFd)
+		if p.termState, err = term.MakeRaw(p.termFd); err != nil {
+			master.Close()
+			return nil, err
+		}
+		p.stopWinch = watchResize(master, p.termFd)
+	}
+
+	if err := cmd.Start(); err != nil {
+		p.restore()
+		master.Close()
+		return nil, err
+	}
+
+	stdin, stdout, _ := tapped(spec)
+	if spec.Stdin != nil {
+		if f, ok := spec.Stdin.(*os.File); ok {
+			// A cancelable reader keeps the copy goroutine from swallowing the
+			// next keypress meant for the TUI once the session is over.
+			if cr, err := cancelreader.NewReader(f); err == nil {
+				p.input = cr
+				stdin = cr
+				if spec.Tap != nil {
+					stdin = tapReader{r: cr, tap: spec.Tap.Input}
+				}
+			}
+		}
+		go io.Copy(master, stdin) //nolint:errcheck
+	}
+	go func() {
+		if stdout != nil {
+			io.Copy(stdout, master) //nolint:errcheck
+		} else {
+			io.Copy(io.Discard, master) //nolint:errcheck
+		}
+		close(p.outDone)
+	}()
+	return p, nil
+}
+
+// finish waits for the remaining output and restores the local terminal.
+func (p *ptySession) finish() {
+	select {
+	case <-p.outDone:
+	case <-time.After(ptyDrainTimeout):
+	}
+	if p.input != nil {
+		p.input.Cancel()
+	}
+	p.stopWinch()
+	p.restore()
+	p.master.Close()
+}
+
+func (p *ptySession) restore() {
+	if p.termState != nil {
+		term.Restore(p.termFd, p.termState) //nolint:errcheck
+		p.termState = nil
+	}
+}
+
+// resizePTY copies the size of the terminal at fd onto master.
+func resizePTY(master *os.File, fd uintptr) {
+	if w, h, err := term.GetSize(fd); err == nil {
+		setPTYSize(master, w, h) //nolint:errcheck
+	}
+}
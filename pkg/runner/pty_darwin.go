@@ -0,0 +1,56 @@
+//go:build darwin
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ptsNameSize is the size of the buffer TIOCPTYGNAME fills, encoded in the
+// request number.
+const ptsNameSize = (unix.TIOCPTYGNAME >> 16) & 0x1fff
+
+// openPTY does what posix_openpt, grantpt, unlockpt and ptsname do in libc.
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fd := master.Fd()
+	if err := ioctl(fd, unix.TIOCPTYGRANT, 0); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("granting pty: %w", err)
+	}
+	if err := ioctl(fd, unix.TIOCPTYUNLK, 0); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlocking pty: %w", err)
+	}
+	var name [ptsNameSize]byte
+	if err := ioctl(fd, unix.TIOCPTYGNAME, uintptr(unsafe.Pointer(&name[0]))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("getting pty name: %w", err)
+	}
+	if i := bytes.IndexByte(name[:], 0); i >= 0 {
+		slave, err := os.OpenFile(string(name[:i]), os.O_RDWR|syscall.O_NOCTTY, 0)
+		if err != nil {
+			master.Close()
+			return nil, nil, err
+		}
+		return master, slave, nil
+	}
+	master.Close()
+	return nil, nil, fmt.Errorf("getting pty name: name too long")
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+func openPTY() (*os.File, *os.File, error) { return nil, nil, errPTYUnsupported }
+
+func setPTYSize(master *os.File, cols, rows int) error { return errPTYUnsupported }
+
+func setControllingTTY(cmd *exec.Cmd) {}
+
+func watchResize(master *os.File, fd uintptr) func() { return func() {} }
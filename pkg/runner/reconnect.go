@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+const (
+	defaultReconnectAttempts = 5
+	defaultReconnectDelay    = 2 * time.Second
+	defaultReconnectMaxDelay = time.Minute
+	defaultReconnectFactor   = 2.0
+	defaultReconnectJitter   = 0.2
+	defaultReconnectStable   = time.Minute
+)
+
+// ExitKind classifies how a session ended.
+type ExitKind int
+
+const (
+	// ExitClean is a normal exit, usually the user closing the session.
+	ExitClean ExitKind = iota
+	// ExitInterrupted means the session was killed by a signal or Ctrl+C.
+	ExitInterrupted
+	// ExitFailure is a dropped session that may be retried.
+	ExitFailure
+	// ExitGiveUp is a failure the policy says not to retry.
+	ExitGiveUp
+)
+
+// exitCodeInterrupted is the shell convention for a process ended by SIGINT.
+const exitCodeInterrupted = 130
+
+// ClassifyExit tells a clean or user-initiated exit apart from a network
+// failure, using the give-up and retry lists of policy.
+func ClassifyExit(code int, policy config.ReconnectPolicy) ExitKind {
+	switch {
+	case code == 0:
+		return ExitClean
+	case code < 0 || code == exitCodeInterrupted:
+		return ExitInterrupted
+	case slices.Contains(policy.GiveUpExitCodes, code):
+		return ExitGiveUp
+	case len(policy.RetryExitCodes) > 0 && !slices.Contains(policy.RetryExitCodes, code):
+		return ExitGiveUp
+	}
+	return ExitFailure
+}
+
+// MaxAttempts returns how many reconnects in a row policy allows.
+func MaxAttempts(policy config.ReconnectPolicy) int {
+	if policy.MaxAttempts <= 0 {
+		return defaultReconnectAttempts
+	}
+	return policy.MaxAttempts
+}
+
+// StableAfter returns how long a session has to stay up before policy
+// resets the attempt counter.
+func StableAfter(policy config.ReconnectPolicy) time.Duration {
+	if policy.StableAfter <= 0 {
+		return defaultReconnectStable
+	}
+	return time.Duration(policy.StableAfter)
+}
+
+// Backoff returns the delay before reconnect attempt n (starting at 1),
+// before jitter is applied.
+func Backoff(policy config.ReconnectPolicy, n int) time.Duration {
+	initial := time.Duration(policy.InitialDelay)
+	if initial <= 0 {
+		initial = defaultReconnectDelay
+	}
+	maxDelay := time.Duration(policy.MaxDelay)
+	if maxDelay <= 0 {
+		maxDelay = defaultReconnectMaxDelay
+	}
+	factor := policy.Multiplier
+	if factor < 1 {
+		factor = defaultReconnectFactor
+	}
+
+	delay := float64(initial) * math.Pow(factor, float64(n-1))
+	if delay > float64(maxDelay) {
+		return maxDelay
+	}
+	return time.Duration(delay)
+}
+
+func withJitter(delay time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		jitter = defaultReconnectJitter
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	spread := (rand.Float64()*2 - 1) * jitter
+	return time.Duration(float64(delay) * (1 + spread))
+}
+
+// ExecuteWithReconnect runs spec like ExecuteWith and starts it again when it
+// drops, following policy. Between attempts a countdown is printed that the
+// user can cancel with Ctrl+C; os.Interrupt is only caught during the
+// countdown. interrupt overrides the os.Interrupt subscription and is mainly
+// useful for tests.
+func ExecuteWithReconnect(backend Backend, spec Spec, policy config.ReconnectPolicy, interrupt <-chan os.Signal) error {
+	return WithHooks(spec, func() (int, error) {
+		return reconnectLoop(backend, spec, policy, interrupt)
+	})
+}
+
+// reconnectLoop runs the attempts of ExecuteWithReconnect and returns the
+// exit code of the last one.
+func reconnectLoop(backend Backend, spec Spec, policy config.ReconnectPolicy, interrupt <-chan os.Signal) (int, error) {
+	maxAttempts := MaxAttempts(policy)
+	stableAfter := StableAfter(policy)
+
+	name := spec.Connection.Name
+	fmt.Fprintf(spec.Stdout, "[+] Attempting to connect to: %s (Key: %s)\n", name, spec.Connection.Key)
+	fmt.Fprintln(spec.Stdout, "    (Press Ctrl+C in the GSocket session to disconnect and return to GSM)")
+
+	attempt := 0
+	for {
+		started := time.Now()
+		r := backend.New(spec)
+		err := r.Start()
+		if err != nil {
+			// The backend itself is broken (e.g. gs-netcat missing); retrying won't help.
+			fmt.Fprintf(spec.Stdout, "[!] Could not start session for %s: %v\n", name, err)
+			return -1, err
+		}
+		err = r.Wait()
+
+		switch ClassifyExit(r.ExitCode(), policy) {
+		case ExitClean:
+			fmt.Fprintf(spec.Stdout, "[<] Disconnected from %s successfully.\n", name)
+			return r.ExitCode(), nil
+		case ExitInterrupted:
+			fmt.Fprintf(spec.Stdout, "[<] Session for %s was interrupted, not reconnecting.\n", name)
+			return r.ExitCode(), err
+		case ExitGiveUp:
+			fmt.Fprintf(spec.Stdout, "[<] Session for %s ended with exit code %d, not reconnecting.\n", name, r.ExitCode())
+			return r.ExitCode(), err
+		}
+
+		if time.Since(started) >= stableAfter {
+			attempt = 0
+		}
+		attempt++
+		if attempt > maxAttempts {
+			fmt.Fprintf(spec.Stdout, "[!] Giving up on %s after %d reconnect attempts: %v\n", name, maxAttempts, err)
+			return r.ExitCode(), err
+		}
+
+		delay := withJitter(Backoff(policy, attempt), policy.Jitter)
+		fmt.Fprintf(spec.Stdout, "[!] Session for %s dropped: %v\n", name, err)
+		if !countdown(spec, delay, attempt, maxAttempts, interrupt) {
+			fmt.Fprintf(spec.Stdout, "\n[<] Reconnect to %s cancelled.\n", name)
+			return r.ExitCode(), err
+		}
+		fmt.Fprintf(spec.Stdout, "\n[+] Reconnecting to %s (attempt %d/%d)...\n", name, attempt, maxAttempts)
+	}
+}
+
+// countdown waits for delay while printing the remaining seconds. It returns
+// false if interrupt fired first. A nil interrupt subscribes to os.Interrupt
+// for the countdown only, so a Ctrl+C from the session before can't cancel it.
+func countdown(spec Spec, delay time.Duration, attempt, maxAttempts int, interrupt <-chan os.Signal) bool {
+	if interrupt == nil {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		defer signal.Stop(sigCh)
+		interrupt = sigCh
+	}
+	deadline := time.Now().Add(delay)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		remaining := time.Until(deadline).Round(time.Second)
+		fmt.Fprintf(spec.Stdout, "\r    Reconnecting to %s in %v (attempt %d/%d). Press Ctrl+C to cancel. ",
+			spec.Connection.Name, remaining, attempt, maxAttempts)
+		select {
+		case <-interrupt:
+			return false
+		case <-timer.C:
+			return true
+		case <-ticker.C:
+		}
+	}
+}
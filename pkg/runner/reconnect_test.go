@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// fastPolicy retries almost immediately so tests don't wait for backoff.
+func fastPolicy() config.ReconnectPolicy {
+	return config.ReconnectPolicy{
+		MaxAttempts:  3,
+		InitialDelay: config.Duration(time.Millisecond),
+		MaxDelay:     config.Duration(time.Millisecond),
+	}
+}
+
+// exitSequence returns a FakeScript whose n-th session exits with codes[n],
+// repeating the last code, and the counter of sessions run.
+func exitSequence(codes ...int) (FakeScript, *atomic.Int32) {
+	var n atomic.Int32
+	return func(Spec, <-chan os.Signal) int {
+		i := int(n.Add(1)) - 1
+		return codes[min(i, len(codes)-1)]
+	}, &n
+}
+
+func TestClassifyExit(t *testing.T) {
+	policy := config.ReconnectPolicy{GiveUpExitCodes: []int{7}}
+	tests := []struct {
+		code   int
+		policy config.ReconnectPolicy
+		want   ExitKind
+	}{
+		{0, policy, ExitClean},
+		{-1, policy, ExitInterrupted},
+		{130, policy, ExitInterrupted},
+		{7, policy, ExitGiveUp},
+		{1, policy, ExitFailure},
+		{1, config.ReconnectPolicy{RetryExitCodes: []int{255}}, ExitGiveUp},
+		{255, config.ReconnectPolicy{RetryExitCodes: []int{255}}, ExitFailure},
+	}
+	for _, tt := range tests {
+		if got := ClassifyExit(tt.code, tt.policy); got != tt.want {
+			t.Errorf("ClassifyExit(%d, %+v) = %v, want %v", tt.code, tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := config.ReconnectPolicy{
+		InitialDelay: config.Duration(time.Second),
+		MaxDelay:     config.Duration(5 * time.Second),
+		Multiplier:   2,
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := Backoff(policy, i+1); got != w {
+			t.Errorf("Backoff(attempt %d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := Backoff(config.ReconnectPolicy{}, 1); got != defaultReconnectDelay {
+		t.Errorf("Backoff with the default policy = %v, want %v", got, defaultReconnectDelay)
+	}
+}
+
+func TestExecuteWithReconnectRecovers(t *testing.T) {
+	script, sessions := exitSequence(1, 1, 0)
+	spec, out := testSpec("")
+	if err := ExecuteWithReconnect(NewFakeBackend(script), spec, fastPolicy(), nil); err != nil {
+		t.Fatalf("ExecuteWithReconnect: %v", err)
+	}
+	if n := sessions.Load(); n != 3 {
+		t.Errorf("ran %d sessions, want 3", n)
+	}
+	text := out.String()
+	for _, want := range []string{"(attempt 1/3)", "(attempt 2/3)", "[<] Disconnected from web-1 successfully."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output %q does not contain %q", text, want)
+		}
+	}
+}
+
+func TestExecuteWithReconnectGivesUp(t *testing.T) {
+	script, sessions := exitSequence(1)
+	spec, out := testSpec("")
+	err := ExecuteWithReconnect(NewFakeBackend(script), spec, fastPolicy(), nil)
+	if code := ExitCodeOf(err); code != 1 {
+		t.Fatalf("ExecuteWithReconnect error %v has exit code %d, want 1", err, code)
+	}
+	if n := sessions.Load(); n != 4 {
+		t.Errorf("ran %d sessions, want the first one and 3 reconnects", n)
+	}
+	if !strings.Contains(out.String(), "Giving up on web-1 after 3 reconnect attempts") {
+		t.Errorf("output %q does not say it gave up", out.String())
+	}
+}
+
+func TestExecuteWithReconnectNoRetry(t *testing.T) {
+	policy := fastPolicy()
+	policy.GiveUpExitCodes = []int{7}
+	for _, code := range []int{0, 7, exitCodeInterrupted} {
+		script, sessions := exitSequence(code)
+		spec, _ := testSpec("")
+		err := ExecuteWithReconnect(NewFakeBackend(script), spec, policy, nil)
+		if got := ExitCodeOf(err); got != code {
+			t.Errorf("exit %d: ExecuteWithReconnect = %v, want exit code %d", code, err, code)
+		}
+		if n := sessions.Load(); n != 1 {
+			t.Errorf("exit %d: ran %d sessions, want 1", code, n)
+		}
+	}
+}
+
+func TestExecuteWithReconnectCancelled(t *testing.T) {
+	script, sessions := exitSequence(1)
+	policy := fastPolicy()
+	policy.InitialDelay = config.Duration(time.Hour)
+	policy.MaxDelay = config.Duration(time.Hour)
+	interrupt := make(chan os.Signal, 1)
+	interrupt <- os.Interrupt
+
+	spec, out := testSpec("")
+	if err := ExecuteWithReconnect(NewFakeBackend(script), spec, policy, interrupt); ExitCodeOf(err) != 1 {
+		t.Fatalf("ExecuteWithReconnect = %v, want exit status 1", err)
+	}
+	if n := sessions.Load(); n != 1 {
+		t.Errorf("ran %d sessions, want 1", n)
+	}
+	if !strings.Contains(out.String(), "[<] Reconnect to web-1 cancelled.") {
+		t.Errorf("output %q does not say the reconnect was cancelled", out.String())
+	}
+}
@@ -0,0 +1,367 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// ErrExecTimeout is returned by RemoteExec when the context expires first.
+var ErrExecTimeout = errors.New("remote command timed out")
+
+// ExecRequest is a single non-interactive command to run on a listener.
+type ExecRequest struct {
+	// Command is passed to the remote shell as-is.
+	Command string
+	// Stdin, if set, is streamed to the remote command, which starts once
+	// Stdin is closed.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Hooks run around the command, with their output on Stderr.
+	Hooks config.Hooks
+}
+
+// ExecResult reports how a remote command ended.
+type ExecResult struct {
+	// ExitCode is the remote command's exit status when ExitKnown is true.
+	ExitCode  int
+	ExitKnown bool
+}
+
+// RemoteExec runs req.Command on the shell served by conn's listener and
+// streams its output back. The command is framed by random markers so the
+// shell's prompt and echo can be told apart from the command's output, and
+// stderr lines are tagged remotely so they can be split from stdout.
+func RemoteExec(ctx context.Context, backend Backend, conn config.Connection, req ExecRequest) (ExecResult, error) {
+	if req.Stdout == nil {
+		req.Stdout = io.Discard
+	}
+	if req.Stderr == nil {
+		req.Stderr = io.Discard
+	}
+	var res ExecResult
+	hookSpec := Spec{Connection: conn, Stdout: req.Stderr, Stderr: req.Stderr, Hooks: req.Hooks}
+	err := WithHooks(hookSpec, func() (int, error) {
+		var err error
+		res, err = remoteExec(ctx, backend, conn, req)
+		if !res.ExitKnown {
+			return -1, err
+		}
+		return res.ExitCode, err
+	})
+	return res, err
+}
+
+func remoteExec(ctx context.Context, backend Backend, conn config.Connection, req ExecRequest) (ExecResult, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return ExecResult{}, err
+	}
+	marker := "__GSM_" + hex.EncodeToString(nonce)
+
+	head, tail := execScript(marker, req.Command, req.Stdin != nil)
+	script := io.Reader(strings.NewReader(head))
+	stdinErr := make(chan error, 1)
+	if req.Stdin != nil {
+		// Stdin is read while the session runs, so the timeout covers it.
+		encoded, w := io.Pipe()
+		defer encoded.Close()
+		go func() {
+			err := encodeStdin(w, req.Stdin)
+			if err != nil {
+				stdinErr <- err
+			}
+			w.CloseWithError(err)
+		}()
+		script = io.MultiReader(script, encoded, strings.NewReader(tail))
+	}
+
+	done := make(chan struct{})
+	parser := newExecParser(marker, req.Stdout, req.Stderr)
+	r := backend.New(Spec{
+		Connection: conn,
+		Args:       ClientArgs(conn),
+		Stdin:      io.MultiReader(script, waitReader{done}),
+		Stdout:     parser,
+		Stderr:     parser,
+		PTY:        true,
+	})
+	if err := r.Start(); err != nil {
+		close(done)
+		return ExecResult{}, err
+	}
+
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- r.Wait() }()
+
+	var err error
+	select {
+	case err = <-waitErr:
+	case <-parser.finished:
+		// The remote shell exits right after the end marker; give the session
+		// a moment to wind down on its own.
+		select {
+		case err = <-waitErr:
+		case <-ctx.Done():
+			err = stopRunner(r, waitErr)
+		case <-time.After(2 * stopGrace):
+			err = stopRunner(r, waitErr)
+		}
+	case err = <-stdinErr:
+		stopRunner(r, waitErr) //nolint:errcheck
+		close(done)
+		return ExecResult{}, fmt.Errorf("reading local stdin: %w", err)
+	case <-ctx.Done():
+		stopRunner(r, waitErr) //nolint:errcheck
+		close(done)
+		parser.flush()
+		return ExecResult{}, ErrExecTimeout
+	}
+	close(done)
+	parser.flush()
+
+	if code, ok := parser.exitCode(); ok {
+		return ExecResult{ExitCode: code, ExitKnown: true}, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("session ended before the remote command finished")
+	}
+	return ExecResult{}, err
+}
+
+// encodeStdin copies stdin to w as base64 in lines short enough for the
+// terminal's line editing, as the body of the here-document of execScript.
+func encodeStdin(w io.Writer, stdin io.Reader) error {
+	bw := bufio.NewWriter(w)
+	lines := &lineWriter{w: bw, width: 76}
+	enc := base64.NewEncoder(base64.StdEncoding, lines)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			enc.Write(buf[:n]) //nolint:errcheck // Errors of bw show up in Flush.
+			if errFlush := bw.Flush(); errFlush != nil {
+				return nil // The session is gone; its own error is reported.
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	enc.Close() //nolint:errcheck
+	lines.end() //nolint:errcheck
+	bw.Flush()  //nolint:errcheck
+	return nil
+}
+
+// lineWriter breaks what is written through it into lines of width bytes.
+type lineWriter struct {
+	w     io.Writer
+	width int
+	n     int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := min(len(p), l.width-l.n)
+		if _, err := l.w.Write(p[:chunk]); err != nil {
+			return written, err
+		}
+		written += chunk
+		l.n += chunk
+		p = p[chunk:]
+		if l.n == l.width {
+			if _, err := io.WriteString(l.w, "\n"); err != nil {
+				return written, err
+			}
+			l.n = 0
+		}
+	}
+	return written, nil
+}
+
+// end terminates a partial last line.
+func (l *lineWriter) end() error {
+	if l.n == 0 {
+		return nil
+	}
+	l.n = 0
+	_, err := io.WriteString(l.w, "\n")
+	return err
+}
+
+// stopGrace is how long a session gets to exit after SIGTERM before it is killed.
+const stopGrace = time.Second
+
+// stopRunner terminates r, escalating to SIGKILL if it ignores SIGTERM, and
+// returns the result of its Wait, which must be delivered on waitErr.
+func stopRunner(r Runner, waitErr <-chan error) error {
+	r.Signal(syscall.SIGTERM) //nolint:errcheck
+	select {
+	case err := <-waitErr:
+		return err
+	case <-time.After(stopGrace):
+	}
+	r.Signal(syscall.SIGKILL) //nolint:errcheck
+	return <-waitErr
+}
+
+// execScript builds the shell input that runs command between markers. With
+// hasInput, the base64 encoded input goes between head and tail.
+func execScript(marker, command string, hasInput bool) (head, tail string) {
+	// The marker is split with '' so the shell's echo of the script never
+	// contains it verbatim.
+	split := marker[:6] + "''" + marker[6:]
+	var b strings.Builder
+	// -onlcr keeps the terminal from turning the command's \n into \r\n.
+	b.WriteString("stty -echo -onlcr 2>/dev/null\n")
+	fmt.Fprintf(&b, "__gsm_m=%s; __gsm_rc=$(mktemp 2>/dev/null || echo /tmp/.gsm_rc.$$); ", split)
+	b.WriteString(`printf '\n%s B\n' "$__gsm_m"; `)
+	b.WriteString(`{ { `)
+	if hasInput {
+		b.WriteString(`{ base64 -d 2>/dev/null || base64 -D; } <<'__GSM_STDIN__' | ( ` + command + " )\n")
+		head = b.String()
+		b.Reset()
+		b.WriteString("__GSM_STDIN__\n")
+	} else {
+		b.WriteString(`( ` + command + " ) </dev/null\n")
+	}
+	b.WriteString(`echo $? >"$__gsm_rc"; } 2>&1 1>&3 3>&- | while IFS= read -r __gsm_l || [ -n "$__gsm_l" ]; do printf '%s E:%s\n' "$__gsm_m" "$__gsm_l"; done; } 3>&1; `)
+	b.WriteString(`printf '\n%s X:%s\n' "$__gsm_m" "$(cat "$__gsm_rc" 2>/dev/null)"; rm -f "$__gsm_rc"; exit` + "\n")
+	if !hasInput {
+		return b.String(), ""
+	}
+	return head, b.String()
+}
+
+// waitReader blocks until done is closed, then reports EOF. It keeps the
+// session's stdin open after the script has been sent.
+type waitReader struct {
+	done <-chan struct{}
+}
+
+func (w waitReader) Read(p []byte) (int, error) {
+	<-w.done
+	return 0, io.EOF
+}
+
+// execParser splits the session output into the command's stdout, stderr and
+// exit status using the markers written by execScript.
+type execParser struct {
+	marker   string
+	stdout   io.Writer
+	stderr   io.Writer
+	finished chan struct{}
+
+	mu      sync.Mutex
+	buf     []byte
+	started bool
+	ended   bool
+	code    int
+	known   bool
+	// pendingNewline holds back the newline that ended the last stdout
+	// line: the one printed just before the end marker belongs to the
+	// framing rather than to the command.
+	pendingNewline bool
+}
+
+func newExecParser(marker string, stdout, stderr io.Writer) *execParser {
+	return &execParser{marker: marker, stdout: stdout, stderr: stderr, finished: make(chan struct{})}
+}
+
+func (p *execParser) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(p.buf[:i])
+		p.buf = p.buf[i+1:]
+		p.line(line)
+	}
+	return len(data), nil
+}
+
+// line handles a complete line of output without its newline. Only the
+// framing is trimmed; the command's output is passed on byte for byte.
+func (p *execParser) line(line string) {
+	if p.ended {
+		return
+	}
+	if !p.started {
+		// The prompt and echo before the start marker may end in \r.
+		if strings.HasSuffix(strings.TrimSuffix(line, "\r"), p.marker+" B") {
+			p.started = true
+		}
+		return
+	}
+
+	idx := strings.Index(line, p.marker+" ")
+	if idx < 0 {
+		p.stdoutLine(line, true)
+		return
+	}
+	if idx > 0 {
+		p.stdoutLine(line[:idx], false)
+	}
+	rest := line[idx+len(p.marker)+1:]
+	switch {
+	case strings.HasPrefix(rest, "E:"):
+		io.WriteString(p.stderr, rest[2:]+"\n") //nolint:errcheck
+	case strings.HasPrefix(rest, "X:"):
+		p.pendingNewline = false
+		if code, err := strconv.Atoi(strings.TrimSpace(rest[2:])); err == nil {
+			p.code, p.known = code, true
+		}
+		p.ended = true
+		close(p.finished)
+	}
+}
+
+// stdoutLine writes s to stdout, holding back the newline after it until
+// the next output shows it isn't the framing one.
+func (p *execParser) stdoutLine(s string, newline bool) {
+	if p.pendingNewline {
+		io.WriteString(p.stdout, "\n") //nolint:errcheck
+		p.pendingNewline = false
+	}
+	io.WriteString(p.stdout, s) //nolint:errcheck
+	p.pendingNewline = newline
+}
+
+// flush writes out any partial trailing stdout line.
+func (p *execParser) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started && !p.ended {
+		p.stdoutLine(string(p.buf), false)
+		p.buf = nil
+	}
+}
+
+func (p *execParser) exitCode() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.code, p.known
+}
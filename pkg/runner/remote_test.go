@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+const testMarker = "__GSM_0123456789abcdef"
+
+func TestExecParser(t *testing.T) {
+	m := testMarker
+	tests := []struct {
+		name       string
+		output     string
+		wantStdout string
+		wantStderr string
+		wantCode   int
+		wantKnown  bool
+	}{
+		{"prompt and echo skipped", "$ stty -echo\r\n$ __gsm_m=__GSM_''0123\r\n\r\n" + m + " B\r\nhello\nworld\n\n" + m + " X:0\n", "hello\nworld\n", "", 0, true},
+		{"no trailing newline", "\n" + m + " B\nabc\n" + m + " X:3\n", "abc", "", 3, true},
+		{"crlf kept", "\n" + m + " B\na\r\nb\r\n\n" + m + " X:0\n", "a\r\nb\r\n", "", 0, true},
+		{"binary kept", "\n" + m + " B\n\x00\xff\r\x01\n\n" + m + " X:0\n", "\x00\xff\r\x01\n", "", 0, true},
+		{"empty lines kept", "\n" + m + " B\n\n\nx\n\n" + m + " X:0\n", "\n\nx\n", "", 0, true},
+		{"stderr", "\n" + m + " B\nout\n" + m + " E:bad thing\n\n" + m + " X:1\n", "out\n", "bad thing\n", 1, true},
+		{"stderr within a stdout line", "\n" + m + " B\npartial" + m + " E:err\nrest\n\n" + m + " X:0\n", "partialrest\n", "err\n", 0, true},
+		{"exit status with cr", "\n" + m + " B\n\n" + m + " X:42\r\n", "", "", 42, true},
+		{"unknown exit status", "\n" + m + " B\nx\n\n" + m + " X:\n", "x\n", "", 0, false},
+		{"output after the end ignored", "\n" + m + " B\nx\n\n" + m + " X:0\nlogout\n", "x\n", "", 0, true},
+		{"session ended early", "\n" + m + " B\nabc\ndef", "abc\ndef", "", 0, false},
+	}
+	for _, tt := range tests {
+		// Feed the output in one piece and byte by byte, as reads may split
+		// it anywhere.
+		for _, chunk := range []int{len(tt.output), 1} {
+			var stdout, stderr bytes.Buffer
+			p := newExecParser(m, &stdout, &stderr)
+			for rest := tt.output; rest != ""; {
+				n := min(chunk, len(rest))
+				p.Write([]byte(rest[:n])) //nolint:errcheck
+				rest = rest[n:]
+			}
+			p.flush()
+			code, known := p.exitCode()
+			if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr || code != tt.wantCode || known != tt.wantKnown {
+				t.Errorf("%s (reads of %d): got stdout %q, stderr %q, exit %d/%v; want %q, %q, %d/%v",
+					tt.name, chunk, stdout.String(), stderr.String(), code, known, tt.wantStdout, tt.wantStderr, tt.wantCode, tt.wantKnown)
+			}
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var b bytes.Buffer
+	l := &lineWriter{w: &b, width: 4}
+	l.Write([]byte("abcdef")) //nolint:errcheck
+	l.Write([]byte("gh"))     //nolint:errcheck
+	l.Write([]byte("ij"))     //nolint:errcheck
+	l.end()                   //nolint:errcheck
+	if want := "abcd\nefgh\nij\n"; b.String() != want {
+		t.Errorf("lineWriter wrote %q, want %q", b.String(), want)
+	}
+}
+
+// shBackend plays the listener's shell with a local sh, without a PTY.
+func shBackend(t *testing.T) *FakeBackend {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the exec script with")
+	}
+	return NewFakeBackend(FakeShell())
+}
+
+func TestRemoteExecWithShell(t *testing.T) {
+	binary := make([]byte, 100*1024)
+	rand.Read(binary) //nolint:errcheck
+
+	tests := []struct {
+		name       string
+		command    string
+		stdin      []byte
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{"output and status", `printf 'a\r\nb'; echo oops >&2; exit 3`, nil, "a\r\nb", "oops\n", 3},
+		{"trailing newline", "echo hello", nil, "hello\n", "", 0},
+		{"stdin", "cat", []byte("line 1\nline 2"), "line 1\nline 2", "", 0},
+		{"empty stdin", "wc -c | tr -d ' '", []byte{}, "0\n", "", 0},
+		{"binary stdin", "cat", binary, string(binary), "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			req := ExecRequest{Command: tt.command, Stdout: &stdout, Stderr: &stderr}
+			if tt.stdin != nil {
+				req.Stdin = bytes.NewReader(tt.stdin)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			res, err := RemoteExec(ctx, shBackend(t), config.Connection{Name: "web-1", Key: "k1"}, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.ExitKnown || res.ExitCode != tt.wantCode {
+				t.Errorf("exit = %d/%v, want %d", res.ExitCode, res.ExitKnown, tt.wantCode)
+			}
+			if stdout.String() != tt.wantStdout {
+				if len(tt.wantStdout) > 100 {
+					t.Errorf("stdout differs: got %d bytes, want %d", stdout.Len(), len(tt.wantStdout))
+				} else {
+					t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+				}
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRemoteExecTimeoutCoversStdin(t *testing.T) {
+	// A stdin that never closes must not keep the command from timing out.
+	stdin, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := RemoteExec(ctx, shBackend(t), config.Connection{Name: "web-1", Key: "k1"}, ExecRequest{Command: "cat", Stdin: stdin})
+	if !errors.Is(err, ErrExecTimeout) {
+		t.Fatalf("RemoteExec = %v, want ErrExecTimeout", err)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("RemoteExec took %v to time out", elapsed)
+	}
+}
+
+func TestRemoteExecStdinError(t *testing.T) {
+	stdin, w := io.Pipe()
+	w.CloseWithError(errors.New("disk on fire"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := RemoteExec(ctx, shBackend(t), config.Connection{Name: "web-1", Key: "k1"}, ExecRequest{Command: "cat", Stdin: stdin})
+	if err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("RemoteExec = %v, want the stdin error", err)
+	}
+}
@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// Runner is a single gs-netcat session. It mirrors the parts of exec.Cmd that
+// gsm relies on, so a backend other than the real binary can stand in for it.
+type Runner interface {
+	// Start launches the session without waiting for it to finish.
+	Start() error
+	// Wait blocks until the session ends. A non-zero exit is reported as an error.
+	Wait() error
+	// Signal delivers sig to a running session.
+	Signal(sig os.Signal) error
+	// ExitCode returns the exit status once Wait has returned.
+	// It is -1 if the session has not exited or was killed by a signal.
+	ExitCode() int
+}
+
+// Resizer is implemented by Runners whose session runs under a PTY that can
+// be resized from outside, e.g. by a client attached to a background session.
+type Resizer interface {
+	Resize(cols, rows int) error
+}
+
+// Process is implemented by Runners backed by an OS process.
+type Process interface {
+	// PID returns the process id, or 0 if the process hasn't started.
+	PID() int
+}
+
+// Spec describes the session a Backend should create.
+type Spec struct {
+	Connection config.Connection
+	Args       []string
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+	// PTY runs the session under a pseudo-terminal where the backend supports it.
+	PTY bool
+	// Tap, if set, sees every byte typed into and shown by the session.
+	Tap Tap
+	// Hooks run before the session starts and after it ends. Backends don't
+	// run them; callers wrap the session with WithHooks.
+	Hooks config.Hooks
+}
+
+// Tap observes the bytes flowing through a session, e.g. to record it.
+type Tap interface {
+	Input(p []byte)
+	Output(p []byte)
+}
+
+type tapWriter struct {
+	w   io.Writer
+	tap func([]byte)
+}
+
+func (t tapWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.tap(p[:n])
+	return n, err
+}
+
+type tapReader struct {
+	r   io.Reader
+	tap func([]byte)
+}
+
+func (t tapReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.tap(p[:n])
+	}
+	return n, err
+}
+
+// tapped returns the streams of spec wrapped so that spec.Tap sees them.
+func tapped(spec Spec) (stdin io.Reader, stdout, stderr io.Writer) {
+	stdin, stdout, stderr = spec.Stdin, spec.Stdout, spec.Stderr
+	if spec.Tap == nil {
+		return
+	}
+	if stdin != nil {
+		stdin = tapReader{r: stdin, tap: spec.Tap.Input}
+	}
+	if stdout != nil {
+		stdout = tapWriter{w: stdout, tap: spec.Tap.Output}
+	}
+	if stderr != nil {
+		stderr = tapWriter{w: stderr, tap: spec.Tap.Output}
+	}
+	return
+}
+
+// Backend creates Runners for connections.
+type Backend interface {
+	New(spec Spec) Runner
+}
+
+// DefaultBackend is the backend used when the caller does not pick one.
+var DefaultBackend Backend = NewExecBackend(gsNetcatCommand)
+
+// ExitError is returned by Wait for backends that do not wrap a real process.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCodeOf returns the exit status a session error stands for: 0 for nil,
+// the code of an exited session and -1 when the session didn't exit on its
+// own, e.g. it never started or was killed by a signal.
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	var procErr *exec.ExitError
+	if errors.As(err, &procErr) {
+		return procErr.ExitCode()
+	}
+	return -1
+}
+
+// ClientArgs returns the gs-netcat arguments used to open an interactive
+// session to conn.
+func ClientArgs(conn config.Connection) []string {
+	return []string{"-i", "-s", conn.Key}
+}
+
+// ListenerArgs returns the gs-netcat arguments for the listening side of
+// conn, which serves an interactive shell to clients using the same key.
+func ListenerArgs(conn config.Connection) []string {
+	return []string{"-s", conn.Key, "-l", "-i"}
+}
+
+// TunnelArgs returns the gs-netcat arguments that listen on the local port
+// and forward every connection made to it through conn's listener.
+func TunnelArgs(conn config.Connection, port int) []string {
+	return []string{"-s", conn.Key, "-p", strconv.Itoa(port)}
+}
+
+// NewSpec builds a Spec for an interactive session on the process' own stdio.
+func NewSpec(conn config.Connection) Spec {
+	return Spec{
+		Connection: conn,
+		Args:       ClientArgs(conn),
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		PTY:        true,
+		Hooks:      config.GetCurrent().HooksFor(conn),
+	}
+}
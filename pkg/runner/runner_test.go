@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of a session.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// recordingTap collects what a Tap is shown.
+type recordingTap struct {
+	mu            sync.Mutex
+	input, output bytes.Buffer
+}
+
+func (t *recordingTap) Input(p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.input.Write(p)
+}
+
+func (t *recordingTap) Output(p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.output.Write(p)
+}
+
+func testSpec(stdin string) (Spec, *syncBuffer) {
+	out := &syncBuffer{}
+	conn := config.Connection{Name: "web-1", Key: "RG9DNqW4WrbiIDlrYJawxj"}
+	return Spec{
+		Connection: conn,
+		Args:       ClientArgs(conn),
+		Stdin:      strings.NewReader(stdin),
+		Stdout:     out,
+		Stderr:     out,
+	}, out
+}
+
+// echoScript copies stdin to stdout and exits with code.
+func echoScript(code int) FakeScript {
+	return func(spec Spec, _ <-chan os.Signal) int {
+		io.Copy(spec.Stdout, spec.Stdin) //nolint:errcheck
+		return code
+	}
+}
+
+func TestExecuteWithSession(t *testing.T) {
+	spec, out := testSpec("uname -a\n")
+	tap := &recordingTap{}
+	spec.Tap = tap
+	backend := NewFakeBackend(echoScript(0))
+
+	if err := ExecuteWith(backend, spec); err != nil {
+		t.Fatalf("ExecuteWith: %v", err)
+	}
+
+	specs := backend.Specs()
+	if len(specs) != 1 || specs[0].Connection.Name != "web-1" {
+		t.Fatalf("backend got specs %+v, want one for web-1", specs)
+	}
+	text := out.String()
+	for _, want := range []string{"[+] Attempting to connect to: web-1", "uname -a\n", "[<] Disconnected from web-1 successfully."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output %q does not contain %q", text, want)
+		}
+	}
+	if got := tap.input.String(); got != "uname -a\n" {
+		t.Errorf("tap input = %q, want the typed command", got)
+	}
+	if got := tap.output.String(); got != "uname -a\n" {
+		t.Errorf("tap output = %q, want the echoed command", got)
+	}
+}
+
+func TestExecuteWithExitCode(t *testing.T) {
+	spec, out := testSpec("")
+	err := ExecuteWith(NewFakeBackend(FakeExit(3)), spec)
+	if code := ExitCodeOf(err); code != 3 {
+		t.Fatalf("ExecuteWith error %v has exit code %d, want 3", err, code)
+	}
+	if !strings.Contains(out.String(), "possibly with error: exit status 3") {
+		t.Errorf("output %q does not report the exit status", out.String())
+	}
+}
+
+func TestExecuteWithHooks(t *testing.T) {
+	spec, out := testSpec("")
+	spec.Hooks = config.Hooks{
+		Pre:  []config.Hook{{Command: "echo pre-hook"}},
+		Post: []config.Hook{{Command: "echo post-hook"}},
+	}
+	if err := ExecuteWith(NewFakeBackend(nil), spec); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	pre, session, post := strings.Index(text, "pre-hook"), strings.Index(text, "Attempting"), strings.Index(text, "post-hook")
+	if pre < 0 || session < 0 || post < 0 || pre > session || session > post {
+		t.Errorf("output %q does not run the hooks around the session", text)
+	}
+}
+
+func TestExecuteWithAbortingHook(t *testing.T) {
+	spec, _ := testSpec("")
+	spec.Hooks = config.Hooks{Pre: []config.Hook{{Command: "exit 1", AbortOnFailure: true}}}
+	backend := NewFakeBackend(nil)
+	if err := ExecuteWith(backend, spec); err == nil {
+		t.Fatal("ExecuteWith succeeded despite an aborting pre-connect hook")
+	}
+	if n := len(backend.Specs()); n != 0 {
+		t.Errorf("backend started %d sessions, want none", n)
+	}
+}
+
+func TestFakeRunnerSignal(t *testing.T) {
+	backend := NewFakeBackend(func(_ Spec, signals <-chan os.Signal) int {
+		<-signals
+		return exitCodeInterrupted
+	})
+	spec, _ := testSpec("")
+	r := backend.New(spec)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	var exitErr *ExitError
+	if err := r.Wait(); !errors.As(err, &exitErr) || exitErr.Code != exitCodeInterrupted {
+		t.Errorf("Wait = %v, want exit status %d", err, exitCodeInterrupted)
+	}
+	if err := r.Signal(os.Interrupt); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("Signal after exit = %v, want os.ErrProcessDone", err)
+	}
+}
@@ -0,0 +1,207 @@
+package sessiond
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/cancelreader"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// ErrNotRunning is returned when no session daemon is listening.
+var ErrNotRunning = errors.New("session daemon is not running")
+
+// dialTimeout bounds connecting to the daemon's socket.
+const dialTimeout = 2 * time.Second
+
+func dial() (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(), dialTimeout)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	return conn, nil
+}
+
+// roundTrip sends req and reads the daemon's answer on a fresh connection.
+func roundTrip(req request) (response, error) {
+	var resp response
+	conn, err := dial()
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	if err := writeJSONLine(conn, req); err != nil {
+		return resp, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return resp, fmt.Errorf("reading daemon response: %w", err)
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return resp, fmt.Errorf("invalid daemon response: %w", err)
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Running reports whether a daemon is listening.
+func Running() bool {
+	conn, err := dial()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// EnsureRunning starts the daemon with spawn if it isn't running yet and
+// waits for its socket to come up.
+func EnsureRunning(spawn func() error) error {
+	if Running() {
+		return nil
+	}
+	if err := spawn(); err != nil {
+		return fmt.Errorf("starting session daemon: %w", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if Running() {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("session daemon did not come up on %s", SocketPath())
+}
+
+// List returns the daemon's live sessions. It returns no sessions and no
+// error if the daemon isn't running.
+func List() ([]Info, error) {
+	resp, err := roundTrip(request{Op: opList})
+	if errors.Is(err, ErrNotRunning) {
+		return nil, nil
+	}
+	return resp.Sessions, err
+}
+
+// LiveCounts returns the number of live sessions per connection name.
+func LiveCounts() map[string]int {
+	counts := map[string]int{}
+	infos, _ := List()
+	for _, info := range infos {
+		counts[info.Connection]++
+	}
+	return counts
+}
+
+// Start opens a new background session to conn and returns its id.
+func Start(conn config.Connection) (string, error) {
+	resp, err := roundTrip(request{Op: opStart, Connection: &conn})
+	return resp.ID, err
+}
+
+// Kill terminates the session with the given id.
+func Kill(id string) error {
+	_, err := roundTrip(request{Op: opKill, ID: id})
+	return err
+}
+
+// Attach connects the terminal to session id until the user presses
+// DetachKey or the session ends. It reports whether the user detached.
+func Attach(id string, stdin *os.File, stdout io.Writer) (bool, error) {
+	conn, err := dial()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if err := writeJSONLine(conn, request{Op: opAttach, ID: id}); err != nil {
+		return false, err
+	}
+	br := bufio.NewReader(conn)
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		return false, fmt.Errorf("reading daemon response: %w", err)
+	}
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return false, fmt.Errorf("invalid daemon response: %w", err)
+	}
+	if !resp.OK {
+		return false, errors.New(resp.Error)
+	}
+
+	fd := stdin.Fd()
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return false, err
+		}
+		defer term.Restore(fd, state) //nolint:errcheck
+		sendSize(conn, fd)
+		stop := watchResize(func() { sendSize(conn, fd) })
+		defer stop()
+	}
+
+	input, err := cancelreader.NewReader(stdin)
+	if err != nil {
+		return false, err
+	}
+	defer input.Close()
+
+	detached := make(chan struct{})
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := input.Read(buf)
+			if n > 0 {
+				chunk := buf[:n]
+				if i := bytes.IndexByte(chunk, DetachKey); i >= 0 {
+					if i > 0 {
+						writeFrame(conn, frameData, chunk[:i]) //nolint:errcheck
+					}
+					writeFrame(conn, frameDetach, nil) //nolint:errcheck
+					close(detached)
+					return
+				}
+				if writeFrame(conn, frameData, chunk) != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	outDone := make(chan struct{})
+	go func() {
+		io.Copy(stdout, br) //nolint:errcheck
+		close(outDone)
+	}()
+
+	select {
+	case <-detached:
+		conn.Close()
+		<-outDone
+		return true, nil
+	case <-outDone:
+		input.Cancel()
+		return false, nil
+	}
+}
+
+func sendSize(conn net.Conn, fd uintptr) {
+	if w, h, err := term.GetSize(fd); err == nil {
+		writeFrame(conn, frameResize, resizePayload(w, h)) //nolint:errcheck
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package sessiond
+
+import (
+	"net"
+	"syscall"
+)
+
+// listen creates the socket at path without access for group and others,
+// from the start rather than after a chmod.
+func listen(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
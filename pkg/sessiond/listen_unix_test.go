@@ -0,0 +1,25 @@
+//go:build !windows
+
+package sessiond
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenCreatesPrivateSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.sock")
+	l, err := listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		t.Errorf("socket mode = %v, want no access for group and others", perm)
+	}
+}
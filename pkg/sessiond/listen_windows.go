@@ -0,0 +1,9 @@
+package sessiond
+
+import "net"
+
+// listen creates the socket at path. Windows has no umask; the socket lives
+// in the config directory under the user's profile, which others can't read.
+func listen(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,395 @@
+package sessiond
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/hooks"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+// scrollbackSize is how much recent output is replayed to a client on attach.
+const scrollbackSize = 64 * 1024
+
+// Server is the session daemon.
+type Server struct {
+	// Backend runs the sessions.
+	Backend runner.Backend
+	// NewTap, if set, is called for every new session. The returned Tap sees
+	// the session's traffic and done is called when the session ends.
+	NewTap func(conn config.Connection) (tap runner.Tap, done func())
+	// Hooks, if set, returns the hooks to run when a session for conn starts
+	// and ends. Their output goes to the daemon's log.
+	Hooks func(conn config.Connection) config.Hooks
+	// IdleTimeout stops the daemon after it had no sessions for this long.
+	// Zero keeps it running.
+	IdleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	nextID   int
+	idleFrom time.Time
+}
+
+type session struct {
+	info   Info
+	runner runner.Runner
+	input  *io.PipeWriter
+
+	mu         sync.Mutex
+	scrollback []byte
+	client     *client
+}
+
+// Write receives the session's output from the runner.
+func (s *session) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scrollback = append(s.scrollback, p...)
+	if over := len(s.scrollback) - scrollbackSize; over > 0 {
+		s.scrollback = append([]byte(nil), s.scrollback[over:]...)
+	}
+	if s.client != nil && !s.client.send(append([]byte(nil), p...)) {
+		log.Printf("Session %s: client too slow, detaching it.", s.info.ID)
+		s.detach(false)
+	}
+	return len(p), nil
+}
+
+// detach drops the attached client. With flush set, output queued for the
+// client is still written before its connection is closed. The caller holds
+// s.mu.
+func (s *session) detach(flush bool) {
+	if s.client == nil {
+		return
+	}
+	s.client.close(flush)
+	s.client = nil
+}
+
+// clientQueue is how many chunks of output may wait for an attached client
+// before it is considered stuck and dropped.
+const clientQueue = 256
+
+// clientWriteTimeout bounds a single write to an attached client.
+const clientWriteTimeout = 5 * time.Second
+
+// client is a connection attached to a session. Its output is queued and
+// written by a goroutine of its own, so a slow client never holds up the
+// session or the daemon.
+type client struct {
+	conn net.Conn
+	out  chan []byte
+}
+
+func newClient(conn net.Conn) *client {
+	c := &client{conn: conn, out: make(chan []byte, clientQueue)}
+	go c.run()
+	return c
+}
+
+func (c *client) run() {
+	failed := false
+	for p := range c.out {
+		if failed {
+			continue
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout)) //nolint:errcheck
+		if _, err := c.conn.Write(p); err != nil {
+			c.conn.Close()
+			failed = true
+		}
+	}
+	c.conn.Close()
+}
+
+// send queues p for the client and reports false if the queue is full.
+func (c *client) send(p []byte) bool {
+	select {
+	case c.out <- p:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the client. Without flush, pending output is discarded.
+func (c *client) close(flush bool) {
+	if !flush {
+		c.conn.Close()
+	}
+	close(c.out)
+}
+
+// ListenAndServe listens on SocketPath and serves until the daemon goes idle.
+func (srv *Server) ListenAndServe() error {
+	path := SocketPath()
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("session daemon already running on %s", path)
+	}
+	os.Remove(path) // Stale socket from a daemon that didn't shut down cleanly.
+
+	l, err := listen(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	return srv.Serve(l)
+}
+
+// Serve accepts clients on l until it is closed or the daemon goes idle.
+func (srv *Server) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	if srv.sessions == nil {
+		srv.sessions = map[string]*session{}
+	}
+	srv.idleFrom = time.Now()
+	srv.mu.Unlock()
+
+	if srv.IdleTimeout > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go srv.watchIdle(l, stop)
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go srv.handle(conn)
+	}
+}
+
+func (srv *Server) watchIdle(l net.Listener, stop <-chan struct{}) {
+	ticker := time.NewTicker(srv.IdleTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			srv.mu.Lock()
+			idle := len(srv.sessions) == 0 && time.Since(srv.idleFrom) >= srv.IdleTimeout
+			srv.mu.Unlock()
+			if idle {
+				log.Printf("No sessions for %v, shutting down.", srv.IdleTimeout)
+				l.Close()
+				return
+			}
+		}
+	}
+}
+
+func (srv *Server) handle(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return
+	}
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		writeJSONLine(conn, response{Error: "invalid request"}) //nolint:errcheck
+		conn.Close()
+		return
+	}
+
+	switch req.Op {
+	case opList:
+		writeJSONLine(conn, response{OK: true, Sessions: srv.list()}) //nolint:errcheck
+	case opStart:
+		if req.Connection == nil {
+			writeJSONLine(conn, response{Error: "missing connection"}) //nolint:errcheck
+			break
+		}
+		id, err := srv.start(*req.Connection)
+		if err != nil {
+			writeJSONLine(conn, response{Error: err.Error()}) //nolint:errcheck
+			break
+		}
+		writeJSONLine(conn, response{OK: true, ID: id}) //nolint:errcheck
+	case opKill:
+		if err := srv.kill(req.ID); err != nil {
+			writeJSONLine(conn, response{Error: err.Error()}) //nolint:errcheck
+			break
+		}
+		writeJSONLine(conn, response{OK: true, ID: req.ID}) //nolint:errcheck
+	case opAttach:
+		srv.attach(conn, br, req.ID)
+		return // attach owns conn
+	default:
+		writeJSONLine(conn, response{Error: fmt.Sprintf("unknown op '%s'", req.Op)}) //nolint:errcheck
+	}
+	conn.Close()
+}
+
+func (srv *Server) list() []Info {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	infos := make([]Info, 0, len(srv.sessions))
+	for _, s := range srv.sessions {
+		s.mu.Lock()
+		info := s.info
+		info.Attached = s.client != nil
+		s.mu.Unlock()
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Started.Before(infos[j].Started) })
+	return infos
+}
+
+func (srv *Server) start(conn config.Connection) (string, error) {
+	var sessionHooks config.Hooks
+	if srv.Hooks != nil {
+		sessionHooks = srv.Hooks(conn)
+	}
+	// The session outlives this request, so the hooks can't wrap it with
+	// runner.WithHooks.
+	if err := hooks.RunPre(conn, sessionHooks.Pre, log.Writer(), log.Writer()); err != nil {
+		return "", err
+	}
+	started := time.Now()
+
+	pr, pw := io.Pipe()
+	s := &session{input: pw}
+
+	var tapDone func()
+	spec := runner.Spec{
+		Connection: conn,
+		Args:       runner.ClientArgs(conn),
+		Stdin:      pr,
+		Stdout:     s,
+		Stderr:     s,
+		PTY:        true,
+		Hooks:      sessionHooks,
+	}
+	if srv.NewTap != nil {
+		spec.Tap, tapDone = srv.NewTap(conn)
+	}
+
+	s.runner = srv.Backend.New(spec)
+	if err := s.runner.Start(); err != nil {
+		pw.Close()
+		if tapDone != nil {
+			tapDone()
+		}
+		hooks.RunPost(conn, spec.Hooks.Post, time.Since(started), -1, log.Writer(), log.Writer())
+		return "", err
+	}
+
+	srv.mu.Lock()
+	srv.nextID++
+	id := strconv.Itoa(srv.nextID)
+	s.info = Info{ID: id, Connection: conn.Name, Started: time.Now()}
+	srv.sessions[id] = s
+	srv.mu.Unlock()
+	log.Printf("Session %s started for '%s'.", id, conn.Name)
+
+	go func() {
+		err := s.runner.Wait()
+		pw.Close()
+		if tapDone != nil {
+			tapDone()
+		}
+		s.mu.Lock()
+		s.detach(true)
+		s.mu.Unlock()
+
+		srv.mu.Lock()
+		delete(srv.sessions, id)
+		if len(srv.sessions) == 0 {
+			srv.idleFrom = time.Now()
+		}
+		srv.mu.Unlock()
+		log.Printf("Session %s for '%s' ended: %v", id, conn.Name, err)
+		hooks.RunPost(conn, spec.Hooks.Post, time.Since(started), s.runner.ExitCode(), log.Writer(), log.Writer())
+	}()
+	return id, nil
+}
+
+func (srv *Server) lookup(id string) (*session, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	s, ok := srv.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("no live session with id '%s'", id)
+	}
+	return s, nil
+}
+
+func (srv *Server) kill(id string) error {
+	s, err := srv.lookup(id)
+	if err != nil {
+		return err
+	}
+	return s.runner.Signal(os.Kill)
+}
+
+func (srv *Server) attach(conn net.Conn, br *bufio.Reader, id string) {
+	defer conn.Close()
+	s, err := srv.lookup(id)
+	if err != nil {
+		writeJSONLine(conn, response{Error: err.Error()}) //nolint:errcheck
+		return
+	}
+	if err := writeJSONLine(conn, response{OK: true, ID: id}); err != nil {
+		return
+	}
+
+	// Take over from any previously attached client and repaint the screen
+	// with recent output.
+	c := newClient(conn)
+	s.mu.Lock()
+	s.detach(false)
+	s.client = c
+	if len(s.scrollback) > 0 {
+		c.send(append([]byte(nil), s.scrollback...))
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if s.client == c {
+			s.detach(false)
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		kind, payload, err := readFrame(br)
+		if err != nil {
+			return
+		}
+		switch kind {
+		case frameData:
+			if _, err := s.input.Write(payload); err != nil {
+				return
+			}
+		case frameResize:
+			if len(payload) == 4 {
+				if rz, ok := s.runner.(runner.Resizer); ok {
+					rz.Resize(int(binary.BigEndian.Uint16(payload)), int(binary.BigEndian.Uint16(payload[2:]))) //nolint:errcheck
+				}
+			}
+		case frameDetach:
+			return
+		}
+	}
+}
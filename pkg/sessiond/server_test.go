@@ -0,0 +1,320 @@
+package sessiond
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+func TestFrames(t *testing.T) {
+	frames := []struct {
+		kind    byte
+		payload []byte
+	}{
+		{frameData, []byte("ls -l\r")},
+		{frameResize, resizePayload(120, 40)},
+		{frameDetach, nil},
+		{frameData, bytes.Repeat([]byte{0xff}, maxFrameSize)},
+	}
+	var buf bytes.Buffer
+	for _, f := range frames {
+		if err := writeFrame(&buf, f.kind, f.payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range frames {
+		kind, payload, err := readFrame(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind != want.kind || !bytes.Equal(payload, want.payload) {
+			t.Errorf("readFrame = %q (%d bytes), want %q (%d bytes)", kind, len(payload), want.kind, len(want.payload))
+		}
+	}
+	if _, _, err := readFrame(&buf); err != io.EOF {
+		t.Errorf("readFrame at the end = %v, want EOF", err)
+	}
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	var tooLarge bytes.Buffer
+	writeFrame(&tooLarge, frameData, make([]byte, maxFrameSize+1)) //nolint:errcheck
+	var truncated bytes.Buffer
+	writeFrame(&truncated, frameData, []byte("hello")) //nolint:errcheck
+	truncated.Truncate(truncated.Len() - 1)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"too large", tooLarge.Bytes(), "frame too large"},
+		{"short header", []byte{frameData, 0, 0}, io.ErrUnexpectedEOF.Error()},
+		{"short payload", truncated.Bytes(), io.ErrUnexpectedEOF.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readFrame(bytes.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readFrame = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResizePayload(t *testing.T) {
+	if got := resizePayload(0x0102, 0x0304); !bytes.Equal(got, []byte{1, 2, 3, 4}) {
+		t.Errorf("resizePayload = %v, want [1 2 3 4]", got)
+	}
+}
+
+// echoSession copies the session's input to its output until it is killed.
+func echoSession(spec runner.Spec, signals <-chan os.Signal) int {
+	go io.Copy(spec.Stdout, spec.Stdin) //nolint:errcheck
+	<-signals
+	return 0
+}
+
+// startDaemon serves a daemon running script on a socket in a temporary
+// config directory.
+func startDaemon(t *testing.T, script runner.FakeScript) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+
+	l, err := listen(SocketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{Backend: runner.NewFakeBackend(script)}
+	served := make(chan struct{})
+	go func() {
+		srv.Serve(l) //nolint:errcheck
+		close(served)
+	}()
+	t.Cleanup(func() {
+		l.Close()
+		<-served
+	})
+}
+
+// attachRaw attaches to session id and returns the connection after the
+// daemon accepted the request.
+func attachRaw(t *testing.T, id string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := writeJSONLine(conn, request{Op: opAttach, ID: id}); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil || !resp.OK {
+		t.Fatalf("attach response %q (%v), want ok", line, err)
+	}
+	return conn, br
+}
+
+// readUntil reads from conn until the output contains want.
+func readUntil(t *testing.T, conn net.Conn, r io.Reader, want string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	defer conn.SetReadDeadline(time.Time{})               //nolint:errcheck
+	var got []byte
+	buf := make([]byte, 1024)
+	for !bytes.Contains(got, []byte(want)) {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil {
+			t.Fatalf("read %q, then %v; want %q", got, err, want)
+		}
+	}
+}
+
+// waitAttached waits until List reports session id as attached or not.
+func waitAttached(t *testing.T, id string, attached bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		infos, err := List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) == 1 && infos[0].ID == id && infos[0].Attached == attached {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sessions = %+v, want %s with attached %v", infos, id, attached)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAttachDetach(t *testing.T) {
+	startDaemon(t, echoSession)
+	id, err := Start(config.Connection{Name: "web-1", Key: "k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitAttached(t, id, false)
+
+	conn, br := attachRaw(t, id)
+	waitAttached(t, id, true)
+	if err := writeFrame(conn, frameData, []byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, conn, br, "hello\n")
+	if err := writeFrame(conn, frameDetach, nil); err != nil {
+		t.Fatal(err)
+	}
+	waitAttached(t, id, false)
+
+	// Attaching again replays the scrollback; Attach returns on the detach key.
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	defer input.Close()
+	go io.WriteString(input, "again\n") //nolint:errcheck
+	var out syncBuffer
+	result := make(chan error, 1)
+	go func() {
+		detached, err := Attach(id, stdin, &out)
+		if err == nil && !detached {
+			err = errors.New("Attach returned without detaching")
+		}
+		result <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "again\n") {
+		if time.Now().After(deadline) {
+			t.Fatalf("attached output %q, want the scrollback and the echoed input", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.HasPrefix(out.String(), "hello\n") {
+		t.Errorf("attached output %q does not start with the scrollback", out.String())
+	}
+	input.Write([]byte{DetachKey}) //nolint:errcheck
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	waitAttached(t, id, false)
+
+	if err := Kill(id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAttachTakesOver(t *testing.T) {
+	startDaemon(t, echoSession)
+	id, err := Start(config.Connection{Name: "web-1", Key: "k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, firstReader := attachRaw(t, id)
+	second, secondReader := attachRaw(t, id)
+
+	first.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	if _, err := io.ReadAll(firstReader); err != nil {
+		t.Fatalf("first client: %v, want the daemon to close it", err)
+	}
+	if err := writeFrame(second, frameData, []byte("still here\n")); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, second, secondReader, "still here\n")
+}
+
+func TestSessionEndFlushesClient(t *testing.T) {
+	release := make(chan struct{})
+	startDaemon(t, func(spec runner.Spec, _ <-chan os.Signal) int {
+		<-release
+		io.WriteString(spec.Stdout, "bye\n") //nolint:errcheck
+		return 0
+	})
+	id, err := Start(config.Connection{Name: "web-1", Key: "k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, br := attachRaw(t, id)
+	waitAttached(t, id, true)
+	close(release)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	out, err := io.ReadAll(br)
+	if err != nil || string(out) != "bye\n" {
+		t.Errorf("read %q, %v; want the last output and then EOF", out, err)
+	}
+}
+
+func TestSlowClientDoesNotBlockSession(t *testing.T) {
+	const chunks = 4 * clientQueue
+	attached := make(chan struct{})
+	finished := make(chan struct{})
+	startDaemon(t, func(spec runner.Spec, signals <-chan os.Signal) int {
+		<-attached
+		chunk := bytes.Repeat([]byte("x"), 32*1024)
+		for range chunks {
+			spec.Stdout.Write(chunk) //nolint:errcheck
+		}
+		close(finished)
+		<-signals
+		return 0
+	})
+	id, err := Start(config.Connection{Name: "web-1", Key: "k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachRaw(t, id) // Never read from.
+	waitAttached(t, id, true)
+	close(attached)
+
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("session output blocked on a client that doesn't read")
+	}
+	waitAttached(t, id, false)
+	if err := Kill(id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for one writer and concurrent readers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,114 @@
+// Package sessiond keeps gs-netcat sessions running in a background daemon so
+// they survive the gsm process that started them. Clients talk to the daemon
+// over a Unix socket in the config directory and can attach to, detach from,
+// list and kill its sessions.
+package sessiond
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// SocketName is the file name of the daemon's socket in the config directory.
+const SocketName = "sessiond.sock"
+
+// DetachKey detaches an attached client from its session (Ctrl+]).
+const DetachKey = 0x1d
+
+// DetachKeyName is DetachKey as shown to users.
+const DetachKeyName = "Ctrl+]"
+
+// SocketPath returns the path of the daemon's socket.
+func SocketPath() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigFilePath), SocketName)
+}
+
+// Info describes a live background session.
+type Info struct {
+	ID         string    `json:"id"`
+	Connection string    `json:"connection"`
+	Started    time.Time `json:"started"`
+	Attached   bool      `json:"attached"`
+}
+
+const (
+	opList   = "list"
+	opStart  = "start"
+	opAttach = "attach"
+	opKill   = "kill"
+)
+
+// request is the first line a client sends on a new socket connection.
+type request struct {
+	Op         string             `json:"op"`
+	ID         string             `json:"id,omitempty"`
+	Connection *config.Connection `json:"connection,omitempty"`
+}
+
+// response answers a request. After a successful attach the socket carries
+// raw session output from the daemon and frames from the client.
+type response struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Sessions []Info `json:"sessions,omitempty"`
+}
+
+// Client to daemon frames sent while attached: a type byte, a big-endian
+// uint32 length and the payload.
+const (
+	frameData   = 'd'
+	frameResize = 'r'
+	frameDetach = 'x'
+)
+
+// maxFrameSize bounds the payload of a single frame.
+const maxFrameSize = 1 << 20
+
+func writeFrame(w io.Writer, kind byte, payload []byte) error {
+	hdr := make([]byte, 5)
+	hdr[0] = kind
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(payload)))
+	if _, err := w.Write(append(hdr, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	hdr := make([]byte, 5)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large: %d bytes", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return hdr[0], payload, nil
+}
+
+func resizePayload(cols, rows int) []byte {
+	p := make([]byte, 4)
+	binary.BigEndian.PutUint16(p, uint16(cols))
+	binary.BigEndian.PutUint16(p[2:], uint16(rows))
+	return p
+}
+
+func writeJSONLine(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package sessiond
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize calls fn on every terminal resize until the returned function is called.
+func watchResize(fn func()) func() {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGWINCH)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package sessiond
+
+// watchResize is a no-op on Windows, which has no SIGWINCH.
+func watchResize(fn func()) func() { return func() {} }
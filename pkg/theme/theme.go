@@ -0,0 +1,195 @@
+// Package theme holds the color themes of the TUI: the built-in ones and
+// custom ones from JSON files in the config directory.
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// DirName is the directory under the config dir holding custom themes.
+const DirName = "themes"
+
+// Auto picks a theme from NO_COLOR and the terminal background.
+const Auto = "auto"
+
+// Theme is the set of colors the TUI draws with. Colors are ANSI numbers
+// ("32") or hex values ("#005f87"). An empty color keeps the terminal's own;
+// the TUI then falls back to reverse, bold and faint text where it needs
+// contrast.
+type Theme struct {
+	// Name is the name the theme was loaded by.
+	Name string `json:"-"`
+	// Base is the theme a custom theme starts from (default dark).
+	Base string `json:"base,omitempty"`
+
+	Text   string `json:"text"`
+	Muted  string `json:"muted"`
+	Subtle string `json:"subtle"`
+	Faint  string `json:"faint"`
+	Border string `json:"border"`
+
+	Title     string `json:"title"`
+	TitleText string `json:"title_text"`
+	Bar       string `json:"bar"`
+
+	Selection     string `json:"selection"`
+	SelectionText string `json:"selection_text"`
+	SelectionDesc string `json:"selection_desc"`
+	Inactive      string `json:"inactive"`
+	InactiveText  string `json:"inactive_text"`
+	Cursor        string `json:"cursor"`
+
+	Success string `json:"success"`
+	Error   string `json:"error"`
+	Warning string `json:"warning"`
+
+	Online           string `json:"online"`
+	NoListener       string `json:"no_listener"`
+	RelayUnreachable string `json:"relay_unreachable"`
+	Timeout          string `json:"timeout"`
+	ProbeError       string `json:"probe_error"`
+}
+
+// Built-in themes.
+var (
+	Dark = Theme{
+		Name: "dark",
+		Text: "252", Muted: "245", Subtle: "242", Faint: "240", Border: "239",
+		Title: "23", TitleText: "231", Bar: "235",
+		Selection: "32", SelectionText: "231", SelectionDesc: "228",
+		Inactive: "238", InactiveText: "252", Cursor: "202",
+		Success: "40", Error: "196", Warning: "214",
+		Online: "42", NoListener: "196", RelayUnreachable: "208", Timeout: "214", ProbeError: "160",
+	}
+	Light = Theme{
+		Name: "light",
+		Text: "235", Muted: "240", Subtle: "243", Faint: "245", Border: "250",
+		Title: "24", TitleText: "231", Bar: "254",
+		Selection: "31", SelectionText: "231", SelectionDesc: "230",
+		Inactive: "252", InactiveText: "235", Cursor: "166",
+		Success: "28", Error: "160", Warning: "130",
+		Online: "28", NoListener: "160", RelayUnreachable: "166", Timeout: "130", ProbeError: "124",
+	}
+	// HighContrast sticks to the 16 basic colors, which terminals map to
+	// their own palette.
+	HighContrast = Theme{
+		Name: "high-contrast",
+		Text: "15", Muted: "15", Subtle: "7", Faint: "7", Border: "15",
+		Title: "15", TitleText: "0", Bar: "0",
+		Selection: "11", SelectionText: "0", SelectionDesc: "0",
+		Inactive: "7", InactiveText: "0", Cursor: "11",
+		Success: "10", Error: "9", Warning: "11",
+		Online: "10", NoListener: "9", RelayUnreachable: "13", Timeout: "11", ProbeError: "9",
+	}
+	// Mono uses no colors at all.
+	Mono = Theme{Name: "mono"}
+)
+
+var builtins = map[string]Theme{
+	Dark.Name:         Dark,
+	Light.Name:        Light,
+	HighContrast.Name: HighContrast,
+	Mono.Name:         Mono,
+}
+
+// Dir returns the directory holding custom themes.
+func Dir() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigFilePath), DirName)
+}
+
+// Names returns the names of the built-in and custom themes, sorted.
+func Names() []string {
+	seen := map[string]bool{}
+	for name := range builtins {
+		seen[name] = true
+	}
+	custom, _ := filepath.Glob(filepath.Join(Dir(), "*.json"))
+	for _, path := range custom {
+		seen[strings.TrimSuffix(filepath.Base(path), ".json")] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the theme called name. A file name.json in Dir takes
+// precedence over a built-in theme; the colors it leaves out come from its
+// base theme.
+func Load(name string) (Theme, error) {
+	return load(name, 0)
+}
+
+func load(name string, depth int) (Theme, error) {
+	if depth > len(builtins) {
+		return Theme{}, fmt.Errorf("theme %s: base themes form a loop", name)
+	}
+	path := filepath.Join(Dir(), name+".json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if t, ok := builtins[name]; ok {
+			return t, nil
+		}
+		return Theme{}, fmt.Errorf("unknown theme '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	if err != nil {
+		return Theme{}, fmt.Errorf("failed to read theme '%s': %w", path, err)
+	}
+
+	var head struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return Theme{}, fmt.Errorf("failed to parse theme '%s': %w", path, err)
+	}
+	base := Dark
+	if head.Base != "" {
+		if head.Base == name {
+			// A custom theme may refine the built-in one of the same name.
+			t, ok := builtins[name]
+			if !ok {
+				return Theme{}, fmt.Errorf("theme %s can't be its own base", name)
+			}
+			base = t
+		} else if base, err = load(head.Base, depth+1); err != nil {
+			return Theme{}, err
+		}
+	}
+	t := base
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("failed to parse theme '%s': %w", path, err)
+	}
+	t.Name = name
+	return t, nil
+}
+
+// Detect returns the theme to use when none was chosen: mono when NO_COLOR
+// is set, otherwise dark or light to match the terminal background.
+func Detect() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return Mono.Name
+	}
+	if lipgloss.HasDarkBackground() {
+		return Dark.Name
+	}
+	return Light.Name
+}
+
+// Resolve loads the theme called name, detecting one for "" and Auto.
+func Resolve(name string) (Theme, error) {
+	if name == "" || name == Auto {
+		name = Detect()
+	}
+	return Load(name)
+}
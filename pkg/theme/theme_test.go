@@ -0,0 +1,134 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// useThemes writes files, theme name to JSON, to the themes directory of a
+// temporary config directory.
+func useThemes(t *testing.T, files map[string]string) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(Dir(), name+".json"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadBuiltin(t *testing.T) {
+	useThemes(t, nil)
+	for _, want := range []Theme{Dark, Light, HighContrast, Mono} {
+		got, err := Load(want.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Load(%s) = %+v, want the built-in theme", want.Name, got)
+		}
+	}
+	if got := Names(); !slices.Equal(got, []string{"dark", "high-contrast", "light", "mono"}) {
+		t.Errorf("Names = %q", got)
+	}
+}
+
+func TestLoadCustom(t *testing.T) {
+	useThemes(t, map[string]string{
+		"ocean":   `{"selection": "#005f87"}`,
+		"paper":   `{"base": "light", "error": "124"}`,
+		"deep":    `{"base": "ocean", "text": "255"}`,
+		"mono":    `{"base": "mono", "error": "1"}`,
+		"fancy":   `{"base": "dark", "unknown_field": "x"}`,
+		"invalid": `{`,
+	})
+	// with returns base named name with the colors set by edit.
+	with := func(base Theme, name string, edit func(*Theme)) Theme {
+		base.Name = name
+		edit(&base)
+		return base
+	}
+
+	tests := []Theme{
+		with(Dark, "ocean", func(t *Theme) { t.Selection = "#005f87" }),
+		with(Light, "paper", func(t *Theme) { t.Base, t.Error = "light", "124" }),
+		with(Dark, "deep", func(t *Theme) { t.Base, t.Selection, t.Text = "ocean", "#005f87", "255" }),
+		with(Mono, "mono", func(t *Theme) { t.Base, t.Error = "mono", "1" }),
+		with(Dark, "fancy", func(t *Theme) { t.Base = "dark" }),
+	}
+	for _, want := range tests {
+		got, err := Load(want.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Load(%s) = %+v, want %+v", want.Name, got, want)
+		}
+	}
+
+	want := []string{"dark", "deep", "fancy", "high-contrast", "invalid", "light", "mono", "ocean", "paper"}
+	if got := Names(); !slices.Equal(got, want) {
+		t.Errorf("Names = %q, want %q", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	useThemes(t, map[string]string{
+		"broken":  `{"text": 1`,
+		"typed":   `{"text": 1}`,
+		"orphan":  `{"base": "nope"}`,
+		"self":    `{"base": "self"}`,
+		"ping":    `{"base": "pong"}`,
+		"pong":    `{"base": "ping"}`,
+		"parent":  `{"base": "broken"}`,
+		"nothing": ``,
+	})
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"solarized", "unknown theme 'solarized' (available: broken, dark, high-contrast, light, mono, nothing, orphan, parent, ping, pong, self, typed)"},
+		{"broken", "failed to parse theme"},
+		{"typed", "failed to parse theme"},
+		{"nothing", "failed to parse theme"},
+		{"orphan", "unknown theme 'nope'"},
+		{"self", "can't be its own base"},
+		{"ping", "base themes form a loop"},
+		{"parent", "failed to parse theme"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(tt.name); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%s) = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	useThemes(t, map[string]string{"ocean": `{"selection": "#005f87"}`})
+	t.Setenv("NO_COLOR", "1")
+	for _, name := range []string{"", Auto} {
+		got, err := Resolve(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != Mono.Name {
+			t.Errorf("Resolve(%q) under NO_COLOR = %s, want mono", name, got.Name)
+		}
+	}
+	if got, err := Resolve("ocean"); err != nil || got.Name != "ocean" {
+		t.Errorf("Resolve(ocean) = %s, %v; want the named theme even under NO_COLOR", got.Name, err)
+	}
+	if _, err := Resolve("nope"); err == nil {
+		t.Error("Resolve(nope) succeeded, want an error")
+	}
+}
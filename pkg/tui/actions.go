@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runAction runs a list action on the highlighted connection, or on the
+// selected ones for bulk actions. Keys and the command palette both end up
+// here. It reports whether the action applied; a key that doesn't apply
+// goes on to the list.
+func (m Model) runAction(action string) (Model, tea.Cmd, bool) {
+	if cmd, handled := m.runSelectionAction(action); handled {
+		return m, cmd, true
+	}
+
+	switch action {
+	case actQuit:
+		return m, tea.Quit, true
+	case actConnect, actBackground:
+		if selected, ok := m.List.SelectedItem().(Item); ok {
+			return m, m.connect(selected, action == actBackground), true
+		}
+	case actEdit:
+		if cmd, ok := m.startEdit(); ok {
+			return m, cmd, true
+		}
+	case actDelete:
+		if m.confirmDelete() {
+			return m, nil, true
+		}
+	case actAdd:
+		return m, m.startAdd(), true
+	case actNew:
+		next, cmd := m.createGenerated()
+		return next.(Model), cmd, true
+	case actTunnels:
+		if Tunnels != nil {
+			return m, m.openTunnels(), true
+		}
+	case actCheck:
+		if cmd := m.checkSelected(); cmd != nil {
+			m.StatusMessage = ""
+			m.StatusType = StatusNone
+			return m, cmd, true
+		}
+	case actTags:
+		return m, m.toggleTagPanel(), true
+	case actQuery:
+		return m, m.openQuery(m.query.String()), true
+	case actFocusPanel:
+		if m.tagPanel != nil {
+			m.tagPanel.focused = true
+			return m, nil, true
+		}
+	case actCopy:
+		if m.openCopyMenu() {
+			return m, nil, true
+		}
+	case actHelp:
+		return m, m.openHelp(), true
+	case actPalette:
+		return m, m.openPalette(), true
+	case actSort:
+		return m, m.setSort(m.sort.next()), true
+	case actSortReverse:
+		if m.sort.by != sortNone {
+			return m, m.setSort(sortOrder{by: m.sort.by, desc: !m.sort.desc}), true
+		}
+	}
+	return m, nil, false
+}
+
+// configIndex returns the index in the config of the highlighted connection.
+func (m Model) configIndex() (Item, int, bool) {
+	selected, ok := m.List.SelectedItem().(Item)
+	if !ok {
+		return Item{}, -1, false
+	}
+	for i, conn := range config.GetCurrent().Connections {
+		if conn.Name == selected.Name && conn.Key == selected.Key {
+			return selected, i, true
+		}
+	}
+	return Item{}, -1, false
+}
+
+// startEdit opens the form on the highlighted connection.
+func (m *Model) startEdit() (tea.Cmd, bool) {
+	selected, index, ok := m.configIndex()
+	if !ok {
+		return nil, false
+	}
+	m.IsEditing = true
+	m.EditingIndex = index
+	m.EditNameInput.SetValue(selected.Name)
+	m.EditKeyInput.SetValue(selected.Key)
+	m.EditTagsInput.SetValue(strings.Join(selected.Tags, ", "))
+	m.EditFocusIndex = focusEditName
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.EditNameInput.Focus(), true
+}
+
+// confirmDelete asks whether to delete the highlighted connection.
+func (m *Model) confirmDelete() bool {
+	selected, index, ok := m.configIndex()
+	if !ok {
+		return false
+	}
+	m.IsConfirmingDelete = true
+	m.DeleteIndex = index
+	m.DeleteConnectionName = selected.Name
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return true
+}
+
+// startAdd opens the empty form for a new connection.
+func (m *Model) startAdd() tea.Cmd {
+	m.IsEditing = true
+	m.EditingIndex = EditingIndexAddNew
+	m.EditNameInput.SetValue("")
+	m.EditKeyInput.SetValue("")
+	m.EditTagsInput.SetValue("")
+	m.EditFocusIndex = focusEditName
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.EditNameInput.Focus()
+}
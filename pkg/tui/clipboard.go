@@ -0,0 +1,184 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/clipboard"
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/deploy"
+	"github.com/NumeXx/gsm/pkg/runner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyTarget is something the copy menu puts on the clipboard.
+type copyTarget struct {
+	action string
+	label  string
+	// secret targets contain the key and are cleared after a while.
+	secret bool
+	text   func(conn config.Connection) (string, error)
+}
+
+// copyTargets are the entries of the copy menu, picked with the key of their
+// action.
+var copyTargets = []copyTarget{
+	{actCopyKey, "key", true, func(conn config.Connection) (string, error) {
+		return conn.Key, nil
+	}},
+	{actCopyName, "name", false, func(conn config.Connection) (string, error) {
+		return conn.Name, nil
+	}},
+	{actCopyClient, "client command", true, func(conn config.Connection) (string, error) {
+		return "gs-netcat " + strings.Join(runner.ClientArgs(conn), " "), nil
+	}},
+	{actCopyListener, "listener command", true, func(conn config.Connection) (string, error) {
+		// The one-liner of gsm deploy, so a custom template applies.
+		var b bytes.Buffer
+		if err := deploy.Render(&b, "oneliner", conn); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(b.String()), nil
+	}},
+}
+
+// Terminal is the output of the TUI; pass it to tea.WithOutput. Its writes
+// don't interleave, so the clipboard escapes written by commands reach the
+// terminal between two frames rather than in the middle of one.
+var Terminal = &terminal{File: os.Stdout}
+
+// terminal is a terminal file that serializes writes.
+type terminal struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(p)
+}
+
+// clipboardCopiedMsg reports a finished copy.
+type clipboardCopiedMsg struct {
+	id     int
+	name   string
+	label  string
+	secret bool
+	err    error
+}
+
+// clipboardClearMsg is sent when copy id is due to be cleared.
+type clipboardClearMsg struct {
+	id int
+}
+
+// clipboardClearedMsg reports a finished clear.
+type clipboardClearedMsg struct {
+	err error
+}
+
+// openCopyMenu asks what to copy from the highlighted connection.
+func (m *Model) openCopyMenu() bool {
+	if _, ok := m.List.SelectedItem().(Item); !ok {
+		return false
+	}
+	m.copyMenu = true
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return true
+}
+
+// updateCopyMenu copies the target picked in the copy menu.
+func (m Model) updateCopyMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.copyMenu = false
+	if msg.String() == forceQuitKey {
+		return m, tea.Quit
+	}
+	action, _ := keys.action(msg, scopeCopy)
+	for _, target := range copyTargets {
+		if action == target.action {
+			return m, m.copy(target)
+		}
+	}
+	return m, nil
+}
+
+// copy puts target of the highlighted connection on the clipboard.
+func (m *Model) copy(target copyTarget) tea.Cmd {
+	item, ok := m.List.SelectedItem().(Item)
+	if !ok {
+		return nil
+	}
+	text, err := target.text(item.Connection)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Copy failed: %v", err)
+		m.StatusType = StatusError
+		return nil
+	}
+	m.clipboardID++
+	msg := clipboardCopiedMsg{id: m.clipboardID, name: item.Name, label: target.label, secret: target.secret}
+	mode := config.GetCurrent().Settings.Clipboard
+	return func() tea.Msg {
+		msg.err = clipboard.Copy(Terminal, mode, text)
+		return msg
+	}
+}
+
+// applyClipboardCopied reports the copy and schedules clearing a secret.
+func (m *Model) applyClipboardCopied(msg clipboardCopiedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.StatusMessage = fmt.Sprintf("Copy failed: %v", msg.err)
+		m.StatusType = StatusError
+		return nil
+	}
+	m.StatusMessage = fmt.Sprintf("Copied the %s of '%s'.", msg.label, msg.name)
+	m.StatusType = StatusSuccess
+	if msg.id != m.clipboardID {
+		return nil
+	}
+	m.clipboardSecret = 0
+	clearAfter := config.GetCurrent().Settings.ClipboardClearAfter()
+	if !msg.secret || clearAfter <= 0 {
+		return nil
+	}
+	m.clipboardSecret = msg.id
+	m.StatusMessage = fmt.Sprintf("Copied the %s of '%s' (cleared in %s).", msg.label, msg.name, clearAfter)
+	return tea.Tick(clearAfter, func(time.Time) tea.Msg { return clipboardClearMsg{id: msg.id} })
+}
+
+// applyClipboardClear clears the clipboard if nothing was copied since the
+// secret it is due for.
+func (m *Model) applyClipboardClear(msg clipboardClearMsg) tea.Cmd {
+	if msg.id != m.clipboardSecret || msg.id != m.clipboardID {
+		return nil
+	}
+	m.clipboardSecret = 0
+	mode := config.GetCurrent().Settings.Clipboard
+	return func() tea.Msg {
+		return clipboardClearedMsg{err: clipboard.Clear(Terminal, mode)}
+	}
+}
+
+// Cleanup clears a copied secret that is still due to be cleared. Call it
+// after the program has ended.
+func (m Model) Cleanup() {
+	if m.clipboardSecret != 0 {
+		clipboard.Clear(Terminal, config.GetCurrent().Settings.Clipboard) //nolint:errcheck
+	}
+}
+
+// viewCopyMenu renders the copy menu in place of the footer.
+func (m Model) viewCopyMenu() string {
+	var parts []string
+	for _, target := range copyTargets {
+		if label := keys.first(target.action); label != "" {
+			parts = append(parts, label+" "+target.label)
+		}
+	}
+	return styles.bar.Padding(0, 1).Render("copy: " + strings.Join(append(parts, "esc cancel"), " • "))
+}
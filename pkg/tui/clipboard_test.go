@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+func TestClipboardClearSurvivesReload(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"})
+	m := NewModel(config.GetCurrent())
+	if m.copy(copyTargets[0]) == nil {
+		t.Fatal("copy returned no command")
+	}
+	if m.applyClipboardCopied(clipboardCopiedMsg{id: m.clipboardID, name: "web-1", label: "key", secret: true}) == nil {
+		t.Fatal("copying a secret scheduled no clear")
+	}
+
+	// Adding, editing or deleting a connection reloads the model.
+	m = m.reloaded()
+	if m.applyClipboardClear(clipboardClearMsg{id: 1}) == nil {
+		t.Error("the clear of the copied secret was dropped after a reload")
+	}
+	if m.clipboardSecret != 0 {
+		t.Errorf("clipboardSecret = %d after clearing, want 0", m.clipboardSecret)
+	}
+}
+
+func TestStaleClipboardClearAfterReload(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"})
+	m := NewModel(config.GetCurrent())
+	m.copy(copyTargets[0])
+	m.applyClipboardCopied(clipboardCopiedMsg{id: m.clipboardID, secret: true})
+	m = m.reloaded()
+
+	// The clear of the first copy must not cut the time of a later one short.
+	m.copy(copyTargets[2])
+	m.applyClipboardCopied(clipboardCopiedMsg{id: m.clipboardID, secret: true})
+	if m.applyClipboardClear(clipboardClearMsg{id: 1}) != nil {
+		t.Error("a stale clear would wipe a later copy")
+	}
+}
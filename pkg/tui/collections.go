@@ -0,0 +1,184 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/query"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// collectionCount is a saved collection and the number of connections in it.
+type collectionCount struct {
+	name  string
+	query string
+	count int
+	// invalid is set when the saved query no longer parses.
+	invalid bool
+}
+
+// countCollections returns the collections of cfg with their live counts,
+// in config order.
+func countCollections(cfg config.Config, now time.Time) []collectionCount {
+	counts := make([]collectionCount, 0, len(cfg.Collections))
+	for _, col := range cfg.Collections {
+		cc := collectionCount{name: col.Name, query: col.Query}
+		if q, err := query.Parse(col.Query); err != nil {
+			cc.invalid = true
+		} else {
+			cc.count = len(q.Filter(cfg.Connections, now))
+		}
+		counts = append(counts, cc)
+	}
+	return counts
+}
+
+// activeCollection returns the name of the collection the list is showing:
+// the one the query was taken from, as long as the query is unchanged.
+func (m Model) activeCollection() (string, bool) {
+	if m.collection == "" || m.query.Empty() {
+		return "", false
+	}
+	col, ok := config.GetCurrent().FindCollection(m.collection)
+	if !ok || strings.TrimSpace(col.Query) != m.query.String() {
+		return "", false
+	}
+	return col.Name, true
+}
+
+// applyCollection shows the collection called name, or the whole list again
+// if it is already shown.
+func (m *Model) applyCollection(name string) tea.Cmd {
+	if active, ok := m.activeCollection(); ok && active == name {
+		m.query = nil
+		m.collection = ""
+		return m.refreshItems()
+	}
+	col, ok := config.GetCurrent().FindCollection(name)
+	if !ok {
+		return nil
+	}
+	q, err := query.Parse(col.Query)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Collection '%s': %v", name, err)
+		m.StatusType = StatusError
+		return nil
+	}
+	m.query = q
+	m.collection = name
+	if q.Empty() {
+		m.query = nil
+	}
+	return m.refreshItems()
+}
+
+// editCollection shows the collection called name and opens its query in
+// the query bar, where ctrl+s saves the changes.
+func (m *Model) editCollection(name string) tea.Cmd {
+	col, ok := config.GetCurrent().FindCollection(name)
+	if !ok {
+		return nil
+	}
+	m.collection = name
+	if q, err := query.Parse(col.Query); err == nil && !q.Empty() {
+		m.query = q
+	}
+	return tea.Batch(m.refreshItems(), m.openQuery(col.Query))
+}
+
+// openCollectionPrompt asks for the name to save the current query under,
+// starting with the collection it came from.
+func (m *Model) openCollectionPrompt() tea.Cmd {
+	if m.StatusType == StatusError || m.query.Empty() {
+		m.StatusMessage = "Type a valid query to save first."
+		m.StatusType = StatusError
+		return nil
+	}
+	input := newInput()
+	input.Prompt = "Save as: "
+	input.Placeholder = "stale"
+	input.CharLimit = 50
+	input.SetValue(m.collection)
+	input.CursorEnd()
+	m.collectionInput = &input
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.collectionInput.Focus()
+}
+
+// updateCollectionPrompt handles keys while the name of a collection is
+// asked for. Enter saves it and closes the query bar; Esc goes back to it.
+func (m Model) updateCollectionPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.Type {
+		case tea.KeyEsc, tea.KeyCtrlC:
+			m.collectionInput = nil
+			return m, nil
+		case tea.KeyEnter:
+			return m.saveCollection()
+		}
+	}
+	input, cmd := m.collectionInput.Update(msg)
+	m.collectionInput = &input
+	return m, cmd
+}
+
+// saveCollection stores the current query under the name typed in the
+// collection prompt.
+func (m Model) saveCollection() (tea.Model, tea.Cmd) {
+	name := strings.TrimSpace(m.collectionInput.Value())
+	if name == "" || strings.ContainsAny(name, ", ") {
+		m.StatusMessage = "A collection name must be non-empty without spaces or commas."
+		m.StatusType = StatusError
+		return m, nil
+	}
+	_, existed := config.GetCurrent().FindCollection(name)
+	config.SetCollection(name, m.query.String())
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving collection: %v", err)
+		m.StatusType = StatusError
+		return m, nil
+	}
+	m.collection = name
+	m.collectionInput = nil
+	m.queryInput = nil
+	verb := "Saved"
+	if existed {
+		verb = "Updated"
+	}
+	m.StatusMessage = fmt.Sprintf("%s collection '%s' (%d connection(s)).", verb, name, len(m.query.Filter(config.GetCurrent().Connections, time.Now())))
+	m.StatusType = StatusSuccess
+	return m, m.refreshItems()
+}
+
+// deleteCollection removes the collection the current query came from. The
+// query itself stays in effect.
+func (m *Model) deleteCollection() tea.Cmd {
+	if m.collection == "" {
+		m.StatusMessage = "The query isn't from a saved collection."
+		m.StatusType = StatusError
+		return nil
+	}
+	name := m.collection
+	if !config.DeleteCollection(name) {
+		m.collection = ""
+		return nil
+	}
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving: %v", err)
+		m.StatusType = StatusError
+		return nil
+	}
+	m.collection = ""
+	m.StatusMessage = fmt.Sprintf("Deleted collection '%s'.", name)
+	m.StatusType = StatusSuccess
+	return m.refreshItems()
+}
+
+// viewCollectionPrompt renders the collection prompt in place of the footer.
+func (m Model) viewCollectionPrompt() string {
+	hint := styles.subtle.Render("  enter save • esc back")
+	return m.collectionInput.View() + hint
+}
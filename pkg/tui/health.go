@@ -0,0 +1,341 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+	"github.com/NumeXx/gsm/pkg/uptime"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ProbeBackend runs the TUI's background liveness checks.
+var ProbeBackend runner.Backend = runner.DefaultBackend
+
+// probeTickMsg starts a round of background checks.
+type probeTickMsg struct{}
+
+// probeResultsMsg carries finished checks. round is set for scheduled rounds
+// so the next one is only scheduled once the previous one is done.
+type probeResultsMsg struct {
+	results []runner.ProbeResult
+	round   bool
+	// uptime is the refreshed history of the checked connections; uptimeErr
+	// is set if the results could not be added to it.
+	uptime    map[string]uptimeSummary
+	uptimeErr error
+}
+
+// uptimeLoadedMsg carries the availability of connections read from disk.
+type uptimeLoadedMsg map[string]uptimeSummary
+
+func scheduleProbes(interval time.Duration) tea.Cmd {
+	if interval <= 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg { return probeTickMsg{} })
+}
+
+func probeConnections(conns []config.Connection, parallel int, round bool) tea.Cmd {
+	return func() tea.Msg {
+		results := runner.ProbeAll(context.Background(), ProbeBackend, conns, parallel, runner.DefaultProbeTimeout)
+		msg := probeResultsMsg{results: results, round: round, uptime: map[string]uptimeSummary{}}
+		for _, res := range results {
+			if err := uptime.Record(res.Connection, res.Record()); err != nil {
+				msg.uptimeErr = err
+			}
+			msg.uptime[res.Connection] = loadUptime(res.Connection)
+		}
+		return msg
+	}
+}
+
+// loadUptimes reads the availability of the connections in items.
+func loadUptimes(items []list.Item) tea.Cmd {
+	var names []string
+	for _, listItem := range items {
+		if item, ok := listItem.(Item); ok {
+			names = append(names, item.Name)
+		}
+	}
+	return func() tea.Msg {
+		summaries := uptimeLoadedMsg{}
+		for _, name := range names {
+			summaries[name] = loadUptime(name)
+		}
+		return summaries
+	}
+}
+
+// startProbeRound checks every connection whose last check is older than the
+// probe interval.
+func (m *Model) startProbeRound() tea.Cmd {
+	var stale []config.Connection
+	var cmds []tea.Cmd
+	for i, listItem := range m.List.Items() {
+		item, ok := listItem.(Item)
+		if !ok || item.Checking {
+			continue
+		}
+		if item.LastProbe != nil && time.Since(item.LastProbe.At) < m.probeInterval {
+			continue
+		}
+		item.Checking = true
+		cmds = append(cmds, m.List.SetItem(i, item))
+		stale = append(stale, item.Connection)
+	}
+	if len(stale) == 0 {
+		return tea.Batch(append(cmds, scheduleProbes(m.probeInterval))...)
+	}
+	return tea.Batch(append(cmds, probeConnections(stale, m.probeParallel, true))...)
+}
+
+// checkSelected re-checks the selected connection right away.
+func (m *Model) checkSelected() tea.Cmd {
+	item, ok := m.List.SelectedItem().(Item)
+	if !ok || item.Checking {
+		return nil
+	}
+	item.Checking = true
+	return tea.Batch(
+		m.List.SetItem(m.List.Index(), item),
+		probeConnections([]config.Connection{item.Connection}, 1, false),
+	)
+}
+
+// storeProbeResults saves results as the last checks of their connections.
+// The file is reloaded first so changes made meanwhile by other gsm processes
+// survive, except while the edit form or delete prompt hold an index into
+// the loaded connections: then the results are only applied in memory and
+// saved with the form. It reports whether the config was reloaded.
+func storeProbeResults(results []runner.ProbeResult, holdsIndex bool) (bool, error) {
+	if holdsIndex {
+		for _, res := range results {
+			config.SetLastProbe(res.Connection, res.Record())
+		}
+		return false, nil
+	}
+	if err := config.Load(); err != nil {
+		return false, err
+	}
+	for _, res := range results {
+		config.SetLastProbe(res.Connection, res.Record()) // Skips connections deleted meanwhile.
+	}
+	return true, config.Save()
+}
+
+// inOverlay reports whether a form, prompt, overlay or the list filter is
+// open, which reloading the model would close.
+func (m Model) inOverlay() bool {
+	return m.IsEditing || m.IsConfirmingDelete || m.bulk != nil || m.help != nil || m.palette != nil ||
+		m.copyMenu || m.tunnels != nil || m.queryInput != nil || m.collectionInput != nil ||
+		m.List.FilterState() != list.Unfiltered
+}
+
+// applyProbeResults stores the results and shows them in the list, which is
+// rebuilt from the reloaded config when nothing is open on top of it.
+func (m *Model) applyProbeResults(msg probeResultsMsg) tea.Cmd {
+	maps.Copy(m.uptime, msg.uptime)
+	if msg.uptimeErr != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving uptime history: %v", msg.uptimeErr)
+		m.StatusType = StatusError
+	}
+	reloaded, err := storeProbeResults(msg.results, m.IsEditing || m.IsConfirmingDelete)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving check results: %v", err)
+		m.StatusType = StatusError
+	}
+	byName := map[string]runner.ProbeResult{}
+	for _, res := range msg.results {
+		byName[res.Connection] = res
+	}
+
+	var cmds []tea.Cmd
+	for i, listItem := range m.List.Items() {
+		item, ok := listItem.(Item)
+		if !ok {
+			continue
+		}
+		if res, found := byName[item.Name]; found {
+			record := res.Record()
+			item.LastProbe = &record
+			item.Checking = false
+			cmds = append(cmds, m.List.SetItem(i, item))
+		}
+	}
+	for i := range m.hidden {
+		if res, found := byName[m.hidden[i].Name]; found {
+			record := res.Record()
+			m.hidden[i].LastProbe = &record
+			m.hidden[i].Checking = false
+		}
+	}
+	if !msg.round && len(msg.results) > 1 {
+		online := 0
+		for _, res := range msg.results {
+			if res.Status == config.ProbeOnline {
+				online++
+			}
+		}
+		m.StatusMessage = fmt.Sprintf("Checked %d connection(s): %d online.", len(msg.results), online)
+		m.StatusType = StatusSuccess
+	}
+	if !msg.round && len(msg.results) == 1 {
+		res := msg.results[0]
+		m.StatusMessage = fmt.Sprintf("'%s' is %s.", res.Connection, res.Status)
+		m.StatusType = StatusSuccess
+		if res.Status != config.ProbeOnline {
+			m.StatusType = StatusError
+		}
+	}
+	switch {
+	case reloaded && !m.inOverlay():
+		*m = m.reloaded()
+	case reloaded || m.sort.by == sortStatus:
+		cmds = append(cmds, m.refreshItems())
+	case m.tagPanel != nil:
+		// Collections may select by status.
+		m.tagPanel.refresh()
+	}
+	if msg.round {
+		cmds = append(cmds, scheduleProbes(m.probeInterval))
+	}
+	return tea.Batch(cmds...)
+}
+
+// probeGlyphs tell check results apart when the theme has no colors for
+// them.
+var probeGlyphs = map[config.ProbeStatus]string{
+	config.ProbeOnline:           "●",
+	config.ProbeNoListener:       "✕",
+	config.ProbeRelayUnreachable: "◇",
+	config.ProbeTimeout:          "◔",
+	config.ProbeError:            "!",
+}
+
+// statusDot renders the reachability of item as a colored dot.
+func statusDot(item Item) string {
+	switch {
+	case item.LastProbe == nil && item.Checking:
+		return styles.unknown.Render("◌")
+	case item.LastProbe == nil:
+		return styles.unknown.Render("○")
+	}
+	style, ok := styles.probe[item.LastProbe.Status]
+	if !ok {
+		style = styles.unknown
+	}
+	glyph := "●"
+	if styles.plainStatus {
+		if g, ok := probeGlyphs[item.LastProbe.Status]; ok {
+			glyph = g
+		}
+	}
+	return style.Render(glyph)
+}
+
+// itemDelegate draws the default list item with the selection mark and a
+// status dot in front.
+type itemDelegate struct {
+	list.DefaultDelegate
+}
+
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	item, ok := listItem.(Item)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, listItem)
+		return
+	}
+	// Leave room for the marks so titles are truncated instead of wrapped.
+	m.SetWidth(m.Width() - 3)
+	var b strings.Builder
+	d.DefaultDelegate.Render(&b, m, index, listItem)
+	lines := strings.Split(b.String(), "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = selectionMark(item) + statusDot(item) + " " + lines[i]
+		} else {
+			lines[i] = "   " + lines[i]
+		}
+	}
+	fmt.Fprint(w, strings.Join(lines, "\n"))
+}
+
+// selectionMark renders whether item is selected for bulk actions.
+func selectionMark(item Item) string {
+	if !item.Selected {
+		return " "
+	}
+	return styles.warning.Bold(true).Render("✓")
+}
+
+// renderProbeDetails renders the last check of item for the detail panel.
+func renderProbeDetails(item Item, keyStyle, valueStyle lipgloss.Style) string {
+	var s strings.Builder
+	status := "Not checked"
+	if item.LastProbe != nil {
+		status = string(item.LastProbe.Status)
+	}
+	if item.Checking {
+		status += " (checking...)"
+	}
+	s.WriteString(keyStyle.Render("Status: ") + statusDot(item) + " " + valueStyle.Render(status) + "\n")
+	if item.LastProbe == nil {
+		return s.String()
+	}
+	s.WriteString(keyStyle.Render("Last Check: ") + valueStyle.Render(formatTimestamp(item.LastProbe.At)) + "\n")
+	if item.LastProbe.Latency > 0 {
+		latency := time.Duration(item.LastProbe.Latency).Round(time.Millisecond)
+		s.WriteString(keyStyle.Render("Latency: ") + valueStyle.Render(latency.String()) + "\n")
+	}
+	if item.LastProbe.Detail != "" {
+		s.WriteString(keyStyle.Render("Detail: ") + valueStyle.Render(item.LastProbe.Detail) + "\n")
+	}
+	return s.String()
+}
+
+// uptimeRange and uptimeSparkWidth shape the sparkline in the detail panel.
+const (
+	uptimeRange      = 24 * time.Hour
+	uptimeSparkWidth = 24
+)
+
+// uptimeSummary is the cached availability of a connection.
+type uptimeSummary struct {
+	spark  string
+	report uptime.Report
+}
+
+// loadUptime reads the recent history of the connection called name.
+func loadUptime(name string) uptimeSummary {
+	samples, err := uptime.Load(name)
+	if err != nil {
+		return uptimeSummary{}
+	}
+	to := time.Now()
+	from := to.Add(-uptimeRange)
+	return uptimeSummary{
+		spark:  uptime.Sparkline(samples, from, to, uptimeSparkWidth),
+		report: uptime.Analyze(name, samples, from, to),
+	}
+}
+
+// renderUptime renders the availability of a connection for the detail panel.
+func renderUptime(summary uptimeSummary, keyStyle, valueStyle lipgloss.Style) string {
+	if summary.report.Checks == 0 {
+		return ""
+	}
+	availability := fmt.Sprintf("%.1f%%", summary.report.Availability)
+	if summary.report.Flapping {
+		availability += " (flapping)"
+	}
+	return keyStyle.Render("Uptime 24h: ") + valueStyle.Render(availability) + "\n" +
+		styles.online.Render(summary.spark) + "\n"
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+// writeConfigFile changes the config file behind the TUI's back, like
+// another gsm process would.
+func writeConfigFile(t *testing.T, cfg config.Config) {
+	t.Helper()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.DefaultConfigFilePath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func onlineResult(name string) runner.ProbeResult {
+	return runner.ProbeResult{Connection: name, Status: config.ProbeOnline, At: time.Now()}
+}
+
+func itemNamed(m Model, name string) (Item, bool) {
+	for _, listItem := range m.List.Items() {
+		if item, ok := listItem.(Item); ok && item.Name == name {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+func TestApplyProbeResultsKeepsOtherChanges(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"}, config.Connection{Name: "web-2", Key: "k2"})
+	m := NewModel(config.GetCurrent())
+	item, _ := itemNamed(m, "web-2")
+	item.Checking = true
+	m.List.SetItem(1, item)
+
+	changed := config.GetCurrent()
+	changed.Connections = append(changed.Connections, config.Connection{Name: "added-elsewhere", Key: "k3"})
+	writeConfigFile(t, changed)
+
+	m.applyProbeResults(probeResultsMsg{results: []runner.ProbeResult{onlineResult("web-1")}, round: true})
+
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.GetCurrent()
+	if len(cfg.Connections) != 3 {
+		t.Fatalf("saved connections = %+v, want the one added meanwhile kept", cfg.Connections)
+	}
+	if probe := cfg.Connections[0].LastProbe; probe == nil || probe.Status != config.ProbeOnline {
+		t.Errorf("saved probe of web-1 = %+v, want online", probe)
+	}
+	if _, ok := itemNamed(m, "added-elsewhere"); !ok {
+		t.Error("the list was not refreshed from the reloaded config")
+	}
+	if item, _ := itemNamed(m, "web-2"); !item.Checking {
+		t.Error("the running check of web-2 was lost on reload")
+	}
+	if item, _ := itemNamed(m, "web-1"); item.Checking || item.LastProbe == nil {
+		t.Errorf("web-1 = %+v, want its check result shown", item)
+	}
+}
+
+func TestApplyProbeResultsWhileEditing(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"}, config.Connection{Name: "web-2", Key: "k2"})
+	m := NewModel(config.GetCurrent())
+	m.startEdit()
+
+	changed := config.GetCurrent()
+	changed.Connections = changed.Connections[1:]
+	writeConfigFile(t, changed)
+
+	m.applyProbeResults(probeResultsMsg{results: []runner.ProbeResult{onlineResult("web-1")}})
+
+	// The form holds an index into the loaded connections, so they must not
+	// be swapped under it; the form's save writes the result.
+	if !m.IsEditing {
+		t.Fatal("the edit form was closed")
+	}
+	cfg := config.GetCurrent()
+	if len(cfg.Connections) != 2 || cfg.Connections[m.EditingIndex].Name != "web-1" {
+		t.Fatalf("connections = %+v, want them unchanged while editing", cfg.Connections)
+	}
+	if probe := cfg.Connections[0].LastProbe; probe == nil || probe.Status != config.ProbeOnline {
+		t.Errorf("probe of web-1 = %+v, want it applied in memory", probe)
+	}
+}
+
+func TestProbeConnectionsRecordsUptime(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"})
+	saved := ProbeBackend
+	t.Cleanup(func() { ProbeBackend = saved })
+	ProbeBackend = runner.NewFakeBackend(func(spec runner.Spec, _ <-chan os.Signal) int {
+		fmt.Fprintln(spec.Stderr, "ERROR: Connection refused (no server listening)")
+		return 255
+	})
+
+	msg, ok := probeConnections(config.GetCurrent().Connections, 1, true)().(probeResultsMsg)
+	if !ok || len(msg.results) != 1 || msg.results[0].Status != config.ProbeNoListener {
+		t.Fatalf("probe message = %+v, want one no-listener result", msg)
+	}
+	if msg.uptimeErr != nil {
+		t.Fatal(msg.uptimeErr)
+	}
+	if report := msg.uptime["web-1"].report; report.Checks != 1 || report.Availability != 0 {
+		t.Errorf("uptime of web-1 = %+v, want one failed check", report)
+	}
+}
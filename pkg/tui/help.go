@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpColumnWidth is the width of one column of the help overlay.
+const helpColumnWidth = 44
+
+// helpSection is the key bindings of one context of the TUI.
+type helpSection struct {
+	title string
+	rows  [][2]string
+}
+
+// bound returns the rows of the bound actions in the order given.
+func bound(actions ...string) [][2]string {
+	var rows [][2]string
+	for _, action := range actions {
+		if b := keys[action]; b.Enabled() {
+			rows = append(rows, [2]string{b.Help().Key, b.Help().Desc})
+		}
+	}
+	return rows
+}
+
+// helpSections lists the bindings of every context, the configurable ones
+// from the active key map.
+func helpSections() []helpSection {
+	return []helpSection{
+		{"List", bound(actUp, actDown, actPageUp, actPageDown, actHome, actEnd, actConnect, actBackground,
+			actEdit, actAdd, actNew, actDelete, actCheck, actTunnels, actSort, actSortReverse, actFilter, actQuery,
+			actTags, actFocusPanel, actCopy, actPalette, actHelp, actQuit)},
+		{"Selection", append(bound(actSelect, actSelectAll, actInvertSelection, actClearSelection, actAddTags,
+			actRemoveTags, actGroup, actExport),
+			[2]string{keysLabel(keys[actDelete].Keys()), "delete selected"},
+			[2]string{keysLabel(keys[actCheck].Keys()), "check selected"},
+			[2]string{keysLabel(keys[actBackground].Keys()), "background sessions"})},
+		{"Tag panel", bound(actUp, actDown, actPanelPick, actPanelMode, actPanelClear, actPanelRename,
+			actPanelMerge, actPanelEdit, actFocusPanel, actPanelClose)},
+		{"Tunnels", bound(actUp, actDown, actTunnelToggle, actTunnelClose)},
+		{"Filter", [][2]string{
+			{"type", "fuzzy filter"},
+			{queryPrefix + " first", "switch to a query"},
+			{"enter", "connect"},
+			{"esc", "clear"},
+		}},
+		{"Query bar", [][2]string{
+			{"type", "filter as you type"},
+			{"enter", "keep the query"},
+			{"esc", "clear the query"},
+			{"ctrl+s", "save as collection"},
+			{"ctrl+x", "delete its collection"},
+		}},
+		{"Form", bound(actFormNext, actFormPrev, actFormSave, actFormGenerate, actFormCancel)},
+		{"Copy menu", copyMenuRows()},
+		{"Confirm", bound(actConfirmYes, actConfirmNo)},
+		{"Bulk prompt", [][2]string{
+			{"enter", "apply"},
+			{"esc", "cancel"},
+		}},
+		{"Command palette", [][2]string{
+			{"type", "search commands"},
+			{"↑/↓", "choose"},
+			{"enter", "run"},
+			{"esc", "close"},
+		}},
+		{"Anywhere", [][2]string{
+			{forceQuitKey, "quit"},
+		}},
+	}
+}
+
+// copyMenuRows lists the keys of the copy menu.
+func copyMenuRows() [][2]string {
+	var actions []string
+	for _, target := range copyTargets {
+		actions = append(actions, target.action)
+	}
+	return append(bound(actions...), [2]string{"esc", "cancel"})
+}
+
+// renderHelpSection renders s as a block of helpColumnWidth.
+func renderHelpSection(s helpSection) string {
+	keyWidth := 0
+	for _, row := range s.rows {
+		keyWidth = max(keyWidth, lipgloss.Width(row[0]))
+	}
+	keyWidth = min(keyWidth, helpColumnWidth/2)
+	lines := []string{lipgloss.NewStyle().Bold(true).Render(s.title)}
+	for _, row := range s.rows {
+		k := row[0]
+		if lipgloss.Width(k) > keyWidth {
+			k = string([]rune(k)[:keyWidth-1]) + "…"
+		}
+		pad := strings.Repeat(" ", keyWidth-lipgloss.Width(k))
+		lines = append(lines, "  "+styles.text.Bold(true).Render(k)+pad+"  "+styles.muted.Render(row[1]))
+	}
+	return lipgloss.NewStyle().Width(helpColumnWidth).Render(strings.Join(lines, "\n"))
+}
+
+// helpLines lays the sections out in as many columns as fit into width and
+// returns the lines.
+func helpLines(width int) []string {
+	columns := max(width/(helpColumnWidth+2), 1)
+	blocks := make([][]string, columns)
+	heights := make([]int, columns)
+	for _, s := range helpSections() {
+		if len(s.rows) == 0 {
+			continue
+		}
+		// Put each section into the shortest column.
+		shortest := 0
+		for i := range heights {
+			if heights[i] < heights[shortest] {
+				shortest = i
+			}
+		}
+		block := renderHelpSection(s)
+		blocks[shortest] = append(blocks[shortest], block)
+		heights[shortest] += lipgloss.Height(block) + 1
+	}
+	var rendered []string
+	for _, column := range blocks {
+		rendered = append(rendered, strings.Join(column, "\n\n"), "  ")
+	}
+	return strings.Split(lipgloss.JoinHorizontal(lipgloss.Top, rendered...), "\n")
+}
+
+// openHelp shows the help overlay.
+func (m *Model) openHelp() tea.Cmd {
+	m.help = &helpOverlay{}
+	return tea.ClearScreen
+}
+
+// helpOverlay is the full-screen list of key bindings.
+type helpOverlay struct {
+	offset int
+}
+
+// helpBodyHeight is the number of help lines that fit on the screen.
+func (m Model) helpBodyHeight() int {
+	return max(m.lastKnownHeight-4, 1)
+}
+
+// updateHelpKey scrolls or closes the help overlay.
+func (m Model) updateHelpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	h := m.help
+	maxOffset := max(len(helpLines(m.lastKnownWidth))-m.helpBodyHeight(), 0)
+	switch {
+	case msg.String() == forceQuitKey:
+		return m, tea.Quit
+	case keys.is(msg, actHelp), msg.String() == "esc", msg.String() == "q":
+		m.help = nil
+		return m, tea.ClearScreen
+	case keys.is(msg, actUp):
+		h.offset = max(h.offset-1, 0)
+	case keys.is(msg, actDown):
+		h.offset = min(h.offset+1, maxOffset)
+	case keys.is(msg, actPageUp):
+		h.offset = max(h.offset-m.helpBodyHeight(), 0)
+	case keys.is(msg, actPageDown), msg.String() == " ":
+		h.offset = min(h.offset+m.helpBodyHeight(), maxOffset)
+	case keys.is(msg, actHome):
+		h.offset = 0
+	case keys.is(msg, actEnd):
+		h.offset = maxOffset
+	}
+	return m, nil
+}
+
+// viewHelp renders the help overlay.
+func (m Model) viewHelp() string {
+	lines := helpLines(m.lastKnownWidth)
+	height := m.helpBodyHeight()
+	offset := min(m.help.offset, max(len(lines)-height, 0))
+	body := lines[offset:min(offset+height, len(lines))]
+
+	footer := keys.footer("scroll=up,down", "close="+actHelp) + " • esc close"
+	if len(lines) > height {
+		footer += fmt.Sprintf(" • %d/%d", offset+len(body), len(lines))
+	}
+	return styles.title.Padding(0, 1).Render("GSM | Key Bindings") + "\n\n" +
+		strings.Join(body, "\n") + "\n" +
+		styles.bar.Padding(0, 1).Render(footer)
+}
@@ -0,0 +1,391 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Scopes of key bindings: the main list, the tag panel, the tunnels view, the
+// copy menu, the delete confirmation and the edit form. Keys only conflict
+// within a scope.
+const (
+	scopeList = 1 << iota
+	scopePanel
+	scopeTunnels
+	scopeCopy
+	scopeConfirm
+	scopeForm
+)
+
+var scopeNames = map[int]string{
+	scopeList:    "list",
+	scopePanel:   "panel",
+	scopeTunnels: "tunnels",
+	scopeCopy:    "copy menu",
+	scopeConfirm: "confirmation",
+	scopeForm:    "form",
+}
+
+// Actions that can be bound to keys. The names are used in the config.
+const (
+	actUp              = "up"
+	actDown            = "down"
+	actPageUp          = "page_up"
+	actPageDown        = "page_down"
+	actHome            = "home"
+	actEnd             = "end"
+	actFilter          = "filter"
+	actConnect         = "connect"
+	actBackground      = "background"
+	actEdit            = "edit"
+	actDelete          = "delete"
+	actAdd             = "add"
+	actNew             = "new"
+	actCheck           = "check"
+	actTunnels         = "tunnels"
+	actSort            = "sort"
+	actSortReverse     = "sort_reverse"
+	actTags            = "tags"
+	actQuery           = "query"
+	actFocusPanel      = "focus_panel"
+	actQuit            = "quit"
+	actSelect          = "select"
+	actSelectAll       = "select_all"
+	actInvertSelection = "invert_selection"
+	actClearSelection  = "clear_selection"
+	actAddTags         = "add_tags"
+	actRemoveTags      = "remove_tags"
+	actGroup           = "group"
+	actExport          = "export"
+	actPanelPick       = "panel_pick"
+	actPanelMode       = "panel_mode"
+	actPanelClear      = "panel_clear"
+	actPanelRename     = "panel_rename"
+	actPanelMerge      = "panel_merge"
+	actPanelEdit       = "panel_edit"
+	actPanelClose      = "panel_close"
+	actTunnelToggle    = "tunnel_toggle"
+	actTunnelClose     = "tunnel_close"
+	actHelp            = "help"
+	actPalette         = "palette"
+	actCopy            = "copy"
+	actCopyKey         = "copy_key"
+	actCopyName        = "copy_name"
+	actCopyClient      = "copy_client"
+	actCopyListener    = "copy_listener"
+	actConfirmYes      = "confirm_yes"
+	actConfirmNo       = "confirm_no"
+	actFormNext        = "form_next"
+	actFormPrev        = "form_prev"
+	actFormSave        = "form_save"
+	actFormGenerate    = "form_generate"
+	actFormCancel      = "form_cancel"
+)
+
+// forceQuitKey always quits, or cancels the confirmation and the form, and
+// can't be bound to an action.
+const forceQuitKey = "ctrl+c"
+
+// keyAction is a bindable action with its default keys.
+type keyAction struct {
+	name   string
+	scopes int
+	desc   string
+	keys   []string
+}
+
+// keyActions lists every bindable action in the order of the help.
+var keyActions = []keyAction{
+	{actUp, scopeList | scopePanel | scopeTunnels, "up", []string{"up", "k"}},
+	{actDown, scopeList | scopePanel | scopeTunnels, "down", []string{"down", "j"}},
+	{actPageUp, scopeList, "page up", []string{"pgup", "left", "h", "u"}},
+	{actPageDown, scopeList, "page down", []string{"pgdown", "right", "l", "f"}},
+	{actHome, scopeList, "first", []string{"home", "g"}},
+	{actEnd, scopeList, "last", []string{"end", "G"}},
+	{actFilter, scopeList, "filter", []string{"/"}},
+	{actQuery, scopeList, "query", []string{":"}},
+	{actConnect, scopeList, "connect", []string{"enter"}},
+	{actBackground, scopeList, "background", []string{"b"}},
+	{actEdit, scopeList, "edit", []string{"e"}},
+	{actDelete, scopeList, "delete", []string{"d"}},
+	{actAdd, scopeList, "add", []string{"a"}},
+	{actNew, scopeList, "new", []string{"n"}},
+	{actCheck, scopeList, "check", []string{"c"}},
+	{actTunnels, scopeList, "tunnels", []string{"t"}},
+	{actSort, scopeList, "sort", []string{"s"}},
+	{actSortReverse, scopeList, "reverse sort", []string{"S"}},
+	{actTags, scopeList, "tags", []string{"T"}},
+	{actFocusPanel, scopeList | scopePanel, "switch panel", []string{"tab"}},
+	{actQuit, scopeList, "quit", []string{"q"}},
+	{actSelect, scopeList, "select", []string{" "}},
+	{actSelectAll, scopeList, "select all", []string{"A"}},
+	{actInvertSelection, scopeList, "invert", []string{"I"}},
+	{actClearSelection, scopeList, "clear selection", []string{"esc"}},
+	{actAddTags, scopeList, "add tags", []string{"+"}},
+	{actRemoveTags, scopeList, "remove tags", []string{"-"}},
+	{actGroup, scopeList, "group", []string{"m"}},
+	{actExport, scopeList, "export", []string{"x"}},
+	{actCopy, scopeList, "copy", []string{"y"}},
+	{actPanelPick, scopePanel, "pick", []string{" ", "enter"}},
+	{actPanelMode, scopePanel, "and/or", []string{"o"}},
+	{actPanelClear, scopePanel, "clear", []string{"c"}},
+	{actPanelRename, scopePanel, "rename", []string{"r"}},
+	{actPanelMerge, scopePanel, "merge", []string{"m"}},
+	{actPanelEdit, scopePanel, "edit collection", []string{"e"}},
+	{actPanelClose, scopePanel, "close", []string{"esc", "T"}},
+	{actTunnelToggle, scopeTunnels, "start/stop", []string{"enter", " "}},
+	{actTunnelClose, scopeTunnels, "back", []string{"esc", "q", "t"}},
+	{actHelp, scopeList | scopePanel | scopeTunnels, "help", []string{"?"}},
+	{actPalette, scopeList, "commands", []string{"ctrl+p"}},
+	{actCopyKey, scopeCopy, "copy key", []string{"k"}},
+	{actCopyName, scopeCopy, "copy name", []string{"n"}},
+	{actCopyClient, scopeCopy, "copy client command", []string{"c"}},
+	{actCopyListener, scopeCopy, "copy listener command", []string{"l"}},
+	{actConfirmYes, scopeConfirm, "delete", []string{"y", "Y"}},
+	{actConfirmNo, scopeConfirm, "cancel", []string{"n", "N", "esc"}},
+	{actFormNext, scopeForm, "next field", []string{"tab"}},
+	{actFormPrev, scopeForm, "previous field", []string{"shift+tab"}},
+	{actFormSave, scopeForm, "save", []string{"enter"}},
+	{actFormGenerate, scopeForm, "generate key", []string{"ctrl+g"}},
+	{actFormCancel, scopeForm, "cancel", []string{"esc"}},
+}
+
+// keyPresets change the defaults of some actions. Overrides from the config
+// are applied on top.
+var keyPresets = map[string]map[string][]string{
+	"default": {},
+	"vim": {
+		actPageUp:     {"ctrl+u", "ctrl+b", "pgup"},
+		actPageDown:   {"ctrl+d", "ctrl+f", "pgdown"},
+		actSelect:     {" ", "v"},
+		actFocusPanel: {"tab", "ctrl+w"},
+	},
+	"emacs": {
+		actUp:             {"up", "ctrl+p"},
+		actDown:           {"down", "ctrl+n"},
+		actPageUp:         {"pgup", "alt+v"},
+		actPageDown:       {"pgdown", "ctrl+v"},
+		actHome:           {"home", "alt+<"},
+		actEnd:            {"end", "alt+>"},
+		actFilter:         {"/", "ctrl+s"},
+		actClearSelection: {"esc", "ctrl+g"},
+		actPanelClose:     {"esc", "T", "ctrl+g"},
+		actTunnelClose:    {"esc", "q", "t", "ctrl+g"},
+		actPalette:        {"alt+x"},
+		actCopy:           {"y", "alt+w"},
+		actConfirmNo:      {"n", "N", "esc", "ctrl+g"},
+	},
+}
+
+// keyMap holds the active bindings by action name.
+type keyMap map[string]key.Binding
+
+// keys is the active key map.
+var keys = mustKeyMap("default", nil)
+
+func mustKeyMap(preset string, overrides map[string][]string) keyMap {
+	km, err := newKeyMap(preset, overrides)
+	if err != nil {
+		panic(err)
+	}
+	return km
+}
+
+// SetKeymap switches the TUI to the key preset (default, vim or emacs) with
+// overrides, a list of keys by action name, applied on top. An empty list
+// unbinds an action. It fails on unknown names and on keys bound to two
+// actions that are active at the same time. It must be called before
+// NewModel.
+func SetKeymap(preset string, overrides map[string][]string) error {
+	km, err := newKeyMap(preset, overrides)
+	if err != nil {
+		return err
+	}
+	keys = km
+	return nil
+}
+
+// KeymapPresets returns the names of the key presets, sorted.
+func KeymapPresets() []string {
+	names := make([]string, 0, len(keyPresets))
+	for name := range keyPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newKeyMap(preset string, overrides map[string][]string) (keyMap, error) {
+	if preset == "" {
+		preset = "default"
+	}
+	presetKeys, ok := keyPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown key preset '%s' (available: %s)", preset, strings.Join(KeymapPresets(), ", "))
+	}
+	for name := range overrides {
+		if !slices.ContainsFunc(keyActions, func(a keyAction) bool { return a.name == name }) {
+			return nil, fmt.Errorf("unknown key action '%s'", name)
+		}
+	}
+
+	km := keyMap{}
+	bound := map[int]map[string]string{}
+	for _, a := range keyActions {
+		keyNames := a.keys
+		if k, ok := presetKeys[a.name]; ok {
+			keyNames = k
+		}
+		if k, ok := overrides[a.name]; ok {
+			keyNames = k
+		}
+		var normalized []string
+		for _, k := range keyNames {
+			if k = normalizeKey(k); k == "" {
+				return nil, fmt.Errorf("key action '%s' has an empty key", a.name)
+			}
+			if k == forceQuitKey {
+				return nil, fmt.Errorf("key %s is reserved for quitting and can't be bound to '%s'", forceQuitKey, a.name)
+			}
+			for scope, scopeName := range scopeNames {
+				if a.scopes&scope == 0 {
+					continue
+				}
+				if bound[scope] == nil {
+					bound[scope] = map[string]string{}
+				}
+				if other, taken := bound[scope][k]; taken && other != a.name {
+					return nil, fmt.Errorf("key '%s' is bound to both '%s' and '%s' in the %s", keyLabel(k), other, a.name, scopeName)
+				}
+				bound[scope][k] = a.name
+			}
+			normalized = append(normalized, k)
+		}
+		b := key.NewBinding(key.WithKeys(normalized...), key.WithHelp(keysLabel(normalized), a.desc))
+		if len(normalized) == 0 {
+			b.SetEnabled(false)
+		}
+		km[a.name] = b
+	}
+	return km, nil
+}
+
+// normalizeKey turns the key names accepted in the config into the names
+// bubbletea reports.
+func normalizeKey(k string) string {
+	if k != " " {
+		k = strings.TrimSpace(k)
+	}
+	switch strings.ToLower(k) {
+	case "space":
+		return " "
+	case "return":
+		return "enter"
+	case "escape":
+		return "esc"
+	}
+	return k
+}
+
+// keyLabel returns the name of k shown in the footer and help.
+func keyLabel(k string) string {
+	switch k {
+	case " ":
+		return "space"
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	return k
+}
+
+func keysLabel(keyNames []string) string {
+	labels := make([]string, len(keyNames))
+	for i, k := range keyNames {
+		labels[i] = keyLabel(k)
+	}
+	return strings.Join(labels, "/")
+}
+
+// is reports whether msg is bound to action.
+func (k keyMap) is(msg tea.KeyMsg, action string) bool {
+	return key.Matches(msg, k[action])
+}
+
+// action returns the action msg is bound to in scope.
+func (k keyMap) action(msg tea.KeyMsg, scope int) (string, bool) {
+	for _, a := range keyActions {
+		if a.scopes&scope != 0 && k.is(msg, a.name) {
+			return a.name, true
+		}
+	}
+	return "", false
+}
+
+// first returns the label of the first key bound to action.
+func (k keyMap) first(action string) string {
+	if b := k[action]; b.Enabled() {
+		return keyLabel(b.Keys()[0])
+	}
+	return ""
+}
+
+// footer renders a one line help from entries, each an action or a
+// "desc=action,action" group shown with the first key of every action, e.g.
+// "nav=up,down" for "↑/↓ nav". Unbound actions are left out.
+func (k keyMap) footer(entries ...string) string {
+	var parts []string
+	for _, entry := range entries {
+		desc, actions, grouped := strings.Cut(entry, "=")
+		if !grouped {
+			actions, desc = entry, k[entry].Help().Desc
+		}
+		var labels []string
+		for _, action := range strings.Split(actions, ",") {
+			if label := k.first(action); label != "" {
+				labels = append(labels, label)
+			}
+		}
+		if len(labels) > 0 {
+			parts = append(parts, strings.Join(labels, "/")+" "+desc)
+		}
+	}
+	return strings.Join(parts, " • ")
+}
+
+// KeyInfo describes one bindable action for listings.
+type KeyInfo struct {
+	Action string
+	Scope  string
+	Desc   string
+	Keys   []string
+}
+
+// Keys returns the actions of the active key map with their keys.
+func Keys() []KeyInfo {
+	var infos []KeyInfo
+	for _, a := range keyActions {
+		var scopes []string
+		for _, scope := range []int{scopeList, scopePanel, scopeTunnels, scopeCopy, scopeConfirm, scopeForm} {
+			if a.scopes&scope != 0 {
+				scopes = append(scopes, scopeNames[scope])
+			}
+		}
+		var labels []string
+		for _, k := range keys[a.name].Keys() {
+			labels = append(labels, keyLabel(k))
+		}
+		infos = append(infos, KeyInfo{Action: a.name, Scope: strings.Join(scopes, ","), Desc: a.desc, Keys: labels})
+	}
+	return infos
+}
@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// useKeymap makes preset with overrides the active key map for the test.
+func useKeymap(t *testing.T, preset string, overrides map[string][]string) {
+	t.Helper()
+	saved := keys
+	t.Cleanup(func() { keys = saved })
+	if err := SetKeymap(preset, overrides); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runeKey(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestNewKeyMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		preset    string
+		overrides map[string][]string
+		action    string
+		want      []string
+	}{
+		{"default", "", nil, actPageDown, []string{"pgdown", "right", "l", "f"}},
+		{"default confirmation", "default", nil, actConfirmYes, []string{"y", "Y"}},
+		{"default form", "default", nil, actFormPrev, []string{"shift+tab"}},
+		{"vim preset", "vim", nil, actPageDown, []string{"ctrl+d", "ctrl+f", "pgdown"}},
+		{"vim keeps other defaults", "vim", nil, actQuit, []string{"q"}},
+		{"emacs preset", "emacs", nil, actConfirmNo, []string{"n", "N", "esc", "ctrl+g"}},
+		{"override", "default", map[string][]string{actFormSave: {"ctrl+s"}}, actFormSave, []string{"ctrl+s"}},
+		{"override beats preset", "vim", map[string][]string{actPageDown: {"J"}}, actPageDown, []string{"J"}},
+		{"override is normalized", "default", map[string][]string{actTunnelToggle: {"Space", " return "}}, actTunnelToggle, []string{" ", "enter"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km, err := newKeyMap(tt.preset, tt.overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := km[tt.action].Keys(); !slices.Equal(got, tt.want) {
+				t.Errorf("keys of %s = %q, want %q", tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeyMapUnbind(t *testing.T) {
+	km, err := newKeyMap("default", map[string][]string{actQuit: {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if km[actQuit].Enabled() {
+		t.Error("quit is still bound after overriding it with no keys")
+	}
+	if got := km.footer(actQuit, actFilter); got != "/ filter" {
+		t.Errorf("footer = %q, want the unbound action left out", got)
+	}
+}
+
+func TestNewKeyMapErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		preset    string
+		overrides map[string][]string
+		want      string
+	}{
+		{"unknown preset", "nano", nil, "unknown key preset 'nano'"},
+		{"unknown action", "default", map[string][]string{"teleport": {"z"}}, "unknown key action 'teleport'"},
+		{"empty key", "default", map[string][]string{actQuit: {"  "}}, "empty key"},
+		{"reserved quit key", "default", map[string][]string{actQuit: {"ctrl+c"}}, "reserved"},
+		{"reserved in the form", "default", map[string][]string{actFormCancel: {"ctrl+c"}}, "reserved"},
+		{"list conflict", "default", map[string][]string{actSort: {"q"}}, "key 'q' is bound to both 'sort' and 'quit' in the list"},
+		{"confirmation conflict", "default", map[string][]string{actConfirmYes: {"esc"}}, "in the confirmation"},
+		{"form conflict", "default", map[string][]string{actFormSave: {"tab"}}, "in the form"},
+		{"conflict on top of a preset", "emacs", map[string][]string{actFormGenerate: {"ctrl+x"}, actFormCancel: {"ctrl+x"}}, "in the form"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newKeyMap(tt.preset, tt.overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("newKeyMap = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysConflictOnlyWithinScope(t *testing.T) {
+	// y copies in the list and confirms a delete; enter connects and saves
+	// the form.
+	km, err := newKeyMap("default", map[string][]string{actFormCancel: {"q"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		msg    tea.KeyMsg
+		scope  int
+		action string
+	}{
+		{runeKey("y"), scopeList, actCopy},
+		{runeKey("y"), scopeConfirm, actConfirmYes},
+		{runeKey("Y"), scopeConfirm, actConfirmYes},
+		{tea.KeyMsg{Type: tea.KeyEnter}, scopeList, actConnect},
+		{tea.KeyMsg{Type: tea.KeyEnter}, scopeForm, actFormSave},
+		{tea.KeyMsg{Type: tea.KeyShiftTab}, scopeForm, actFormPrev},
+		{runeKey("q"), scopeList, actQuit},
+		{runeKey("q"), scopeForm, actFormCancel},
+	} {
+		if got, _ := km.action(tt.msg, tt.scope); got != tt.action {
+			t.Errorf("action(%q, %s) = %q, want %q", tt.msg.String(), scopeNames[tt.scope], got, tt.action)
+		}
+	}
+	if got, ok := km.action(tea.KeyMsg{Type: tea.KeyEsc}, scopeForm); ok {
+		t.Errorf("esc still bound to %q in the form after rebinding cancel", got)
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	for in, want := range map[string]string{
+		" ":         " ",
+		"space":     " ",
+		"SPACE":     " ",
+		" return ":  "enter",
+		"Escape":    "esc",
+		"ctrl+a":    "ctrl+a",
+		" G ":       "G",
+		"shift+tab": "shift+tab",
+		"   ":       "",
+	} {
+		if got := normalizeKey(in); got != want {
+			t.Errorf("normalizeKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestConfirmDeleteUsesKeymap(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"}, config.Connection{Name: "web-2", Key: "k2"})
+	useKeymap(t, "default", map[string][]string{actConfirmYes: {"D"}})
+	m := NewModel(config.GetCurrent())
+	m.IsConfirmingDelete = true
+	m.DeleteIndex = 0
+	m.DeleteConnectionName = "web-1"
+
+	next, _ := m.Update(runeKey("y"))
+	m = next.(Model)
+	if !m.IsConfirmingDelete || len(config.GetCurrent().Connections) != 2 {
+		t.Fatal("y deleted after rebinding confirm_yes")
+	}
+	next, _ = m.Update(runeKey("D"))
+	m = next.(Model)
+	if m.IsConfirmingDelete {
+		t.Fatal("the rebound key did not confirm")
+	}
+	if conns := config.GetCurrent().Connections; len(conns) != 1 || conns[0].Name != "web-2" {
+		t.Errorf("connections = %+v, want web-1 deleted", conns)
+	}
+}
+
+func TestEditFormUsesKeymap(t *testing.T) {
+	useConfig(t, config.Connection{Name: "web-1", Key: "k1"})
+	useKeymap(t, "default", map[string][]string{actFormCancel: {"ctrl+q"}, actFormNext: {"ctrl+n"}})
+	m := NewModel(config.GetCurrent())
+	m.startEdit()
+
+	focus := m.EditFocusIndex
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = next.(Model)
+	if m.EditFocusIndex != focus {
+		t.Error("tab moved the focus after rebinding form_next")
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = next.(Model)
+	if m.EditFocusIndex == focus {
+		t.Error("the rebound key did not move the focus")
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(Model)
+	if !m.IsEditing {
+		t.Fatal("esc cancelled the form after rebinding form_cancel")
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlQ})
+	m = next.(Model)
+	if m.IsEditing {
+		t.Fatal("the rebound key did not cancel the form")
+	}
+
+	// ctrl+c cancels whatever the key map says.
+	m.startEdit()
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if next.(Model).IsEditing {
+		t.Error("ctrl+c did not cancel the form")
+	}
+}
@@ -2,12 +2,16 @@ package tui
 
 import (
 	"fmt"
+	"maps"
 	"strings"
 	"time"
 
 	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/query"
+	"github.com/NumeXx/gsm/pkg/sessiond"
 	"github.com/NumeXx/gsm/pkg/utils"
 	"github.com/NumeXx/gsm/pkg/wordlist"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -15,27 +19,59 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var ChosenConnectionGlobal *Item
+// liveRefreshInterval is how often live background sessions are polled.
+const liveRefreshInterval = 3 * time.Second
+
+type liveSessionsMsg map[string]int
+
+func fetchLiveSessions() tea.Msg {
+	return liveSessionsMsg(sessiond.LiveCounts())
+}
+
+func scheduleLiveSessions() tea.Cmd {
+	return tea.Tick(liveRefreshInterval, func(time.Time) tea.Msg { return fetchLiveSessions() })
+}
 
 type Item struct {
 	config.Connection
+	LiveSessions int
+	// Checking is set while a liveness check of the connection is running.
+	Checking bool
+	// Selected marks the connection for bulk actions.
+	Selected bool
 }
 
-func (i Item) Title() string { return i.Name }
+func (i Item) Title() string {
+	switch {
+	case i.LiveSessions == 1:
+		return i.Name + " [live]"
+	case i.LiveSessions > 1:
+		return fmt.Sprintf("%s [%d live]", i.Name, i.LiveSessions)
+	}
+	return i.Name
+}
 
 func (i Item) Description() string {
-	if len(i.Tags) == 0 {
-		return ""
+	var parts []string
+	if i.Group != "" {
+		parts = append(parts, "["+i.Group+"]")
+	}
+	if len(i.Tags) > 0 {
+		parts = append(parts, "# "+strings.Join(i.Tags, ", "))
 	}
-	return "# " + strings.Join(i.Tags, ", ")
+	return strings.Join(parts, " ")
 }
 
-func (i Item) FilterValue() string { return i.Name + " " + strings.Join(i.Tags, " ") }
+func (i Item) FilterValue() string {
+	return strings.TrimSpace(i.Name + " " + i.Group + " " + strings.Join(i.Tags, " "))
+}
 
 const (
 	focusEditName = iota
 	focusEditKey
 	focusEditTags
+	// focusEditGenerate is the generate-key button, only shown when adding.
+	focusEditGenerate
 )
 
 const (
@@ -66,6 +102,32 @@ type Model struct {
 	DeleteIndex          int
 	DeleteConnectionName string
 	detailViewport       viewport.Model
+	probeInterval        time.Duration
+	probeParallel        int
+	uptime               map[string]uptimeSummary
+	tunnels              *tunnelsView
+	tunnelPolling        bool
+	sort                 sortOrder
+	bulk                 *bulkPrompt
+	bulkDelete           []string
+	// hidden are the connections the tag filter leaves out of the list.
+	hidden    []Item
+	tagFilter tagFilter
+	tagPanel  *tagPanel
+	// query limits the list further; queryInput is set while it is edited.
+	query      *query.Query
+	queryInput *textinput.Model
+	// collection is the saved collection the query was taken from;
+	// collectionInput asks for its name while saving.
+	collection      string
+	collectionInput *textinput.Model
+	help            *helpOverlay
+	palette         *palette
+	copyMenu        bool
+	// clipboardID counts copies; clipboardSecret is the copy of a secret
+	// that is still due to be cleared, 0 for none.
+	clipboardID     int
+	clipboardSecret int
 }
 
 func NewModel(cfg config.Config) Model {
@@ -73,63 +135,73 @@ func NewModel(cfg config.Config) Model {
 	for _, c := range cfg.Connections {
 		items = append(items, Item{Connection: c})
 	}
+	order := sortOrder{by: cfg.Settings.SortBy, desc: cfg.Settings.SortDesc}
+	sortItems(items, order)
 
 	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
 	l.SetFilteringEnabled(true)
 	l.FilterInput.Placeholder = "Filter by name or tag... (type to search)"
-	l.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	l.FilterInput.PromptStyle = styles.hint
+	l.FilterInput.PlaceholderStyle = styles.hint
 	l.FilterInput.TextStyle = lipgloss.NewStyle()
 
-	titleStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color("23")).
-		Foreground(lipgloss.Color("231")).
-		Padding(0, 1).
-		Bold(true)
-	l.Title = "GSM | GSocket Manager"
-	l.Styles.Title = titleStyle
+	l.Styles.Title = styles.title.Padding(0, 1)
 
 	delegate := list.NewDefaultDelegate()
-	delegate.Styles.NormalTitle = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).MaxHeight(1)
-	delegate.Styles.NormalDesc = lipgloss.NewStyle().Foreground(lipgloss.Color("245")).MaxHeight(1)
-	delegate.Styles.SelectedTitle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("231")).
-		Background(lipgloss.Color("32")).
-		Padding(0, 1).
-		Bold(true)
-	delegate.Styles.SelectedDesc = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("228")).
-		Background(lipgloss.Color("32")).
-		Padding(0, 1).MaxHeight(1)
+	delegate.Styles.NormalTitle = styles.text.MaxHeight(1)
+	delegate.Styles.NormalDesc = styles.muted.MaxHeight(1)
+	delegate.Styles.SelectedTitle = styles.selected.Padding(0, 1)
+	delegate.Styles.SelectedDesc = styles.selectedDesc.Padding(0, 1).MaxHeight(1)
+	delegate.Styles.DimmedTitle = styles.hint.Padding(0, 0, 0, 2)
+	delegate.Styles.DimmedDesc = styles.hint.Padding(0, 0, 0, 2)
 	delegate.SetHeight(2)
-	l.SetDelegate(delegate)
+	l.SetDelegate(itemDelegate{delegate})
 
 	l.SetShowStatusBar(true)
 	l.SetStatusBarItemName("connection", "connections")
-	l.Styles.StatusBar = lipgloss.NewStyle().Padding(0, 1).Background(lipgloss.Color("235")).Foreground(lipgloss.Color("242"))
-	l.Styles.FilterPrompt = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	l.Styles.FilterCursor = lipgloss.NewStyle().Foreground(lipgloss.Color("202"))
+	l.Styles.StatusBar = styles.bar.Padding(0, 1)
+	l.Styles.FilterPrompt = styles.hint
+	l.Styles.FilterCursor = styles.cursor
+	l.Styles.StatusBarActiveFilter = styles.text
+	l.Styles.StatusBarFilterCount = styles.hint
+	l.Styles.StatusEmpty = styles.subtle
+	l.Styles.NoItems = styles.subtle
+	l.Styles.ArabicPagination = styles.subtle
+	l.Styles.DividerDot = styles.hint.SetString(" • ")
+	l.Styles.ActivePaginationDot = styles.text.SetString("•")
+	l.Styles.InactivePaginationDot = styles.hint.SetString("•")
 
 	l.SetShowHelp(false)
-
-	ni := textinput.New()
+	l.KeyMap.CursorUp = keys[actUp]
+	l.KeyMap.CursorDown = keys[actDown]
+	l.KeyMap.PrevPage = keys[actPageUp]
+	l.KeyMap.NextPage = keys[actPageDown]
+	l.KeyMap.GoToStart = keys[actHome]
+	l.KeyMap.GoToEnd = keys[actEnd]
+	l.KeyMap.Filter = keys[actFilter]
+	// Esc still quits when there is nothing to clear; the quit action is
+	// handled in Update.
+	l.KeyMap.Quit = key.NewBinding(key.WithKeys("esc"))
+	l.KeyMap.ShowFullHelp.SetEnabled(false)
+	l.KeyMap.CloseFullHelp.SetEnabled(false)
+
+	ni := newInput()
 	ni.Placeholder = "Name (optional, auto-gen from Key)"
 	ni.CharLimit = 100
 	ni.Width = 50
 
-	ki := textinput.New()
+	ki := newInput()
 	ki.Placeholder = "GSocket Key (required)"
 	ki.CharLimit = 256
 	ki.Width = 50
-	ti := textinput.New()
+	ti := newInput()
 	ti.Placeholder = "tag1,tag2 (optional)"
 	ti.CharLimit = 200
 	ti.Width = 50
 
-	ChosenConnectionGlobal = nil
-
 	dvp := viewport.New(0, 0)
 
-	return Model{
+	m := Model{
 		List:                 l,
 		IsEditing:            false,
 		EditNameInput:        ni,
@@ -143,13 +215,27 @@ func NewModel(cfg config.Config) Model {
 		DeleteIndex:          -1,
 		DeleteConnectionName: "",
 		detailViewport:       dvp,
+		probeInterval:        cfg.Settings.BackgroundProbeInterval(),
+		probeParallel:        cfg.Settings.BackgroundProbeParallel(),
+		uptime:               map[string]uptimeSummary{},
+		sort:                 order,
 	}
+	m.List.Title = m.title()
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.List.StartSpinner(),
 		textinput.Blink,
+		fetchLiveSessions,
+		loadUptimes(m.List.Items()),
+		func() tea.Msg {
+			if m.probeInterval <= 0 {
+				return nil
+			}
+			return probeTickMsg{}
+		},
 	)
 }
 
@@ -157,11 +243,98 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
+	// Background updates are handled in every mode so their polling loops
+	// keep running while a form or prompt is open.
+	switch msg := msg.(type) {
+	case liveSessionsMsg:
+		for i, listItem := range m.List.Items() {
+			if item, ok := listItem.(Item); ok && item.LiveSessions != msg[item.Name] {
+				item.LiveSessions = msg[item.Name]
+				cmds = append(cmds, m.List.SetItem(i, item))
+			}
+		}
+		cmds = append(cmds, scheduleLiveSessions())
+		return m, tea.Batch(cmds...)
+	case probeTickMsg:
+		return m, m.startProbeRound()
+	case probeResultsMsg:
+		return m, m.applyProbeResults(msg)
+	case uptimeLoadedMsg:
+		maps.Copy(m.uptime, msg)
+		m.refreshDetail()
+		return m, nil
+	case tunnelTickMsg, tunnelToggledMsg:
+		return m, m.updateTunnels(msg)
+	case bulkStartedMsg:
+		return m, m.applySessionsStarted(msg)
+	case sessionEndedMsg:
+		return m, m.applySessionEnded(msg)
+	case clipboardCopiedMsg:
+		return m, m.applyClipboardCopied(msg)
+	case clipboardClearMsg:
+		return m, m.applyClipboardClear(msg)
+	case clipboardClearedMsg:
+		if msg.err != nil {
+			m.StatusMessage = fmt.Sprintf("Clearing the clipboard failed: %v", msg.err)
+			m.StatusType = StatusError
+		} else {
+			m.StatusMessage = "Clipboard cleared."
+			m.StatusType = StatusNone
+		}
+		return m, nil
+	}
+
+	if m.help != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateHelpKey(key)
+		}
+		if size, ok := msg.(tea.WindowSizeMsg); ok {
+			m.lastKnownWidth, m.lastKnownHeight = size.Width, size.Height
+		}
+	}
+
+	if m.palette != nil {
+		return m.updatePalette(msg)
+	}
+
+	if m.copyMenu {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateCopyMenu(key)
+		}
+	}
+
+	if m.tunnels != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTunnelsKey(key)
+		}
+	}
+
+	if m.bulk != nil {
+		return m.updateBulkPrompt(msg)
+	}
+
+	if m.queryInput != nil {
+		return m.updateQuery(msg)
+	}
+
+	if m.tagPanel != nil && m.tagPanel.focused {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTagPanelKey(key)
+		}
+	}
+
 	if m.IsConfirmingDelete {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch strings.ToLower(msg.String()) {
-			case "y":
+			action, _ := keys.action(msg, scopeConfirm)
+			if msg.String() == forceQuitKey {
+				action = actConfirmNo
+			}
+			switch action {
+			case actConfirmYes:
+				if len(m.bulkDelete) > 0 {
+					return m.deleteSelected()
+				}
 				if err := config.DeleteConnectionByIndex(m.DeleteIndex); err != nil {
 					m.StatusMessage = fmt.Sprintf("Error deleting '%s': %v", m.DeleteConnectionName, err)
 					m.StatusType = StatusError
@@ -183,19 +356,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, tea.ClearScreen
 				}
 
-				reloadedCfg := config.GetCurrent()
-				newM := NewModel(reloadedCfg)
-				newM.lastKnownWidth = m.lastKnownWidth
-				newM.lastKnownHeight = m.lastKnownHeight
-				newM.StatusMessage = m.StatusMessage
-				newM.StatusType = m.StatusType
-
-				if newM.lastKnownWidth > 0 && newM.lastKnownHeight > 0 {
-					newM.List.SetSize(newM.lastKnownWidth, newM.lastKnownHeight-1)
-				}
-				return newM, tea.ClearScreen
-			case "n", "esc", "ctrl+c":
+				return m.reloaded(), tea.ClearScreen
+			case actConfirmNo:
 				m.IsConfirmingDelete = false
+				m.bulkDelete = nil
 				m.DeleteIndex = -1
 				m.DeleteConnectionName = ""
 				m.StatusMessage = "Delete cancelled."
@@ -209,8 +373,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.IsEditing {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.Type {
-			case tea.KeyCtrlC, tea.KeyEsc:
+			action, _ := keys.action(msg, scopeForm)
+			if msg.String() == forceQuitKey {
+				action = actFormCancel
+			}
+			switch action {
+			case actFormCancel:
 				m.IsEditing = false
 				m.EditingIndex = -1
 				m.EditNameInput.Blur()
@@ -219,11 +387,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.StatusMessage = "Edit cancelled."
 				m.StatusType = StatusNone
 				return m, tea.ClearScreen
-			case tea.KeyTab, tea.KeyShiftTab:
-				cmd = m.updateFocusEdit(msg.Type == tea.KeyTab)
+			case actFormNext, actFormPrev:
+				cmd = m.updateFocusEdit(action == actFormNext)
 				cmds = append(cmds, cmd)
 				return m, tea.Batch(cmds...)
-			case tea.KeyEnter:
+			case actFormGenerate:
+				if m.EditingIndex == EditingIndexAddNew {
+					m.generateKey()
+				}
+				return m, nil
+			case actFormSave:
+				if m.EditFocusIndex == focusEditGenerate {
+					m.generateKey()
+					return m, nil
+				}
 				m.StatusMessage = ""
 				m.StatusType = StatusNone
 
@@ -307,12 +484,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							}
 						}
 					}
-					updatedConn := config.Connection{
-						Name:  finalName,
-						Key:   keyFromForm,
-						Tags:  tags,
-						Usage: config.GetCurrent().Connections[m.EditingIndex].Usage,
-					}
+					updatedConn := config.GetCurrent().Connections[m.EditingIndex]
+					updatedConn.Name = finalName
+					updatedConn.Key = keyFromForm
+					updatedConn.Tags = tags
 					saveErr = config.UpdateConnectionByIndex(m.EditingIndex, updatedConn)
 					successMessage = fmt.Sprintf("Connection '%s' updated.", finalName)
 				}
@@ -340,16 +515,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, tea.ClearScreen
 				}
 
-				newM := NewModel(config.GetCurrent())
-				newM.lastKnownWidth = m.lastKnownWidth
-				newM.lastKnownHeight = m.lastKnownHeight
-				newM.StatusMessage = m.StatusMessage
-				newM.StatusType = m.StatusType
-
-				if newM.lastKnownWidth > 0 && newM.lastKnownHeight > 0 {
-					newM.List.SetSize(newM.lastKnownWidth, newM.lastKnownHeight-1)
-				}
-				return newM, tea.ClearScreen
+				return m.reloaded(), tea.ClearScreen
 			}
 		}
 
@@ -396,77 +562,26 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.StatusMessage = ""
 			m.StatusType = StatusNone
 		}
-		if m.List.FilterState() != list.Filtering {
-			switch msg.String() {
-			case "q", "ctrl+c":
-				ChosenConnectionGlobal = nil
-				return m, tea.Quit
-			case "e":
-				if len(m.List.VisibleItems()) > 0 {
-					selected, ok := m.List.SelectedItem().(Item)
-					if ok {
-						m.IsEditing = true
-						cfg := config.GetCurrent()
-						foundIndex := -1
-						for i, conn := range cfg.Connections {
-							if conn.Name == selected.Name && conn.Key == selected.Key {
-								foundIndex = i
-								break
-							}
-						}
-						if foundIndex != -1 {
-							m.EditingIndex = foundIndex
-						} else {
-							m.IsEditing = false
-							return m, nil
-						}
-						m.EditNameInput.SetValue(selected.Name)
-						m.EditKeyInput.SetValue(selected.Key)
-						m.EditTagsInput.SetValue(strings.Join(selected.Tags, ", "))
-						m.EditFocusIndex = focusEditName
-						m.StatusMessage = ""
-						m.StatusType = StatusNone
-						return m, m.EditNameInput.Focus()
-					}
-				}
-			case "d":
-				if len(m.List.VisibleItems()) > 0 {
-					selected, ok := m.List.SelectedItem().(Item)
-					if ok {
-						cfg := config.GetCurrent()
-						foundIndex := -1
-						for i, connVal := range cfg.Connections {
-							if connVal.Name == selected.Name && connVal.Key == selected.Key {
-								foundIndex = i
-								break
-							}
-						}
-						if foundIndex != -1 {
-							m.IsConfirmingDelete = true
-							m.DeleteIndex = foundIndex
-							m.DeleteConnectionName = selected.Name
-							m.StatusMessage = ""
-							m.StatusType = StatusNone
-							return m, nil
-						}
-					}
+		if msg.String() == forceQuitKey {
+			return m, tea.Quit
+		}
+		// Enter also connects from the filter, which it applies first so the
+		// list is browsable after the session; other keys are typed into it.
+		filterConnect := m.List.FilterState() == list.Filtering && msg.Type == tea.KeyEnter && keys.is(msg, actConnect)
+		if filterConnect {
+			m.List, cmd = m.List.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+		if m.List.FilterState() != list.Filtering || filterConnect {
+			if action, ok := keys.action(msg, scopeList); ok {
+				if next, cmd, handled := m.runAction(action); handled {
+					next.refreshDetail()
+					return next, tea.Batch(append(cmds, cmd)...)
 				}
-			case "a":
-				m.IsEditing = true
-				m.EditingIndex = EditingIndexAddNew
-				m.EditNameInput.SetValue("")
-				m.EditKeyInput.SetValue("")
-				m.EditTagsInput.SetValue("")
-				m.EditFocusIndex = focusEditName
-				m.StatusMessage = ""
-				m.StatusType = StatusNone
-				return m, m.EditNameInput.Focus()
 			}
-		}
-		if msg.Type == tea.KeyEnter && !m.IsEditing && !m.IsConfirmingDelete {
-			if selected, ok := m.List.SelectedItem().(Item); ok {
-				ChosenConnectionGlobal = &selected
-				return m, tea.Quit
+			if filterConnect {
+				m.refreshDetail()
+				return m, tea.Batch(cmds...)
 			}
 		}
 	}
@@ -474,6 +589,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.List, cmd = m.List.Update(msg)
 	cmds = append(cmds, cmd)
 
+	// Typing the query prefix into the fuzzy filter switches to a query.
+	if m.List.FilterState() == list.Filtering && strings.HasPrefix(m.List.FilterInput.Value(), queryPrefix) {
+		text := strings.TrimPrefix(m.List.FilterInput.Value(), queryPrefix)
+		m.List.ResetFilter()
+		cmds = append(cmds, m.openQuery(text))
+	}
+
+	m.refreshDetail()
+	return m, tea.Batch(cmds...)
+}
+
+// refreshDetail shows the highlighted connection in the detail panel.
+func (m *Model) refreshDetail() {
 	if item, ok := m.List.SelectedItem().(Item); ok {
 		m.detailViewport.SetContent(m.renderDetailPanel(item))
 	} else if len(m.List.Items()) == 0 {
@@ -481,20 +609,48 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	} else {
 		m.detailViewport.SetContent("No connection selected, or list is empty.")
 	}
-
-	return m, tea.Batch(cmds...)
 }
 
 func (m Model) View() string {
+	if m.help != nil {
+		return m.viewHelp()
+	}
+
+	if m.palette != nil {
+		return m.viewPalette()
+	}
+
+	if m.tunnels != nil {
+		return m.viewTunnels()
+	}
+
+	if m.bulk != nil {
+		return m.viewBulkPrompt()
+	}
+
+	if m.IsConfirmingDelete && len(m.bulkDelete) > 0 {
+		var b strings.Builder
+		headerStyle := styles.danger.Bold(true).MarginBottom(1)
+		b.WriteString(headerStyle.Render(fmt.Sprintf("DELETE %d Connection(s)?", len(m.bulkDelete))) + "\n\n")
+		for _, name := range m.bulkDelete {
+			b.WriteString("  - " + name + "\n")
+		}
+		promptStyle := lipgloss.NewStyle().MarginBottom(1)
+		b.WriteString("\n" + promptStyle.Render("This action cannot be undone.") + "\n\n")
+		hintStyle := styles.hint
+		b.WriteString(hintStyle.Render("(" + keys.footer(actConfirmYes, actConfirmNo) + ")"))
+		return b.String()
+	}
+
 	if m.IsConfirmingDelete {
 		var b strings.Builder
-		headerStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1).Foreground(lipgloss.Color("196"))
+		headerStyle := styles.danger.Bold(true).MarginBottom(1)
 		b.WriteString(headerStyle.Render(fmt.Sprintf("DELETE Connection: %s?", m.DeleteConnectionName)) + "\n\n")
 		promptStyle := lipgloss.NewStyle().MarginBottom(1)
 		b.WriteString(promptStyle.Render(fmt.Sprintf("Are you sure you want to delete '%s'?", m.DeleteConnectionName)) + "\n")
 		b.WriteString(promptStyle.Render("This action cannot be undone.") + "\n\n")
-		hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-		b.WriteString(hintStyle.Render("(Y)es, delete it! / (N)o or (Esc) to cancel."))
+		hintStyle := styles.hint
+		b.WriteString(hintStyle.Render("(" + keys.footer(actConfirmYes, actConfirmNo) + ")"))
 		return b.String()
 	}
 
@@ -503,11 +659,11 @@ func (m Model) View() string {
 		headerStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
 		var formTitle string
 		if m.EditingIndex == EditingIndexAddNew {
-			formTitle = "Add New Connection (Esc to Cancel)"
+			formTitle = "Add New Connection"
 		} else if m.EditingIndex >= 0 && m.EditingIndex < len(config.GetCurrent().Connections) {
-			formTitle = fmt.Sprintf("Editing Connection: %s (Esc to Cancel)", config.GetCurrent().Connections[m.EditingIndex].Name)
+			formTitle = fmt.Sprintf("Editing Connection: %s", config.GetCurrent().Connections[m.EditingIndex].Name)
 		} else {
-			formTitle = "Edit Connection (Esc to Cancel)"
+			formTitle = "Edit Connection"
 		}
 		formBuilder.WriteString(headerStyle.Render(formTitle) + "\n")
 
@@ -515,19 +671,29 @@ func (m Model) View() string {
 		formBuilder.WriteString(inputStyle.Render("Name:  "+m.EditNameInput.View()) + "\n")
 		formBuilder.WriteString(inputStyle.Render("Key:   "+m.EditKeyInput.View()) + "\n")
 		formBuilder.WriteString(inputStyle.Render("Tags:  "+m.EditTagsInput.View()+" (comma-separated)") + "\n")
+		if m.EditingIndex == EditingIndexAddNew {
+			buttonStyle := styles.muted
+			if m.EditFocusIndex == focusEditGenerate {
+				buttonStyle = styles.selected
+			}
+			formBuilder.WriteString("       " + buttonStyle.Render("[ Generate key ]") + "\n")
+		}
 
 		if m.StatusMessage != "" && (m.StatusType == StatusError || strings.Contains(m.StatusMessage, "generated name")) {
 			var statusStyle lipgloss.Style
 			if m.StatusType == StatusError {
-				statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true).MarginTop(1)
+				statusStyle = styles.danger.Bold(true).MarginTop(1)
 			} else {
-				statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("242")).MarginTop(1)
+				statusStyle = styles.subtle.MarginTop(1)
 			}
 			formBuilder.WriteString("\n" + statusStyle.Render(m.StatusMessage))
 		}
 
-		hintText := "(Tab/Shift+Tab • Enter to Save)"
-		formBuilder.WriteString("\n\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(hintText))
+		hintActions := []string{"fields=" + actFormNext + "," + actFormPrev, actFormSave, actFormCancel}
+		if m.EditingIndex == EditingIndexAddNew {
+			hintActions = append(hintActions, actFormGenerate)
+		}
+		formBuilder.WriteString("\n\n" + styles.hint.Render("("+keys.footer(hintActions...)+")"))
 
 		return formBuilder.String()
 	}
@@ -537,24 +703,30 @@ func (m Model) View() string {
 	if m.StatusMessage != "" {
 		var statusStyle lipgloss.Style
 		if m.StatusType == StatusError {
-			statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true).Padding(0, 1)
+			statusStyle = styles.danger.Bold(true).Padding(0, 1)
 		} else if m.StatusType == StatusSuccess {
-			statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("40")).Bold(true).Padding(0, 1)
+			statusStyle = styles.success.Bold(true).Padding(0, 1)
 		} else {
-			statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("242")).Padding(0, 1)
+			statusStyle = styles.subtle.Padding(0, 1)
 		}
 		statusLine = statusStyle.Render(m.StatusMessage)
 	}
 
-	listColumnWidth := (m.lastKnownWidth * 40) / 100
+	// The tag panel, when open, takes its width from the list and details.
+	width := m.lastKnownWidth
+	if m.tagPanel != nil {
+		width -= tagPanelWidth + 1
+	}
+
+	listColumnWidth := (width * 40) / 100
 	if listColumnWidth < 30 {
 		listColumnWidth = 30
 	}
-	if listColumnWidth > m.lastKnownWidth-25 {
-		listColumnWidth = m.lastKnownWidth - 25
+	if listColumnWidth > width-25 {
+		listColumnWidth = width - 25
 	}
-	if m.lastKnownWidth < 50 {
-		listColumnWidth = m.lastKnownWidth
+	if width < 50 {
+		listColumnWidth = width
 	}
 
 	// Calculate height for list and viewport (panel kanan)
@@ -573,10 +745,12 @@ func (m Model) View() string {
 	var finalCombinedView string
 	var detailPanelRenderedContent string // Declare here to ensure it's always available
 
-	if m.lastKnownWidth > listColumnWidth+5 { // Only show detail panel if there is enough space
-		detailColumnWidth := m.lastKnownWidth - listColumnWidth - 1
+	if width > listColumnWidth+5 { // Only show detail panel if there is enough space
+		detailColumnWidth := width - listColumnWidth - 1
 		m.detailViewport.Width = detailColumnWidth
 		m.detailViewport.Height = availableHeight
+		m.List.SetWidth(listColumnWidth)
+		listRender = m.List.View()
 
 		if item, ok := m.List.SelectedItem().(Item); ok {
 			m.detailViewport.SetContent(m.renderDetailPanel(item))
@@ -587,7 +761,7 @@ func (m Model) View() string {
 		}
 		detailPanelRenderedContent = m.detailViewport.View()
 
-		separatorStyle := lipgloss.NewStyle().SetString("│").Foreground(lipgloss.Color("239"))
+		separatorStyle := styles.border.SetString("│")
 		separatorHeight := m.detailViewport.Height
 		if separatorHeight < 1 {
 			separatorHeight = 1
@@ -599,23 +773,43 @@ func (m Model) View() string {
 			lipgloss.NewStyle().Width(m.detailViewport.Width).PaddingLeft(1).Render(detailPanelRenderedContent),
 		)
 	} else { // Not enough space for detail panel, list takes full width
-		m.List.SetSize(m.lastKnownWidth, availableHeight)
+		m.List.SetSize(width, availableHeight)
 		listRender = m.List.View() // Re-render list with full width
 		finalCombinedView = listRender
 		// detailPanelRenderedContent remains empty string, which is fine
 	}
 
+	if m.tagPanel != nil {
+		separator := styles.border.Render(strings.TrimSuffix(strings.Repeat("│\n", availableHeight), "\n"))
+		finalCombinedView = lipgloss.JoinHorizontal(lipgloss.Top, m.viewTagPanel(availableHeight), separator, finalCombinedView)
+	}
+
 	mainVerticalParts := []string{finalCombinedView}
 	if statusLine != "" {
 		mainVerticalParts = append(mainVerticalParts, statusLine)
 	}
 
-	footerText := "↑/↓ nav • q quit • / filter • e edit • d del • a add • Enter exec"
+	footerText := keys.footer("nav=up,down", actQuit, actFilter, actEdit, "del="+actDelete, actAdd, "exec="+actConnect,
+		actBackground, actCheck, actTunnels, actNew, "sort="+actSort+","+actSortReverse, actTags, actQuery, actCopy, actPalette, actHelp)
+	if n := len(m.selectedNames()); n > 0 {
+		footerText = fmt.Sprintf("%d selected • ", n) + keys.footer("toggle="+actSelect, "all="+actSelectAll, "invert="+actInvertSelection,
+			"del="+actDelete, "tag="+actAddTags+","+actRemoveTags, actGroup, actExport, "connect="+actBackground, actCheck, "clear="+actClearSelection, actPalette)
+	}
+	if m.tagPanel != nil && m.tagPanel.focused {
+		footerText = keys.footer("nav=up,down", actPanelPick, actPanelEdit, actPanelMode, actPanelClear, actPanelRename, actPanelMerge,
+			"list="+actFocusPanel, actPanelClose, actHelp)
+	}
 	if m.List.FilterState() == list.Filtering {
 		footerText = "esc clear • enter select"
 	}
-	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242")).Background(lipgloss.Color("235")).Padding(0, 1)
-	mainVerticalParts = append(mainVerticalParts, footerStyle.Render(footerText))
+	footerStyle := styles.bar.Padding(0, 1)
+	if m.queryInput != nil {
+		mainVerticalParts = append(mainVerticalParts, m.viewQuery())
+	} else if m.copyMenu {
+		mainVerticalParts = append(mainVerticalParts, m.viewCopyMenu())
+	} else {
+		mainVerticalParts = append(mainVerticalParts, footerStyle.Render(footerText))
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, mainVerticalParts...)
 }
@@ -629,10 +823,14 @@ func (m *Model) updateFocusEdit(forward bool) tea.Cmd {
 	case focusEditTags:
 		m.EditTagsInput.Blur()
 	}
+	fields := 3
+	if m.EditingIndex == EditingIndexAddNew {
+		fields = 4
+	}
 	if forward {
-		m.EditFocusIndex = (m.EditFocusIndex + 1) % 3
+		m.EditFocusIndex = (m.EditFocusIndex + 1) % fields
 	} else {
-		m.EditFocusIndex = (m.EditFocusIndex - 1 + 3) % 3
+		m.EditFocusIndex = (m.EditFocusIndex - 1 + fields) % fields
 	}
 	switch m.EditFocusIndex {
 	case focusEditName:
@@ -647,7 +845,7 @@ func (m *Model) updateFocusEdit(forward bool) tea.Cmd {
 
 func (m Model) renderDetailPanel(item Item) string {
 	var s strings.Builder
-	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
+	keyStyle := styles.subtle
 	valueStyle := lipgloss.NewStyle().Bold(true)
 
 	s.WriteString(valueStyle.Render(item.Name) + "\n\n")
@@ -657,27 +855,41 @@ func (m Model) renderDetailPanel(item Item) string {
 	} else {
 		s.WriteString(keyStyle.Render("Tags: ") + valueStyle.Render("-") + "\n")
 	}
+	if item.Group != "" {
+		s.WriteString(keyStyle.Render("Group: ") + valueStyle.Render(item.Group) + "\n")
+	}
 	s.WriteString(keyStyle.Render("Usage: ") + valueStyle.Render(fmt.Sprintf("%d times", item.Usage)) + "\n")
 
 	lastConnectedStr := "Never"
 	if item.LastConnected != nil {
-		if time.Since(*item.LastConnected).Hours() < 24*7 {
-			if time.Now().YearDay() == item.LastConnected.YearDay() && time.Now().Year() == item.LastConnected.Year() {
-				lastConnectedStr = "Today, " + item.LastConnected.Format("15:04")
-			} else if time.Now().YearDay()-1 == item.LastConnected.YearDay() && time.Now().Year() == item.LastConnected.Year() {
-				lastConnectedStr = "Yesterday, " + item.LastConnected.Format("15:04")
-			} else {
-				lastConnectedStr = item.LastConnected.Format("Mon, 2 Jan 15:04")
-			}
-		} else {
-			lastConnectedStr = item.LastConnected.Format("2 Jan 2006")
-		}
+		lastConnectedStr = formatTimestamp(*item.LastConnected)
 	}
 	s.WriteString(keyStyle.Render("Last Seen: ") + valueStyle.Render(lastConnectedStr) + "\n")
+	if item.PendingKey != "" && item.PendingSince != nil {
+		s.WriteString(keyStyle.Render("Rotation: ") + valueStyle.Render("pending since "+formatTimestamp(*item.PendingSince)) + "\n")
+	}
+	if item.LiveSessions > 0 {
+		s.WriteString(keyStyle.Render("Live: ") + valueStyle.Render(fmt.Sprintf("%d background session(s)", item.LiveSessions)) + "\n")
+	}
+	s.WriteString("\n" + renderProbeDetails(item, keyStyle, valueStyle))
+	s.WriteString(renderUptime(m.uptime[item.Name], keyStyle, valueStyle))
 
 	return s.String()
 }
 
+// formatTimestamp formats t relative to today for the detail panel.
+func formatTimestamp(t time.Time) string {
+	if time.Since(t).Hours() < 24*7 {
+		if time.Now().YearDay() == t.YearDay() && time.Now().Year() == t.Year() {
+			return "Today, " + t.Format("15:04")
+		} else if time.Now().YearDay()-1 == t.YearDay() && time.Now().Year() == t.Year() {
+			return "Yesterday, " + t.Format("15:04")
+		}
+		return t.Format("Mon, 2 Jan 15:04")
+	}
+	return t.Format("2 Jan 2006")
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+	"github.com/NumeXx/gsm/pkg/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// createGenerated saves a new connection with a generated key and shows the
+// listener command to run on the other side.
+func (m Model) createGenerated() (tea.Model, tea.Cmd) {
+	conn, err := config.GenerateConnection("")
+	if err == nil {
+		config.AddConnection(conn)
+		err = config.Save()
+	}
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Error creating connection: %v", err)
+		m.StatusType = StatusError
+		return m, nil
+	}
+	m.StatusMessage = fmt.Sprintf("Created '%s'. Listener: gs-netcat %s", conn.Name, strings.Join(runner.ListenerArgs(conn), " "))
+	m.StatusType = StatusSuccess
+	return m.reloaded(), tea.ClearScreen
+}
+
+// generateKey fills the key field of the add form with a fresh secret.
+func (m *Model) generateKey() {
+	key, err := utils.GenerateSecret()
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Error generating key: %v", err)
+		m.StatusType = StatusError
+		return
+	}
+	m.EditKeyInput.SetValue(key)
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+}
+
+// reloaded returns a fresh model for the current config that keeps the
+// window size, selection, running checks, filters, status message and
+// pending clipboard clear of m.
+func (m Model) reloaded() Model {
+	newM := NewModel(config.GetCurrent())
+	newM.tagFilter = m.tagFilter
+	newM.query = m.query
+	newM.collection = m.collection
+	if m.tagPanel != nil {
+		newM.tagPanel = m.tagPanel
+		newM.tagPanel.refresh()
+	}
+	// refreshItems keeps the state of the items it already knows.
+	newM.List.SetItems(m.List.Items())
+	newM.hidden = m.hidden
+	newM.refreshItems()
+	newM.uptime = m.uptime
+	newM.tunnelPolling = m.tunnelPolling
+	newM.clipboardID = m.clipboardID
+	newM.clipboardSecret = m.clipboardSecret
+	newM.lastKnownWidth = m.lastKnownWidth
+	newM.lastKnownHeight = m.lastKnownHeight
+	newM.StatusMessage = m.StatusMessage
+	newM.StatusType = m.StatusType
+	if newM.lastKnownWidth > 0 && newM.lastKnownHeight > 0 {
+		newM.List.SetSize(newM.lastKnownWidth, newM.lastKnownHeight-1)
+	}
+	return newM
+}
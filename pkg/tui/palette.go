@@ -0,0 +1,292 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/theme"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// paletteWidth is the width of the command palette box.
+const paletteWidth = 60
+
+// paletteRows is the number of commands the palette shows at once.
+const paletteRows = 12
+
+// paletteCommand is one entry of the command palette.
+type paletteCommand struct {
+	title string
+	// action is the key action the command runs, if any, for its key label.
+	action string
+	run    func(Model) (Model, tea.Cmd)
+}
+
+// palette is the command palette: a fuzzy search over every TUI action.
+type palette struct {
+	input    textinput.Model
+	commands []paletteCommand
+	matches  []list.Rank
+	cursor   int
+}
+
+// actionCommand returns a palette command running a key action.
+func actionCommand(title, action string) paletteCommand {
+	return paletteCommand{title: title, action: action, run: func(m Model) (Model, tea.Cmd) {
+		next, cmd, ok := m.runAction(action)
+		if !ok {
+			m.StatusMessage = fmt.Sprintf("'%s' doesn't apply here.", title)
+			m.StatusType = StatusError
+			return m, nil
+		}
+		next.refreshDetail()
+		return next, cmd
+	}}
+}
+
+// paletteCommands lists every command the palette offers for m.
+func (m Model) paletteCommands() []paletteCommand {
+	commands := []paletteCommand{
+		actionCommand("Connect", actConnect),
+		actionCommand("Connect in the background", actBackground),
+		actionCommand("Edit connection", actEdit),
+		actionCommand("Delete connection", actDelete),
+		actionCommand("Add connection", actAdd),
+		actionCommand("New connection with a generated key", actNew),
+		actionCommand("Check health (probe)", actCheck),
+		actionCommand("Export", actExport),
+		actionCommand("Copy…", actCopy),
+		actionCommand("Add tags", actAddTags),
+		actionCommand("Remove tags", actRemoveTags),
+		actionCommand("Move to group", actGroup),
+		actionCommand("Select", actSelect),
+		actionCommand("Select all visible", actSelectAll),
+		actionCommand("Invert selection", actInvertSelection),
+		actionCommand("Clear selection", actClearSelection),
+		{title: "Filter", action: actFilter, run: func(m Model) (Model, tea.Cmd) {
+			m.List.SetFilterText("")
+			m.List.SetFilterState(list.Filtering)
+			return m, textinput.Blink
+		}},
+		actionCommand("Query", actQuery),
+		actionCommand("Tag panel", actTags),
+		actionCommand("Tunnels", actTunnels),
+		actionCommand("Next sort order", actSort),
+		actionCommand("Reverse sort", actSortReverse),
+	}
+	for _, target := range copyTargets {
+		commands = append(commands, paletteCommand{title: "Copy " + target.label, run: func(m Model) (Model, tea.Cmd) {
+			return m, m.copy(target)
+		}})
+	}
+	for _, field := range sortFields {
+		order := sortOrder{by: field, desc: sortDescByDefault[field]}
+		title := "Sort by " + field
+		if field == sortNone {
+			title = "Sort as in the config file"
+		}
+		commands = append(commands, paletteCommand{title: title, run: func(m Model) (Model, tea.Cmd) {
+			return m, m.setSort(order)
+		}})
+	}
+	for _, c := range config.GetCurrent().Collections {
+		name := c.Name
+		commands = append(commands, paletteCommand{title: "Show collection @" + name, run: func(m Model) (Model, tea.Cmd) {
+			if active, ok := m.activeCollection(); ok && active == name {
+				return m, nil
+			}
+			return m, m.applyCollection(name)
+		}})
+	}
+	for _, name := range append([]string{theme.Auto}, theme.Names()...) {
+		commands = append(commands, paletteCommand{title: "Theme: " + name, run: func(m Model) (Model, tea.Cmd) {
+			return m.switchTheme(name)
+		}})
+	}
+	commands = append(commands,
+		paletteCommand{title: "Help: key bindings", action: actHelp, run: func(m Model) (Model, tea.Cmd) {
+			return m, m.openHelp()
+		}},
+		actionCommand("Quit", actQuit),
+	)
+	return commands
+}
+
+// switchTheme redraws the TUI in the theme name and saves it in the config.
+func (m Model) switchTheme(name string) (Model, tea.Cmd) {
+	t, err := theme.Resolve(name)
+	if err != nil {
+		m.StatusMessage = err.Error()
+		m.StatusType = StatusError
+		return m, nil
+	}
+	SetTheme(t)
+	config.SetTheme(name)
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving theme: %v", err)
+		m.StatusType = StatusError
+	} else {
+		m.StatusMessage = fmt.Sprintf("Theme switched to %s.", name)
+		m.StatusType = StatusSuccess
+	}
+	// The list delegate and inputs take their styles when they are built.
+	cursor := m.List.Index()
+	next := m.reloaded()
+	next.List.Select(cursor)
+	next.refreshDetail()
+	return next, tea.ClearScreen
+}
+
+// openPalette shows the command palette.
+func (m *Model) openPalette() tea.Cmd {
+	input := newInput()
+	input.Prompt = "> "
+	input.Placeholder = "type a command"
+	input.CharLimit = 100
+	input.Width = paletteWidth - 8
+	m.palette = &palette{input: input, commands: m.paletteCommands()}
+	m.palette.filter()
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.palette.input.Focus()
+}
+
+// filter ranks the commands by the search text; all of them in order when
+// it is empty.
+func (p *palette) filter() {
+	term := strings.TrimSpace(p.input.Value())
+	if term == "" {
+		p.matches = make([]list.Rank, len(p.commands))
+		for i := range p.commands {
+			p.matches[i] = list.Rank{Index: i}
+		}
+	} else {
+		titles := make([]string, len(p.commands))
+		for i, c := range p.commands {
+			titles[i] = c.title
+		}
+		p.matches = list.DefaultFilter(term, titles)
+	}
+	p.cursor = min(p.cursor, max(len(p.matches)-1, 0))
+}
+
+// updatePalette handles messages while the command palette is open.
+func (m Model) updatePalette(msg tea.Msg) (tea.Model, tea.Cmd) {
+	p := m.palette
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case forceQuitKey:
+			return m, tea.Quit
+		case "esc":
+			m.palette = nil
+			return m, nil
+		case "up", "ctrl+p", "shift+tab":
+			if p.cursor > 0 {
+				p.cursor--
+			}
+			return m, nil
+		case "down", "ctrl+n", "tab":
+			if p.cursor < len(p.matches)-1 {
+				p.cursor++
+			}
+			return m, nil
+		case "enter":
+			if len(p.matches) == 0 {
+				return m, nil
+			}
+			command := p.commands[p.matches[p.cursor].Index]
+			m.palette = nil
+			next, cmd := command.run(m)
+			return next, cmd
+		}
+	}
+
+	input, cmd := p.input.Update(msg)
+	if input.Value() != p.input.Value() {
+		p.input = input
+		p.cursor = 0
+		p.filter()
+	} else {
+		p.input = input
+	}
+	return m, cmd
+}
+
+// paletteTarget describes what the palette's commands act on.
+func (m Model) paletteTarget() string {
+	if n := len(m.selectedNames()); n > 0 {
+		return fmt.Sprintf("%d selected", n)
+	}
+	if item, ok := m.List.SelectedItem().(Item); ok {
+		return item.Name
+	}
+	return "no connection"
+}
+
+// viewPalette renders the command palette box.
+func (m Model) viewPalette() string {
+	p := m.palette
+	inner := paletteWidth - 4
+
+	lines := []string{
+		styles.text.Bold(true).Render("Commands") + styles.subtle.Render(" · "+truncate(m.paletteTarget(), inner-12)),
+		p.input.View(),
+		"",
+	}
+	start := 0
+	if p.cursor >= paletteRows {
+		start = p.cursor - paletteRows + 1
+	}
+	for i := start; i < min(start+paletteRows, len(p.matches)); i++ {
+		match := p.matches[i]
+		command := p.commands[match.Index]
+		label := keys.first(command.action)
+
+		title := truncate(command.title, inner-lipgloss.Width(label)-3)
+		base := styles.text
+		if i == p.cursor {
+			base = styles.selected
+		}
+		rendered := lipgloss.StyleRunes(title, match.MatchedIndexes, base.Underline(true), base)
+		if i == p.cursor {
+			pad := inner - 1 - lipgloss.Width(title) - lipgloss.Width(label)
+			line := base.Render(" ") + rendered + base.Render(strings.Repeat(" ", max(pad, 1))) + base.Render(label)
+			lines = append(lines, line)
+			continue
+		}
+		pad := inner - 1 - lipgloss.Width(title) - lipgloss.Width(label)
+		lines = append(lines, " "+rendered+strings.Repeat(" ", max(pad, 1))+styles.hint.Render(label))
+	}
+	if len(p.matches) == 0 {
+		lines = append(lines, styles.hint.Render(" no matching command"))
+	}
+	lines = append(lines, "", styles.hint.Render("↑/↓ choose • enter run • esc close"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.border.GetForeground()).
+		Padding(0, 1).
+		Width(paletteWidth).
+		Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(m.lastKnownWidth, m.lastKnownHeight, lipgloss.Center, lipgloss.Top, box,
+		lipgloss.WithWhitespaceChars(" "))
+}
+
+// truncate shortens s to width cells, ending it with "…".
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
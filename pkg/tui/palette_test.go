@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/theme"
+)
+
+// paletteTitles returns the titles of the commands the palette shows, in order.
+func paletteTitles(p *palette) []string {
+	var titles []string
+	for _, match := range p.matches {
+		titles = append(titles, p.commands[match.Index].title)
+	}
+	return titles
+}
+
+func TestPaletteFilter(t *testing.T) {
+	p := &palette{input: newInput()}
+	for _, title := range []string{"Connect", "Sort by name", "Sort by usage", "Select", "Quit"} {
+		p.commands = append(p.commands, paletteCommand{title: title})
+	}
+
+	p.filter()
+	if got := paletteTitles(p); !slices.Equal(got, []string{"Connect", "Sort by name", "Sort by usage", "Select", "Quit"}) {
+		t.Errorf("without a search the palette shows %q, want every command in order", got)
+	}
+
+	p.cursor = 4
+	for _, tt := range []struct {
+		term  string
+		first string
+	}{
+		{"usage", "Sort by usage"},
+		{"sbn", "Sort by name"},
+		{"  quit ", "Quit"},
+		{"SEL", "Select"},
+	} {
+		p.input.SetValue(tt.term)
+		p.filter()
+		got := paletteTitles(p)
+		if len(got) == 0 || got[0] != tt.first {
+			t.Errorf("search %q shows %q, want %q first", tt.term, got, tt.first)
+		}
+		if p.cursor >= len(got) {
+			t.Errorf("search %q left the cursor at %d of %d matches", tt.term, p.cursor, len(got))
+		}
+	}
+
+	p.input.SetValue("xyzzy")
+	p.filter()
+	if len(p.matches) != 0 || p.cursor != 0 {
+		t.Errorf("search xyzzy shows %q with the cursor at %d, want nothing", paletteTitles(p), p.cursor)
+	}
+}
+
+// openTestPalette returns a model over conns with the palette open.
+func openTestPalette(t *testing.T, conns ...config.Connection) Model {
+	t.Helper()
+	useConfig(t, conns...)
+	useKeymap(t, "default", nil)
+	useTheme(t, theme.Dark)
+	m := NewModel(config.GetCurrent())
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = next.(Model)
+	if m.palette == nil {
+		t.Fatal("ctrl+p did not open the palette")
+	}
+	return m
+}
+
+// runPalette types term into the open palette and runs the first match.
+func runPalette(t *testing.T, m Model, term string) Model {
+	t.Helper()
+	next, _ := m.Update(runeKey(term))
+	m = next.(Model)
+	if titles := paletteTitles(m.palette); len(titles) == 0 {
+		t.Fatalf("no command matches %q", term)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if m.palette != nil {
+		t.Fatalf("the palette is still open after running %q", term)
+	}
+	return m
+}
+
+func TestPaletteCommands(t *testing.T) {
+	useConfig(t)
+	config.SetCollection("stale", "seen>30d")
+	m := NewModel(config.GetCurrent())
+	var titles []string
+	for _, c := range m.paletteCommands() {
+		titles = append(titles, c.title)
+	}
+	for _, want := range []string{"Connect", "Copy key", "Sort by usage", "Sort as in the config file", "Show collection @stale", "Theme: auto", "Theme: high-contrast", "Quit"} {
+		if !slices.Contains(titles, want) {
+			t.Errorf("palette commands %q do not include %q", titles, want)
+		}
+	}
+}
+
+func TestPaletteRunsActions(t *testing.T) {
+	conns := []config.Connection{{Name: "web-2", Key: "k1", Usage: 1}, {Name: "db-1", Key: "k2", Usage: 7}}
+	m := openTestPalette(t, conns...)
+	if got := m.paletteTarget(); got != "web-2" {
+		t.Errorf("palette target = %q, want the highlighted connection", got)
+	}
+
+	m = runPalette(t, m, "sort by usage")
+	if m.sort != (sortOrder{by: sortUsage, desc: true}) {
+		t.Errorf("sort = %+v, want usage descending", m.sort)
+	}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = runPalette(t, next.(Model), "select all visible")
+	if got := m.selectedNames(); !slices.Equal(got, []string{"db-1", "web-2"}) {
+		t.Errorf("selected %v, want every connection", got)
+	}
+	if got := m.paletteTarget(); got != "2 selected" {
+		t.Errorf("palette target = %q, want the selection", got)
+	}
+
+	// An action that doesn't apply reports it instead of doing nothing.
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = runPalette(t, next.(Model), "sort as in the config")
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = runPalette(t, next.(Model), "reverse sort")
+	if m.StatusType != StatusError || !strings.Contains(m.StatusMessage, "doesn't apply") {
+		t.Errorf("status %q, want reverse sort reported as not applying", m.StatusMessage)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = runPalette(t, next.(Model), "theme: mono")
+	if m.StatusType != StatusSuccess {
+		t.Errorf("status %q after switching themes", m.StatusMessage)
+	}
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := config.GetCurrent().Settings.Theme; got != theme.Mono.Name {
+		t.Errorf("saved theme %q, want mono", got)
+	}
+}
+
+func TestPaletteKeys(t *testing.T) {
+	m := openTestPalette(t, config.Connection{Name: "web-1", Key: "k1"})
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	m = next.(Model)
+	if m.palette.cursor != 0 {
+		t.Errorf("up at the top moved the cursor to %d", m.palette.cursor)
+	}
+	for range 3 {
+		next, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		m = next.(Model)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	m = next.(Model)
+	if m.palette.cursor != 2 {
+		t.Errorf("cursor at %d after three downs and one up, want 2", m.palette.cursor)
+	}
+
+	// Typing resets the cursor; enter with no match does nothing.
+	next, _ = m.Update(runeKey("xyzzy"))
+	m = next.(Model)
+	if m.palette.cursor != 0 {
+		t.Errorf("cursor at %d after typing, want 0", m.palette.cursor)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if m.palette == nil {
+		t.Fatal("enter without a match closed the palette")
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(Model)
+	if m.palette != nil {
+		t.Error("esc did not close the palette")
+	}
+	if len(config.GetCurrent().Connections) != 1 || m.IsEditing || m.IsConfirmingDelete {
+		t.Error("closing the palette ran a command")
+	}
+}
@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NumeXx/gsm/pkg/query"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// queryPrefix starts a structured query instead of a fuzzy filter.
+const queryPrefix = ":"
+
+// openQuery shows the query bar, starting from text.
+func (m *Model) openQuery(text string) tea.Cmd {
+	input := newInput()
+	input.Prompt = queryPrefix
+	input.Placeholder = "tag:prod usage>5 seen<7d"
+	input.CharLimit = 300
+	input.SetValue(text)
+	input.CursorEnd()
+	m.queryInput = &input
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.queryInput.Focus()
+}
+
+// updateQuery handles keys while the query bar is open. The list follows
+// the query as it is typed, as long as it parses. ctrl+s saves the query as
+// a collection and ctrl+x deletes the collection it came from.
+func (m Model) updateQuery(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.collectionInput != nil {
+		return m.updateCollectionPrompt(msg)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.Type {
+		case tea.KeyCtrlS:
+			return m, m.openCollectionPrompt()
+		case tea.KeyCtrlX:
+			return m, m.deleteCollection()
+		case tea.KeyEsc, tea.KeyCtrlC:
+			m.queryInput = nil
+			m.query = nil
+			m.collection = ""
+			m.StatusMessage = ""
+			m.StatusType = StatusNone
+			return m, m.refreshItems()
+		case tea.KeyEnter:
+			if m.StatusType == StatusError {
+				return m, nil
+			}
+			m.queryInput = nil
+			return m, nil
+		}
+	}
+
+	input, cmd := m.queryInput.Update(msg)
+	m.queryInput = &input
+	text := strings.TrimSpace(input.Value())
+	if text == m.query.String() {
+		return m, cmd
+	}
+	q, err := query.Parse(text)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Query: %v", err)
+		m.StatusType = StatusError
+		return m, cmd
+	}
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	m.query = q
+	if q.Empty() {
+		m.query = nil
+	}
+	return m, tea.Batch(cmd, m.refreshItems())
+}
+
+// viewQuery renders the query bar in place of the footer.
+func (m Model) viewQuery() string {
+	if m.collectionInput != nil {
+		return m.viewCollectionPrompt()
+	}
+	hint := "  enter keep • esc clear • ctrl+s save"
+	if m.collection != "" {
+		hint += " • ctrl+x delete @" + m.collection
+	}
+	return m.queryInput.View() + styles.subtle.Render(hint)
+}
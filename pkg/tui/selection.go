@@ -0,0 +1,420 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/utils"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SessionStarter starts detached background sessions.
+type SessionStarter interface {
+	Start(conn config.Connection) (id string, err error)
+}
+
+// Sessions starts the sessions of the bulk connect action. The action is
+// unavailable while it is nil.
+var Sessions SessionStarter
+
+// Bulk actions that ask for a value first.
+const (
+	bulkAddTags = iota
+	bulkRemoveTags
+	bulkGroup
+	bulkExport
+)
+
+// bulkPrompt asks for the value of a bulk action on names.
+type bulkPrompt struct {
+	kind  int
+	names []string
+	input textinput.Model
+}
+
+type bulkStartedMsg struct {
+	started []string
+	errs    []string
+}
+
+// selectedNames returns the names of the selected connections in list order.
+func (m Model) selectedNames() []string {
+	var names []string
+	for _, listItem := range m.List.Items() {
+		if item, ok := listItem.(Item); ok && item.Selected {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// targets returns the connections a bulk action applies to: the selection,
+// or the highlighted connection if nothing is selected.
+func (m Model) targets() []string {
+	if names := m.selectedNames(); len(names) > 0 {
+		return names
+	}
+	if item, ok := m.List.SelectedItem().(Item); ok {
+		return []string{item.Name}
+	}
+	return nil
+}
+
+// setSelected sets the selection mark of every item for which selected
+// returns a different value than it has.
+func (m *Model) setSelected(selected func(Item) bool) tea.Cmd {
+	var cmds []tea.Cmd
+	for i, listItem := range m.List.Items() {
+		item, ok := listItem.(Item)
+		if !ok {
+			continue
+		}
+		if want := selected(item); want != item.Selected {
+			item.Selected = want
+			cmds = append(cmds, m.List.SetItem(i, item))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// visibleNames returns the names of the connections that pass the filter.
+func (m Model) visibleNames() map[string]bool {
+	names := map[string]bool{}
+	for _, listItem := range m.List.VisibleItems() {
+		if item, ok := listItem.(Item); ok {
+			names[item.Name] = true
+		}
+	}
+	return names
+}
+
+// runSelectionAction runs the actions that select connections, and the bulk
+// versions of the others while connections are selected. It reports whether
+// it handled action.
+func (m *Model) runSelectionAction(action string) (tea.Cmd, bool) {
+	selection := m.selectedNames()
+	switch action {
+	case actSelect:
+		current, ok := m.List.SelectedItem().(Item)
+		if !ok {
+			return nil, true
+		}
+		cmd := m.setSelected(func(item Item) bool {
+			if item.Name == current.Name {
+				return !item.Selected
+			}
+			return item.Selected
+		})
+		m.List.CursorDown()
+		return cmd, true
+	case actSelectAll:
+		visible := m.visibleNames()
+		all := true
+		for _, listItem := range m.List.VisibleItems() {
+			if item, ok := listItem.(Item); ok && !item.Selected {
+				all = false
+			}
+		}
+		// Selecting all when everything visible is selected clears it instead.
+		return m.setSelected(func(item Item) bool {
+			if visible[item.Name] {
+				return !all
+			}
+			return item.Selected
+		}), true
+	case actInvertSelection:
+		visible := m.visibleNames()
+		return m.setSelected(func(item Item) bool {
+			return item.Selected != visible[item.Name]
+		}), true
+	case actAddTags:
+		return m.openBulkPrompt(bulkAddTags), true
+	case actRemoveTags:
+		return m.openBulkPrompt(bulkRemoveTags), true
+	case actGroup:
+		return m.openBulkPrompt(bulkGroup), true
+	case actExport:
+		return m.openBulkPrompt(bulkExport), true
+	}
+
+	if len(selection) == 0 {
+		return nil, false
+	}
+	switch action {
+	case actClearSelection:
+		if m.List.FilterState() != list.Unfiltered {
+			return nil, false
+		}
+		return m.setSelected(func(Item) bool { return false }), true
+	case actDelete:
+		m.IsConfirmingDelete = true
+		m.bulkDelete = selection
+		m.StatusMessage = ""
+		m.StatusType = StatusNone
+		return nil, true
+	case actCheck:
+		var conns []config.Connection
+		var cmds []tea.Cmd
+		for i, listItem := range m.List.Items() {
+			if item, ok := listItem.(Item); ok && item.Selected && !item.Checking {
+				item.Checking = true
+				cmds = append(cmds, m.List.SetItem(i, item))
+				conns = append(conns, item.Connection)
+			}
+		}
+		if len(conns) > 0 {
+			cmds = append(cmds, probeConnections(conns, m.probeParallel, false))
+		}
+		return tea.Batch(cmds...), true
+	case actBackground:
+		if Sessions == nil {
+			m.StatusMessage = "Background sessions are not available."
+			m.StatusType = StatusError
+			return nil, true
+		}
+		m.StatusMessage = fmt.Sprintf("Starting %d background session(s)...", len(selection))
+		m.StatusType = StatusNone
+		return startSessions(m.selectedConnections()), true
+	}
+	return nil, false
+}
+
+func (m Model) selectedConnections() []config.Connection {
+	var conns []config.Connection
+	for _, listItem := range m.List.Items() {
+		if item, ok := listItem.(Item); ok && item.Selected {
+			conns = append(conns, item.Connection)
+		}
+	}
+	return conns
+}
+
+// startSessions starts a background session for each of conns.
+func startSessions(conns []config.Connection) tea.Cmd {
+	return func() tea.Msg {
+		var msg bulkStartedMsg
+		for _, conn := range conns {
+			if _, err := Sessions.Start(conn); err != nil {
+				msg.errs = append(msg.errs, fmt.Sprintf("%s: %v", conn.Name, err))
+				continue
+			}
+			msg.started = append(msg.started, conn.Name)
+		}
+		return msg
+	}
+}
+
+// applySessionsStarted records usage of the connections whose sessions
+// were started and reports the outcome.
+func (m *Model) applySessionsStarted(msg bulkStartedMsg) tea.Cmd {
+	now := time.Now()
+	for _, name := range msg.started {
+		config.RecordUsage(name, now)
+	}
+	if len(msg.started) > 0 {
+		if err := config.Save(); err != nil {
+			msg.errs = append(msg.errs, fmt.Sprintf("saving usage: %v", err))
+		}
+	}
+	m.StatusMessage = fmt.Sprintf("Started %d background session(s); attach with b or gsm attach.", len(msg.started))
+	m.StatusType = StatusSuccess
+	if len(msg.errs) > 0 {
+		m.StatusMessage = fmt.Sprintf("Started %d, failed %d: %s", len(msg.started), len(msg.errs), strings.Join(msg.errs, "; "))
+		m.StatusType = StatusError
+	}
+	return fetchLiveSessions
+}
+
+// openBulkPrompt asks for the value of a bulk action on the targets.
+func (m *Model) openBulkPrompt(kind int) tea.Cmd {
+	names := m.targets()
+	if len(names) == 0 {
+		return nil
+	}
+	input := newInput()
+	input.CharLimit = 200
+	input.Width = 50
+	switch kind {
+	case bulkAddTags, bulkRemoveTags:
+		input.Placeholder = "tag1,tag2"
+	case bulkGroup:
+		input.Placeholder = "group name (empty to ungroup)"
+	case bulkExport:
+		input.SetValue(filepath.Join("~", "gsm-export-"+time.Now().Format("20060102-150405")+".txt"))
+	}
+	m.bulk = &bulkPrompt{kind: kind, names: names, input: input}
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.bulk.input.Focus()
+}
+
+// updateBulkPrompt handles input while a bulk prompt is open.
+func (m Model) updateBulkPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		m.bulk.input, cmd = m.bulk.input.Update(msg)
+		return m, cmd
+	}
+	switch key.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.bulk = nil
+		return m, tea.ClearScreen
+	case tea.KeyEnter:
+		return m.applyBulkPrompt()
+	}
+	var cmd tea.Cmd
+	m.bulk.input, cmd = m.bulk.input.Update(msg)
+	return m, cmd
+}
+
+// applyBulkPrompt runs the action of the open bulk prompt.
+func (m Model) applyBulkPrompt() (tea.Model, tea.Cmd) {
+	p := m.bulk
+	value := strings.TrimSpace(p.input.Value())
+	m.bulk = nil
+
+	if p.kind == bulkExport {
+		if err := exportConnections(value, p.names); err != nil {
+			m.StatusMessage = fmt.Sprintf("Error exporting: %v", err)
+			m.StatusType = StatusError
+		} else {
+			m.StatusMessage = fmt.Sprintf("Exported %d connection(s) to %s.", len(p.names), value)
+			m.StatusType = StatusSuccess
+		}
+		return m, tea.ClearScreen
+	}
+
+	tags := splitTags(value)
+	if (p.kind == bulkAddTags || p.kind == bulkRemoveTags) && len(tags) == 0 {
+		return m, tea.ClearScreen
+	}
+	for _, name := range p.names {
+		idx := config.IndexOfConnection(name)
+		if idx == -1 {
+			continue
+		}
+		conn := config.GetCurrent().Connections[idx]
+		switch p.kind {
+		case bulkAddTags:
+			for _, tag := range tags {
+				if !slices.Contains(conn.Tags, tag) {
+					conn.Tags = append(conn.Tags, tag)
+				}
+			}
+		case bulkRemoveTags:
+			conn.Tags = slices.DeleteFunc(conn.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+		case bulkGroup:
+			conn.Group = value
+		}
+		config.UpdateConnectionByIndex(idx, conn) //nolint:errcheck // idx was just looked up.
+	}
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving: %v", err)
+		m.StatusType = StatusError
+		return m, tea.ClearScreen
+	}
+	switch p.kind {
+	case bulkAddTags:
+		m.StatusMessage = fmt.Sprintf("Tagged %d connection(s) with %s.", len(p.names), strings.Join(tags, ", "))
+	case bulkRemoveTags:
+		m.StatusMessage = fmt.Sprintf("Removed %s from %d connection(s).", strings.Join(tags, ", "), len(p.names))
+	case bulkGroup:
+		m.StatusMessage = fmt.Sprintf("Moved %d connection(s) to group '%s'.", len(p.names), value)
+		if value == "" {
+			m.StatusMessage = fmt.Sprintf("Removed %d connection(s) from their group.", len(p.names))
+		}
+	}
+	m.StatusType = StatusSuccess
+	return m.reloaded(), tea.ClearScreen
+}
+
+// deleteSelected deletes the connections of a confirmed bulk delete.
+func (m Model) deleteSelected() (tea.Model, tea.Cmd) {
+	deleted := 0
+	for _, name := range m.bulkDelete {
+		if idx := config.IndexOfConnection(name); idx != -1 {
+			if err := config.DeleteConnectionByIndex(idx); err == nil {
+				deleted++
+			}
+		}
+	}
+	m.IsConfirmingDelete = false
+	m.bulkDelete = nil
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving after deletion: %v", err)
+		m.StatusType = StatusError
+	} else {
+		m.StatusMessage = fmt.Sprintf("Deleted %d connection(s).", deleted)
+		m.StatusType = StatusSuccess
+	}
+	return m.reloaded(), tea.ClearScreen
+}
+
+// exportConnections writes the connections called names to path in the
+// format gsm import -f reads.
+func exportConnections(path string, names []string) error {
+	if path == "" {
+		return fmt.Errorf("no file given")
+	}
+	if rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator)); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, rest)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# GSM export, %s. Import with: gsm import -f <file>\n", time.Now().Format(time.RFC3339))
+	for _, name := range names {
+		idx := config.IndexOfConnection(name)
+		if idx == -1 {
+			continue
+		}
+		conn := config.GetCurrent().Connections[idx]
+		fmt.Fprintln(&b, utils.FormatKeyLine(conn.Key, conn.Tags, conn.Name))
+	}
+	// The file holds secret keys.
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// splitTags splits a comma-separated list of tags, dropping empty ones.
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func (m Model) viewBulkPrompt() string {
+	var b strings.Builder
+	headerStyle := lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	var title, label string
+	switch m.bulk.kind {
+	case bulkAddTags:
+		title, label = "Add tags", "Tags:  "
+	case bulkRemoveTags:
+		title, label = "Remove tags", "Tags:  "
+	case bulkGroup:
+		title, label = "Move to group", "Group: "
+	case bulkExport:
+		title, label = "Export", "File:  "
+	}
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%s: %d connection(s) (Esc to Cancel)", title, len(m.bulk.names))) + "\n")
+	b.WriteString(label + m.bulk.input.View() + "\n\n")
+	hint := styles.hint
+	b.WriteString(hint.Render(strings.Join(m.bulk.names, ", ")) + "\n\n")
+	b.WriteString(hint.Render("(Enter to Apply)"))
+	return b.String()
+}
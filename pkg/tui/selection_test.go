@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/utils"
+)
+
+// useConfig points the config package at an empty file in a temporary
+// directory and adds conns to it.
+func useConfig(t *testing.T, conns ...config.Connection) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config.DefaultConfigFilePath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range conns {
+		config.AddConnection(conn)
+	}
+}
+
+func TestExportConnectionsRoundTrip(t *testing.T) {
+	conns := []config.Connection{
+		{Name: "web-1", Key: "RG9DNqW4WrbiIDlrYJawxj", Tags: []string{"prod", "web"}},
+		{Name: "lab box", Key: "AAAAqW4WrbiIDlrYJawxj"},
+	}
+	useConfig(t, conns...)
+
+	path := filepath.Join(t.TempDir(), "export.txt")
+	if err := exportConnections(path, []string{"web-1", "lab box", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []config.Connection
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyAndTags, name := utils.ParseKeyLine(line)
+		key, tags, _ := strings.Cut(keyAndTags, "#")
+		conn := config.Connection{Name: name, Key: key}
+		if tags != "" {
+			conn.Tags = strings.Split(tags, ",")
+		}
+		got = append(got, conn)
+	}
+	if len(got) != len(conns) {
+		t.Fatalf("exported %d connections, want %d:\n%s", len(got), len(conns), data)
+	}
+	for i, want := range conns {
+		if got[i].Name != want.Name || got[i].Key != want.Key || !slices.Equal(got[i].Tags, want.Tags) {
+			t.Errorf("connection %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SessionConnector opens interactive sessions while the TUI is suspended.
+type SessionConnector interface {
+	// Connect runs a session to conn on the terminal streams and returns
+	// when it ends. With background it attaches to a detachable session of
+	// the session daemon instead, starting one if needed.
+	Connect(conn config.Connection, background bool, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// Connector opens the sessions of the connect actions. Connecting is
+// unavailable while it is nil.
+var Connector SessionConnector
+
+// sessionEndedMsg reports a session opened from the TUI that has ended.
+type sessionEndedMsg struct {
+	name       string
+	background bool
+	duration   time.Duration
+	err        error
+}
+
+// sessionCommand runs a session through tea.Exec, which hands it the
+// terminal and gives it back to the TUI afterwards.
+type sessionCommand struct {
+	conn       config.Connection
+	background bool
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	duration   time.Duration
+	err        error
+}
+
+func (c *sessionCommand) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *sessionCommand) SetStdout(w io.Writer) { c.stdout = w }
+func (c *sessionCommand) SetStderr(w io.Writer) { c.stderr = w }
+
+func (c *sessionCommand) Run() error {
+	started := time.Now()
+	c.err = Connector.Connect(c.conn, c.background, c.stdin, c.stdout, c.stderr)
+	c.duration = time.Since(started)
+	return c.err
+}
+
+// connect suspends the TUI for a session to item. Filter, scroll position
+// and selection are untouched, so the TUI resumes where it was.
+func (m *Model) connect(item Item, background bool) tea.Cmd {
+	if Connector == nil {
+		m.StatusMessage = "Connecting isn't available here."
+		m.StatusType = StatusError
+		return nil
+	}
+	c := &sessionCommand{conn: item.Connection, background: background}
+	return tea.Exec(c, func(err error) tea.Msg {
+		if c.err != nil {
+			err = c.err
+		}
+		return sessionEndedMsg{name: item.Name, background: background, duration: c.duration, err: err}
+	})
+}
+
+// applySessionEnded shows a summary of the session in the status line and
+// picks up the usage it recorded.
+func (m *Model) applySessionEnded(msg sessionEndedMsg) tea.Cmd {
+	duration := msg.duration.Round(time.Second)
+	code := runner.ExitCodeOf(msg.err)
+	switch {
+	case msg.background && msg.err == nil:
+		m.StatusMessage = fmt.Sprintf("Left background session '%s' after %s.", msg.name, duration)
+		m.StatusType = StatusSuccess
+	case msg.err == nil:
+		m.StatusMessage = fmt.Sprintf("Session to '%s' closed after %s (exit status 0).", msg.name, duration)
+		m.StatusType = StatusSuccess
+	case code >= 0:
+		m.StatusMessage = fmt.Sprintf("Session to '%s' ended after %s with exit status %d.", msg.name, duration, code)
+		m.StatusType = StatusError
+	default:
+		m.StatusMessage = fmt.Sprintf("Session to '%s' failed after %s: %v", msg.name, duration, msg.err)
+		m.StatusType = StatusError
+	}
+	return tea.Batch(m.refreshItems(), tea.ClearScreen)
+}
@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listTitle is the title of the connection list, before the sort indicator.
+const listTitle = "GSM | GSocket Manager"
+
+// Fields the connection list can be sorted by, in the order the sort key
+// cycles through them. sortNone keeps the order of the config file.
+const (
+	sortNone    = ""
+	sortName    = "name"
+	sortUsage   = "usage"
+	sortLast    = "last"
+	sortCreated = "created"
+	sortStatus  = "status"
+)
+
+var sortFields = []string{sortNone, sortName, sortUsage, sortLast, sortCreated, sortStatus}
+
+// sortDescByDefault lists the fields whose natural order is descending:
+// most used, most recently seen and newest first.
+var sortDescByDefault = map[string]bool{sortUsage: true, sortLast: true, sortCreated: true}
+
+// sortOrder is how the connection list is sorted.
+type sortOrder struct {
+	by   string
+	desc bool
+}
+
+// next returns the order sorting by the next field, in its natural direction.
+func (o sortOrder) next() sortOrder {
+	i := 0
+	for j, f := range sortFields {
+		if f == o.by {
+			i = j
+		}
+	}
+	by := sortFields[(i+1)%len(sortFields)]
+	return sortOrder{by: by, desc: sortDescByDefault[by]}
+}
+
+// label describes o for the list title, e.g. "usage ↓".
+func (o sortOrder) label() string {
+	if o.by == sortNone {
+		return ""
+	}
+	if o.desc {
+		return o.by + " ↓"
+	}
+	return o.by + " ↑"
+}
+
+// statusRank orders check results from best to worst; unchecked comes last.
+var statusRank = map[config.ProbeStatus]int{
+	config.ProbeOnline:           0,
+	config.ProbeTimeout:          1,
+	config.ProbeRelayUnreachable: 2,
+	config.ProbeNoListener:       3,
+	config.ProbeError:            4,
+}
+
+func probeRank(item Item) int {
+	if item.LastProbe == nil {
+		return len(statusRank)
+	}
+	if rank, ok := statusRank[item.LastProbe.Status]; ok {
+		return rank
+	}
+	return len(statusRank)
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// compare returns a negative number if a sorts before b in ascending order,
+// a positive number if after and 0 if o doesn't tell them apart.
+func (o sortOrder) compare(a, b Item) int {
+	switch o.by {
+	case sortName:
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case sortUsage:
+		return a.Usage - b.Usage
+	case sortLast:
+		return timeOrZero(a.LastConnected).Compare(timeOrZero(b.LastConnected))
+	case sortCreated:
+		return timeOrZero(a.CreatedAt).Compare(timeOrZero(b.CreatedAt))
+	case sortStatus:
+		return probeRank(a) - probeRank(b)
+	}
+	return 0
+}
+
+// sortItems sorts items by o. Ties, and every item when o is sortNone, keep
+// the order they were given in.
+func sortItems(items []list.Item, o sortOrder) {
+	if o.by == sortNone {
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, aok := items[i].(Item)
+		b, bok := items[j].(Item)
+		if !aok || !bok {
+			return false
+		}
+		c := o.compare(a, b)
+		if o.desc {
+			c = -c
+		}
+		return c < 0
+	})
+}
+
+// refreshItems rebuilds the list from the config: the connections that
+// pass the tag filter and query, sorted by the current order. The highlighted
+// connection stays highlighted and per-item state such as live sessions and
+// selection marks is kept. The counts in the tag panel are updated too.
+func (m *Model) refreshItems() tea.Cmd {
+	m.List.Title = m.title()
+	current, hadCurrent := m.List.SelectedItem().(Item)
+
+	known := map[string]Item{}
+	for _, listItem := range m.List.Items() {
+		if item, ok := listItem.(Item); ok {
+			known[item.Name] = item
+		}
+	}
+	for _, item := range m.hidden {
+		known[item.Name] = item
+	}
+	var items []list.Item
+	m.hidden = nil
+	now := time.Now()
+	for _, conn := range config.GetCurrent().Connections {
+		item, ok := known[conn.Name]
+		if !ok {
+			item = Item{Connection: conn}
+		}
+		item.Connection = conn
+		if m.tagFilter.match(conn) && m.query.Match(conn, now) {
+			items = append(items, item)
+		} else {
+			item.Selected = false
+			m.hidden = append(m.hidden, item)
+		}
+	}
+	sortItems(items, m.sort)
+	if m.tagPanel != nil {
+		m.tagPanel.refresh()
+	}
+
+	cmd := m.List.SetItems(items)
+	if hadCurrent {
+		for i, listItem := range m.List.VisibleItems() {
+			if item, ok := listItem.(Item); ok && item.Name == current.Name {
+				m.List.Select(i)
+				break
+			}
+		}
+	}
+	return cmd
+}
+
+// title returns the list title with the sort order and tag filter.
+func (m Model) title() string {
+	title := listTitle
+	if label := m.sort.label(); label != "" {
+		title += " · sort: " + label
+	}
+	if label := m.tagFilter.label(); label != "" {
+		title += " · tags: " + label
+	}
+	if name, ok := m.activeCollection(); ok {
+		title += " · @" + name
+	} else if !m.query.Empty() {
+		title += " · " + queryPrefix + m.query.String()
+	}
+	return title
+}
+
+// setSort switches to o, re-sorts the list and saves the choice.
+func (m *Model) setSort(o sortOrder) tea.Cmd {
+	m.sort = o
+	cmd := m.refreshItems()
+	config.SetSort(o.by, o.desc)
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving sort order: %v", err)
+		m.StatusType = StatusError
+	}
+	return cmd
+}
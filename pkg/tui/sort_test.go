@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+func TestSortOrderNext(t *testing.T) {
+	want := []sortOrder{
+		{sortName, false},
+		{sortUsage, true},
+		{sortLast, true},
+		{sortCreated, true},
+		{sortStatus, false},
+		{sortNone, false},
+	}
+	// The direction is reset to the field's natural one on every step.
+	o := sortOrder{by: sortNone, desc: true}
+	for _, w := range want {
+		o = o.next()
+		if o != w {
+			t.Fatalf("next = %+v, want %+v", o, w)
+		}
+	}
+	if got := (sortOrder{by: "bogus"}).next(); got.by != sortName {
+		t.Errorf("next after an unknown field = %+v, want name", got)
+	}
+}
+
+func TestSortOrderLabel(t *testing.T) {
+	for o, want := range map[sortOrder]string{
+		{sortNone, false}:  "",
+		{sortNone, true}:   "",
+		{sortName, false}:  "name ↑",
+		{sortUsage, true}:  "usage ↓",
+		{sortStatus, true}: "status ↓",
+	} {
+		if got := o.label(); got != want {
+			t.Errorf("label of %+v = %q, want %q", o, got, want)
+		}
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	t1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2, t3 := t1.Add(time.Hour), t1.Add(2*time.Hour)
+	probe := func(status config.ProbeStatus) *config.ProbeRecord {
+		return &config.ProbeRecord{Status: status, At: t1}
+	}
+	conns := []config.Connection{
+		{Name: "beta", Usage: 5, LastConnected: &t2, CreatedAt: &t1, LastProbe: probe(config.ProbeOnline)},
+		{Name: "Alpha", Usage: 2, CreatedAt: &t3},
+		{Name: "gamma", Usage: 5, LastConnected: &t1, CreatedAt: &t2, LastProbe: probe(config.ProbeNoListener)},
+		{Name: "delta", LastConnected: &t2, CreatedAt: &t1, LastProbe: probe(config.ProbeOnline)},
+		{Name: "eps", Usage: 2, LastProbe: probe("weird")},
+	}
+
+	// Ties keep the given order in both directions; missing times sort as
+	// the oldest, and unknown or missing check results as the worst.
+	tests := []struct {
+		order sortOrder
+		want  []string
+	}{
+		{sortOrder{sortNone, false}, []string{"beta", "Alpha", "gamma", "delta", "eps"}},
+		{sortOrder{sortNone, true}, []string{"beta", "Alpha", "gamma", "delta", "eps"}},
+		{sortOrder{sortName, false}, []string{"Alpha", "beta", "delta", "eps", "gamma"}},
+		{sortOrder{sortName, true}, []string{"gamma", "eps", "delta", "beta", "Alpha"}},
+		{sortOrder{sortUsage, true}, []string{"beta", "gamma", "Alpha", "eps", "delta"}},
+		{sortOrder{sortUsage, false}, []string{"delta", "Alpha", "eps", "beta", "gamma"}},
+		{sortOrder{sortLast, true}, []string{"beta", "delta", "gamma", "Alpha", "eps"}},
+		{sortOrder{sortLast, false}, []string{"Alpha", "eps", "gamma", "beta", "delta"}},
+		{sortOrder{sortCreated, true}, []string{"Alpha", "gamma", "beta", "delta", "eps"}},
+		{sortOrder{sortCreated, false}, []string{"eps", "beta", "delta", "gamma", "Alpha"}},
+		{sortOrder{sortStatus, false}, []string{"beta", "delta", "gamma", "Alpha", "eps"}},
+		{sortOrder{sortStatus, true}, []string{"Alpha", "eps", "gamma", "beta", "delta"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s desc=%v", tt.order.by, tt.order.desc), func(t *testing.T) {
+			items := make([]list.Item, len(conns))
+			for i, conn := range conns {
+				items[i] = Item{Connection: conn}
+			}
+			sortItems(items, tt.order)
+			var got []string
+			for _, item := range items {
+				got = append(got, item.(Item).Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	useConfig(t,
+		config.Connection{Name: "web-2", Key: "k1", Usage: 1},
+		config.Connection{Name: "db-1", Key: "k2", Usage: 7},
+		config.Connection{Name: "web-1", Key: "k3", Usage: 3},
+	)
+	useKeymap(t, "default", nil)
+	m := NewModel(config.GetCurrent())
+
+	names := func() []string {
+		var got []string
+		for _, item := range m.List.Items() {
+			got = append(got, item.(Item).Name)
+		}
+		return got
+	}
+
+	// S does nothing while the list is in config order.
+	next, _ := m.Update(runeKey("S"))
+	m = next.(Model)
+	if m.sort.by != sortNone {
+		t.Fatalf("S set the order to %+v without a sort field", m.sort)
+	}
+
+	for _, step := range []struct {
+		key   string
+		title string
+		want  []string
+	}{
+		{"s", "sort: name ↑", []string{"db-1", "web-1", "web-2"}},
+		{"S", "sort: name ↓", []string{"web-2", "web-1", "db-1"}},
+		{"s", "sort: usage ↓", []string{"db-1", "web-1", "web-2"}},
+		{"S", "sort: usage ↑", []string{"web-2", "web-1", "db-1"}},
+	} {
+		next, _ := m.Update(runeKey(step.key))
+		m = next.(Model)
+		if !strings.Contains(m.List.Title, step.title) {
+			t.Errorf("after %s the title is %q, want %q in it", step.key, m.List.Title, step.title)
+		}
+		if got := names(); !slices.Equal(got, step.want) {
+			t.Errorf("after %s the order is %v, want %v", step.key, got, step.want)
+		}
+	}
+
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if s := config.GetCurrent().Settings; s.SortBy != sortUsage || s.SortDesc {
+		t.Errorf("saved sort %q desc %v, want usage ascending", s.SortBy, s.SortDesc)
+	}
+	m = NewModel(config.GetCurrent())
+	if got := names(); !slices.Equal(got, []string{"web-2", "web-1", "db-1"}) {
+		t.Errorf("a new model lists %v, want the saved order", got)
+	}
+}
@@ -0,0 +1,361 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tagPanelWidth is the width of the tag panel next to the list.
+const tagPanelWidth = 26
+
+// tagFilter limits the list to connections with some or all of its tags.
+type tagFilter struct {
+	tags []string
+	// all requires every tag (AND) instead of any of them (OR).
+	all bool
+}
+
+func (f tagFilter) match(conn config.Connection) bool {
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, tag := range f.tags {
+		has := slices.Contains(conn.Tags, tag)
+		if has && !f.all {
+			return true
+		}
+		if !has && f.all {
+			return false
+		}
+	}
+	return f.all
+}
+
+// label describes f for the list title, e.g. "prod & web".
+func (f tagFilter) label() string {
+	if f.all {
+		return strings.Join(f.tags, " & ")
+	}
+	return strings.Join(f.tags, " | ")
+}
+
+// mode names how f combines its tags.
+func (f tagFilter) mode() string {
+	if f.all {
+		return "AND"
+	}
+	return "OR"
+}
+
+// toggle adds tag to f or removes it.
+func (f *tagFilter) toggle(tag string) {
+	if i := slices.Index(f.tags, tag); i != -1 {
+		f.tags = slices.Delete(f.tags, i, i+1)
+		return
+	}
+	f.tags = append(f.tags, tag)
+}
+
+// tagCount is a tag and the number of connections that have it.
+type tagCount struct {
+	tag   string
+	count int
+}
+
+// countTags returns every tag used by conns with its count, sorted by tag.
+func countTags(conns []config.Connection) []tagCount {
+	counts := map[string]int{}
+	for _, conn := range conns {
+		for _, tag := range conn.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]tagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, tagCount{tag: tag, count: n})
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].tag) < strings.ToLower(tags[j].tag) })
+	return tags
+}
+
+// Actions of the tag panel that ask for a tag name first.
+const (
+	tagRename = iota
+	tagMerge
+)
+
+// tagPanel lists the saved collections and the tags next to the connection
+// list. The cursor runs over the collections first, then the tags.
+type tagPanel struct {
+	collections []collectionCount
+	tags        []tagCount
+	cursor      int
+	// focused sends keys to the panel instead of the list.
+	focused bool
+	// prompt, if set, asks for the target of a rename or merge.
+	prompt     *textinput.Model
+	promptKind int
+}
+
+// refresh recounts the collections and tags of the current config.
+func (p *tagPanel) refresh() {
+	cfg := config.GetCurrent()
+	p.collections = countCollections(cfg, time.Now())
+	p.tags = countTags(cfg.Connections)
+	if p.cursor >= p.rows() {
+		p.cursor = max(p.rows()-1, 0)
+	}
+}
+
+func (p *tagPanel) rows() int {
+	return len(p.collections) + len(p.tags)
+}
+
+// current returns the tag under the cursor.
+func (p *tagPanel) current() (string, bool) {
+	i := p.cursor - len(p.collections)
+	if i < 0 || i >= len(p.tags) {
+		return "", false
+	}
+	return p.tags[i].tag, true
+}
+
+// currentCollection returns the collection under the cursor.
+func (p *tagPanel) currentCollection() (string, bool) {
+	if p.cursor >= len(p.collections) {
+		return "", false
+	}
+	return p.collections[p.cursor].name, true
+}
+
+// toggleTagPanel opens the tag panel with focus, or closes it. The tag
+// filter stays in effect after closing.
+func (m *Model) toggleTagPanel() tea.Cmd {
+	if m.tagPanel != nil {
+		m.tagPanel = nil
+		return tea.ClearScreen
+	}
+	m.tagPanel = &tagPanel{focused: true}
+	m.tagPanel.refresh()
+	return tea.ClearScreen
+}
+
+// updateTagPanelKey handles keys while the tag panel has focus.
+func (m Model) updateTagPanelKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.tagPanel
+	if p.prompt != nil {
+		switch msg.Type {
+		case tea.KeyEsc, tea.KeyCtrlC:
+			p.prompt = nil
+			return m, nil
+		case tea.KeyEnter:
+			return m.applyTagPrompt()
+		}
+		input, cmd := p.prompt.Update(msg)
+		p.prompt = &input
+		return m, cmd
+	}
+
+	switch {
+	case msg.String() == forceQuitKey:
+		return m, tea.Quit
+	case keys.is(msg, actPanelClose):
+		return m, m.toggleTagPanel()
+	case keys.is(msg, actHelp):
+		return m, m.openHelp()
+	case keys.is(msg, actFocusPanel):
+		p.focused = false
+	case keys.is(msg, actUp):
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case keys.is(msg, actDown):
+		if p.cursor < p.rows()-1 {
+			p.cursor++
+		}
+	case keys.is(msg, actPanelPick):
+		if name, ok := p.currentCollection(); ok {
+			return m, m.applyCollection(name)
+		}
+		if tag, ok := p.current(); ok {
+			m.tagFilter.toggle(tag)
+			return m, m.refreshItems()
+		}
+	case keys.is(msg, actPanelMode):
+		m.tagFilter.all = !m.tagFilter.all
+		return m, m.refreshItems()
+	case keys.is(msg, actPanelClear):
+		m.tagFilter.tags = nil
+		return m, m.refreshItems()
+	case keys.is(msg, actPanelEdit):
+		if name, ok := p.currentCollection(); ok {
+			return m, m.editCollection(name)
+		}
+	case keys.is(msg, actPanelRename):
+		if tag, ok := p.current(); ok {
+			return m, m.openTagPrompt(tagRename, tag)
+		}
+	case keys.is(msg, actPanelMerge):
+		if len(m.tagFilter.tags) > 0 {
+			return m, m.openTagPrompt(tagMerge, "")
+		}
+		m.StatusMessage = "Pick the tags to merge with space first."
+		m.StatusType = StatusError
+	}
+	return m, nil
+}
+
+// openTagPrompt asks for the new name of a rename or the target of a merge.
+func (m *Model) openTagPrompt(kind int, value string) tea.Cmd {
+	input := newInput()
+	input.CharLimit = 100
+	input.Width = tagPanelWidth - 4
+	input.Prompt = "> "
+	input.SetValue(value)
+	m.tagPanel.prompt = &input
+	m.tagPanel.promptKind = kind
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	return m.tagPanel.prompt.Focus()
+}
+
+// applyTagPrompt renames the highlighted tag, or merges the filtered tags
+// into one, across all connections.
+func (m Model) applyTagPrompt() (tea.Model, tea.Cmd) {
+	p := m.tagPanel
+	target := strings.TrimSpace(p.prompt.Value())
+	p.prompt = nil
+	if target == "" || strings.Contains(target, ",") {
+		m.StatusMessage = "A tag must be non-empty and can't contain commas."
+		m.StatusType = StatusError
+		return m, nil
+	}
+
+	var sources []string
+	if p.promptKind == tagRename {
+		tag, ok := p.current()
+		if !ok {
+			return m, nil
+		}
+		sources = []string{tag}
+	} else {
+		sources = slices.Clone(m.tagFilter.tags)
+	}
+	changed := 0
+	for _, tag := range sources {
+		changed += config.RenameTag(tag, target)
+	}
+	if err := config.Save(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Error saving: %v", err)
+		m.StatusType = StatusError
+		return m, nil
+	}
+
+	// Keep filtering by the tags under their new name.
+	var filter []string
+	for _, tag := range m.tagFilter.tags {
+		if slices.Contains(sources, tag) {
+			tag = target
+		}
+		if !slices.Contains(filter, tag) {
+			filter = append(filter, tag)
+		}
+	}
+	m.tagFilter.tags = filter
+
+	if p.promptKind == tagRename {
+		m.StatusMessage = fmt.Sprintf("Renamed tag '%s' to '%s' on %d connection(s).", sources[0], target, changed)
+	} else {
+		m.StatusMessage = fmt.Sprintf("Merged %s into '%s' on %d connection(s).", strings.Join(sources, ", "), target, changed)
+	}
+	m.StatusType = StatusSuccess
+	p.refresh()
+	for i, tc := range p.tags {
+		if tc.tag == target {
+			p.cursor = len(p.collections) + i
+		}
+	}
+	return m, m.refreshItems()
+}
+
+// viewTagPanel renders the tag panel with the given height.
+func (m Model) viewTagPanel(height int) string {
+	p := m.tagPanel
+	headerStyle := lipgloss.NewStyle().Bold(true)
+	dimStyle := styles.subtle
+	cursorStyle := styles.selected
+	if !p.focused {
+		cursorStyle = styles.inactive
+	}
+	nameWidth := tagPanelWidth - 10
+	row := func(mark, name, count string, index int) string {
+		if len(name) > nameWidth {
+			name = name[:nameWidth-1] + "…"
+		}
+		line := fmt.Sprintf("%s %-*s %3s", mark, nameWidth, name, count)
+		if index == p.cursor {
+			return cursorStyle.Render(line)
+		}
+		return line
+	}
+
+	// Build every line first and note where the cursor is, then scroll so
+	// that it stays visible.
+	lines := []string{headerStyle.Render("Collections")}
+	cursorLine := 0
+	if len(p.collections) == 0 {
+		lines = append(lines, dimStyle.Render("Save a query with ctrl+s."))
+	}
+	active, _ := m.activeCollection()
+	for i, cc := range p.collections {
+		mark, count := "( )", fmt.Sprint(cc.count)
+		if cc.name == active {
+			mark = "(•)"
+		}
+		if cc.invalid {
+			count = "!"
+		}
+		if i == p.cursor {
+			cursorLine = len(lines)
+		}
+		lines = append(lines, row(mark, "@"+cc.name, count, i))
+	}
+
+	lines = append(lines, "", headerStyle.Render("Tags")+dimStyle.Render(" ("+m.tagFilter.mode()+")"))
+	if len(p.tags) == 0 {
+		lines = append(lines, dimStyle.Render("No tags yet."))
+	}
+	for i, tc := range p.tags {
+		mark := "[ ]"
+		if slices.Contains(m.tagFilter.tags, tc.tag) {
+			mark = "[x]"
+		}
+		index := len(p.collections) + i
+		if index == p.cursor {
+			cursorLine = len(lines)
+		}
+		lines = append(lines, row(mark, tc.tag, fmt.Sprint(tc.count), index))
+	}
+
+	var promptLines []string
+	if p.prompt != nil {
+		label := "Rename to:"
+		if p.promptKind == tagMerge {
+			label = "Merge into:"
+		}
+		promptLines = []string{"", dimStyle.Render(label), p.prompt.View()}
+	}
+	rows := max(height-len(promptLines), 1)
+	first := max(cursorLine-rows+1, 0)
+	last := min(first+rows, len(lines))
+	lines = append(lines[first:last], promptLines...)
+	return lipgloss.NewStyle().Width(tagPanelWidth).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/theme"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// styleSet holds the styles the TUI draws with, derived from a theme.
+type styleSet struct {
+	text   lipgloss.Style
+	muted  lipgloss.Style
+	subtle lipgloss.Style
+	hint   lipgloss.Style
+	border lipgloss.Style
+
+	title lipgloss.Style
+	bar   lipgloss.Style
+
+	selected     lipgloss.Style
+	selectedDesc lipgloss.Style
+	inactive     lipgloss.Style
+	cursor       lipgloss.Style
+
+	success lipgloss.Style
+	danger  lipgloss.Style
+	warning lipgloss.Style
+
+	online  lipgloss.Style
+	unknown lipgloss.Style
+	probe   map[config.ProbeStatus]lipgloss.Style
+	// plainStatus is set when the theme has no status colors, so that
+	// statuses are told apart by their glyph instead.
+	plainStatus bool
+}
+
+// styles are the styles of the current theme.
+var styles = newStyleSet(theme.Dark)
+
+// SetTheme switches the TUI to t. It must be called before NewModel.
+func SetTheme(t theme.Theme) {
+	styles = newStyleSet(t)
+}
+
+// fg returns a style with text color c, or the terminal's own for "".
+func fg(c string) lipgloss.Style {
+	if c == "" {
+		return lipgloss.NewStyle()
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+}
+
+// dim is fg for secondary text, which is drawn faint without a color.
+func dim(c string) lipgloss.Style {
+	if c == "" {
+		return lipgloss.NewStyle().Faint(true)
+	}
+	return fg(c)
+}
+
+// block returns text color fgc on background bgc, or reversed text when the
+// theme has no background color.
+func block(fgc, bgc string) lipgloss.Style {
+	if bgc == "" {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	return fg(fgc).Background(lipgloss.Color(bgc))
+}
+
+func newStyleSet(t theme.Theme) styleSet {
+	s := styleSet{
+		text:   fg(t.Text),
+		muted:  fg(t.Muted),
+		subtle: dim(t.Subtle),
+		hint:   dim(t.Faint),
+		border: dim(t.Border),
+
+		title: block(t.TitleText, t.Title).Bold(true),
+		bar:   dim(t.Subtle),
+
+		selected:     block(t.SelectionText, t.Selection).Bold(true),
+		selectedDesc: block(t.SelectionDesc, t.Selection),
+		inactive:     block(t.InactiveText, t.Inactive),
+		cursor:       fg(t.Cursor),
+
+		success: fg(t.Success),
+		danger:  fg(t.Error),
+		warning: fg(t.Warning),
+
+		online:  fg(t.Online),
+		unknown: dim(t.Faint),
+		probe: map[config.ProbeStatus]lipgloss.Style{
+			config.ProbeOnline:           fg(t.Online),
+			config.ProbeNoListener:       fg(t.NoListener),
+			config.ProbeRelayUnreachable: fg(t.RelayUnreachable),
+			config.ProbeTimeout:          fg(t.Timeout),
+			config.ProbeError:            fg(t.ProbeError),
+		},
+		plainStatus: t.Online == "",
+	}
+	if t.Bar != "" {
+		s.bar = s.bar.Background(lipgloss.Color(t.Bar))
+	}
+	if t.Inactive == "" {
+		// Reversed text is kept for the focused cursor.
+		s.inactive = lipgloss.NewStyle().Underline(true)
+	}
+	return s
+}
+
+// newInput returns a text input drawn in the current theme.
+func newInput() textinput.Model {
+	input := textinput.New()
+	input.PlaceholderStyle = styles.hint
+	input.CompletionStyle = styles.hint
+	return input
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/theme"
+)
+
+// useTheme makes t the theme of the TUI for the test.
+func useTheme(t *testing.T, th theme.Theme) {
+	t.Helper()
+	saved := styles
+	t.Cleanup(func() { styles = saved })
+	SetTheme(th)
+}
+
+func TestNewStyleSetColors(t *testing.T) {
+	s := newStyleSet(theme.Dark)
+	for name, tt := range map[string]struct {
+		style  lipgloss.Style
+		fg, bg string
+	}{
+		"title":    {s.title, theme.Dark.TitleText, theme.Dark.Title},
+		"selected": {s.selected, theme.Dark.SelectionText, theme.Dark.Selection},
+		"inactive": {s.inactive, theme.Dark.InactiveText, theme.Dark.Inactive},
+		"bar":      {s.bar, theme.Dark.Subtle, theme.Dark.Bar},
+		"danger":   {s.danger, theme.Dark.Error, ""},
+		"timeout":  {s.probe[config.ProbeTimeout], theme.Dark.Timeout, ""},
+	} {
+		if got := tt.style.GetForeground(); got != lipgloss.Color(tt.fg) {
+			t.Errorf("%s foreground = %v, want %s", name, got, tt.fg)
+		}
+		if tt.bg == "" {
+			if _, ok := tt.style.GetBackground().(lipgloss.NoColor); !ok {
+				t.Errorf("%s has background %v, want none", name, tt.style.GetBackground())
+			}
+		} else if got := tt.style.GetBackground(); got != lipgloss.Color(tt.bg) {
+			t.Errorf("%s background = %v, want %s", name, got, tt.bg)
+		}
+	}
+	if s.plainStatus {
+		t.Error("dark theme draws statuses without colors")
+	}
+}
+
+func TestNewStyleSetWithoutColors(t *testing.T) {
+	s := newStyleSet(theme.Mono)
+	if !s.title.GetReverse() || !s.selected.GetReverse() {
+		t.Error("title and selection aren't reversed without background colors")
+	}
+	if s.inactive.GetReverse() || !s.inactive.GetUnderline() {
+		t.Error("the unfocused cursor isn't underlined without background colors")
+	}
+	if !s.hint.GetFaint() || !s.subtle.GetFaint() {
+		t.Error("secondary text isn't faint without colors")
+	}
+	if _, ok := s.text.GetForeground().(lipgloss.NoColor); !ok {
+		t.Errorf("text color = %v, want the terminal's own", s.text.GetForeground())
+	}
+	if !s.plainStatus {
+		t.Error("mono theme doesn't tell statuses apart by glyph")
+	}
+}
+
+func TestStatusDotGlyphs(t *testing.T) {
+	probed := func(status config.ProbeStatus) Item {
+		return Item{Connection: config.Connection{LastProbe: &config.ProbeRecord{Status: status, At: time.Now()}}}
+	}
+	tests := []struct {
+		item       Item
+		dark, mono string
+	}{
+		{Item{}, "○", "○"},
+		{Item{Checking: true}, "◌", "◌"},
+		{probed(config.ProbeOnline), "●", "●"},
+		{probed(config.ProbeNoListener), "●", "✕"},
+		{probed(config.ProbeTimeout), "●", "◔"},
+		{probed("weird"), "●", "●"},
+	}
+	// Tests run without a terminal, so rendering leaves only the glyph.
+	for _, th := range []theme.Theme{theme.Dark, theme.Mono} {
+		useTheme(t, th)
+		for _, tt := range tests {
+			want := tt.dark
+			if th.Name == theme.Mono.Name {
+				want = tt.mono
+			}
+			if got := statusDot(tt.item); got != want {
+				t.Errorf("%s: statusDot(%+v) = %q, want %q", th.Name, tt.item.LastProbe, got, want)
+			}
+		}
+	}
+}
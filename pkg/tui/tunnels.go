@@ -0,0 +1,244 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/tunnel"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TunnelController starts and stops managed tunnels.
+type TunnelController interface {
+	Up(conn config.Connection) error
+	Down(name string) error
+}
+
+// Tunnels runs the actions of the tunnels view. The view is unavailable
+// while it is nil.
+var Tunnels TunnelController
+
+// tunnelRefreshInterval is how often tunnel state is re-read while the
+// tunnels view is open.
+const tunnelRefreshInterval = 2 * time.Second
+
+type tunnelTickMsg struct{}
+
+type tunnelToggledMsg struct {
+	name string
+	up   bool
+	err  error
+}
+
+func scheduleTunnelStates() tea.Cmd {
+	return tea.Tick(tunnelRefreshInterval, func(time.Time) tea.Msg { return tunnelTickMsg{} })
+}
+
+// tunnelRow is a connection that can be tunneled and its tunnel, if running.
+type tunnelRow struct {
+	conn  config.Connection
+	state *tunnel.State
+}
+
+// tunnelsView lists connections with a local port and running tunnels.
+type tunnelsView struct {
+	rows   []tunnelRow
+	cursor int
+	// busy is the name of the tunnel being started or stopped.
+	busy string
+}
+
+// refresh re-reads the tunnel state files.
+func (v *tunnelsView) refresh() {
+	states, _ := tunnel.List()
+	v.setStates(config.GetCurrent().Connections, states)
+}
+
+// setStates rebuilds the rows from the configured connections and states.
+func (v *tunnelsView) setStates(conns []config.Connection, states []tunnel.State) {
+	byName := map[string]*tunnel.State{}
+	for i := range states {
+		byName[states[i].Name] = &states[i]
+	}
+	v.rows = v.rows[:0]
+	for _, conn := range conns {
+		if conn.LocalPort > 0 || byName[conn.Name] != nil {
+			v.rows = append(v.rows, tunnelRow{conn: conn, state: byName[conn.Name]})
+			delete(byName, conn.Name)
+		}
+	}
+	// Tunnels of connections that were deleted or renamed since.
+	for i := range states {
+		if byName[states[i].Name] != nil {
+			v.rows = append(v.rows, tunnelRow{conn: config.Connection{Name: states[i].Name}, state: &states[i]})
+		}
+	}
+	if v.cursor >= len(v.rows) {
+		v.cursor = max(len(v.rows)-1, 0)
+	}
+}
+
+// toggleTunnel starts the tunnel of row if it isn't running and stops it
+// otherwise.
+func toggleTunnel(row tunnelRow) tea.Cmd {
+	up := row.state == nil || row.state.Status() == tunnel.StatusDead
+	return func() tea.Msg {
+		var err error
+		if up {
+			err = Tunnels.Up(row.conn)
+		} else {
+			err = Tunnels.Down(row.conn.Name)
+		}
+		return tunnelToggledMsg{name: row.conn.Name, up: up, err: err}
+	}
+}
+
+// openTunnels shows the tunnels view and starts polling tunnel state.
+func (m *Model) openTunnels() tea.Cmd {
+	m.tunnels = &tunnelsView{}
+	m.tunnels.refresh()
+	m.StatusMessage = ""
+	m.StatusType = StatusNone
+	if m.tunnelPolling {
+		return tea.ClearScreen
+	}
+	m.tunnelPolling = true
+	return tea.Batch(tea.ClearScreen, scheduleTunnelStates())
+}
+
+// updateTunnels handles the tunnels view's background messages.
+func (m *Model) updateTunnels(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tunnelTickMsg:
+		if m.tunnels == nil {
+			m.tunnelPolling = false
+			return nil
+		}
+		m.tunnels.refresh()
+		return scheduleTunnelStates()
+	case tunnelToggledMsg:
+		if m.tunnels == nil {
+			return nil
+		}
+		m.tunnels.busy = ""
+		m.tunnels.refresh()
+		switch {
+		case msg.err != nil:
+			m.StatusMessage = fmt.Sprintf("Tunnel '%s': %v", msg.name, msg.err)
+			m.StatusType = StatusError
+		case msg.up:
+			m.StatusMessage = fmt.Sprintf("Tunnel '%s' started.", msg.name)
+			m.StatusType = StatusSuccess
+		default:
+			m.StatusMessage = fmt.Sprintf("Tunnel '%s' stopped.", msg.name)
+			m.StatusType = StatusSuccess
+		}
+	}
+	return nil
+}
+
+func (m Model) updateTunnelsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.tunnels
+	switch {
+	case msg.String() == forceQuitKey:
+		return m, tea.Quit
+	case keys.is(msg, actTunnelClose):
+		m.tunnels = nil
+		return m, tea.ClearScreen
+	case keys.is(msg, actHelp):
+		return m, m.openHelp()
+	case keys.is(msg, actUp):
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case keys.is(msg, actDown):
+		if v.cursor < len(v.rows)-1 {
+			v.cursor++
+		}
+	case keys.is(msg, actTunnelToggle):
+		if v.busy != "" || v.cursor >= len(v.rows) {
+			return m, nil
+		}
+		row := v.rows[v.cursor]
+		if row.state == nil && row.conn.LocalPort <= 0 {
+			m.StatusMessage = fmt.Sprintf("'%s' has no local_port to listen on.", row.conn.Name)
+			m.StatusType = StatusError
+			return m, nil
+		}
+		v.busy = row.conn.Name
+		m.StatusMessage = ""
+		m.StatusType = StatusNone
+		return m, toggleTunnel(row)
+	}
+	return m, nil
+}
+
+func (m Model) viewTunnels() string {
+	v := m.tunnels
+	var b strings.Builder
+	titleStyle := styles.title.Padding(0, 1)
+	b.WriteString(titleStyle.Render("GSM | Tunnels") + "\n\n")
+
+	if len(v.rows) == 0 {
+		hint := styles.subtle
+		b.WriteString(hint.Render("No tunnels. Set \"local_port\" on a connection to manage its tunnel here.") + "\n")
+	}
+
+	nameWidth := 4
+	for _, row := range v.rows {
+		nameWidth = max(nameWidth, len(row.conn.Name))
+	}
+	selectedStyle := styles.selected
+	for i, row := range v.rows {
+		status, detail := "down", ""
+		port := row.conn.LocalPort
+		if row.state != nil {
+			status = row.state.Status()
+			port = row.state.Port
+			if status == tunnel.StatusUp {
+				detail = "up " + row.state.Uptime().Round(time.Second).String()
+			}
+			detail += fmt.Sprintf("  restarts %d", row.state.Restarts)
+		}
+		if v.busy == row.conn.Name {
+			status = "working..."
+		}
+		line := fmt.Sprintf("%-*s  localhost:%-5d  %-10s %s", nameWidth, row.conn.Name, port, status, strings.TrimSpace(detail))
+		if i == v.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(tunnelDot(row) + " " + line + "\n")
+	}
+
+	if m.StatusMessage != "" {
+		statusStyle := styles.success.Bold(true)
+		if m.StatusType == StatusError {
+			statusStyle = styles.danger.Bold(true)
+		}
+		b.WriteString("\n" + statusStyle.Render(m.StatusMessage) + "\n")
+	}
+	footerStyle := styles.bar.Padding(0, 1)
+	b.WriteString("\n" + footerStyle.Render(keys.footer("nav=up,down", actTunnelToggle, actTunnelClose, actHelp)))
+	return b.String()
+}
+
+// tunnelDot renders the state of a tunnel as a colored dot.
+func tunnelDot(row tunnelRow) string {
+	style, glyph := styles.unknown, "○"
+	if row.state != nil {
+		switch row.state.Status() {
+		case tunnel.StatusUp:
+			style, glyph = styles.online, "●"
+		case tunnel.StatusStarting, tunnel.StatusRestarting:
+			style, glyph = styles.warning, "◐"
+		case tunnel.StatusDead:
+			style, glyph = styles.danger, "✕"
+		}
+	}
+	if !styles.plainStatus {
+		glyph = "●"
+	}
+	return style.Render(glyph)
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given id exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// terminate asks the process to shut down cleanly.
+func terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
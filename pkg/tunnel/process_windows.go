@@ -0,0 +1,37 @@
+//go:build windows
+
+package tunnel
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given id exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	const processQueryLimitedInformation = 0x1000
+	const stillActive = 259
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// terminate stops the process. Windows has no SIGTERM, so the supervisor
+// can't clean up after itself; Stop removes its state instead.
+func terminate(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -0,0 +1,164 @@
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+// readyPoll is how often a new gs-netcat's port is tried until it accepts.
+const readyPoll = 100 * time.Millisecond
+
+// readyGrace is how long a new gs-netcat may take to accept connections on
+// its port. One still running after that counts as up anyway.
+var readyGrace = 3 * time.Second
+
+// stopGrace is how long gs-netcat gets to exit before it is killed.
+const stopGrace = 2 * time.Second
+
+// Supervisor keeps the tunnel for one connection running.
+type Supervisor struct {
+	Backend runner.Backend
+	Conn    config.Connection
+	Port    int
+	// Output receives gs-netcat's output.
+	Output io.Writer
+	// Policy sets the delays between restarts. Once gs-netcat exited its
+	// MaxAttempts times in a row without staying up for StableAfter, the
+	// supervisor gives up.
+	Policy config.ReconnectPolicy
+	// Hooks run once when the tunnel comes up and once when it stops, with
+	// their output on Output.
+	Hooks config.Hooks
+}
+
+// Run starts gs-netcat and restarts it whenever it exits, until a signal
+// arrives on stop. It keeps the tunnel's state file up to date and removes it
+// on return.
+func (s *Supervisor) Run(stop <-chan os.Signal) error {
+	name := s.Conn.Name
+	state := State{Name: name, Port: s.Port, SupervisorPID: os.Getpid(), Started: time.Now()}
+	if err := Save(state); err != nil {
+		return err
+	}
+	defer Remove(name) //nolint:errcheck
+
+	spec := runner.Spec{Connection: s.Conn, Stdout: s.Output, Stderr: s.Output, Hooks: s.Hooks}
+	return runner.WithHooks(spec, func() (int, error) {
+		return s.supervise(stop, state)
+	})
+}
+
+// supervise is the restart loop of Run. It returns gs-netcat's last exit code.
+func (s *Supervisor) supervise(stop <-chan os.Signal, state State) (int, error) {
+	name := s.Conn.Name
+	attempt := 0
+	for {
+		r := s.Backend.New(runner.Spec{
+			Connection: s.Conn,
+			Args:       runner.TunnelArgs(s.Conn, s.Port),
+			Stdout:     s.Output,
+			Stderr:     s.Output,
+		})
+		if err := r.Start(); err != nil {
+			// gs-netcat itself is missing or broken; restarting won't help.
+			return -1, err
+		}
+		state.Since = time.Now()
+		state.PID = 0
+		if p, ok := r.(runner.Process); ok {
+			state.PID = p.PID()
+		}
+		saveOrLog(state)
+		log.Printf("Tunnel %s: started gs-netcat (pid %d), waiting for port %d.", name, state.PID, s.Port)
+
+		done := make(chan error, 1)
+		go func() { done <- r.Wait() }()
+		poll := time.NewTicker(readyPoll)
+		grace := time.NewTimer(readyGrace)
+		var err error
+		for exited := false; !exited; {
+			select {
+			case sig := <-stop:
+				poll.Stop()
+				grace.Stop()
+				log.Printf("Tunnel %s: %v received, shutting down.", name, sig)
+				r.Signal(sig) //nolint:errcheck
+				select {
+				case <-done:
+				case <-time.After(stopGrace):
+					r.Signal(os.Kill) //nolint:errcheck
+					<-done
+				}
+				return r.ExitCode(), nil
+			case err = <-done:
+				exited = true
+			case <-poll.C:
+				if !state.Running && portOpen(s.Port) {
+					state.Running = true
+					saveOrLog(state)
+					log.Printf("Tunnel %s: listening on port %d.", name, s.Port)
+				}
+			case <-grace.C:
+				if !state.Running {
+					state.Running = true
+					saveOrLog(state)
+					log.Printf("Tunnel %s: port %d does not accept connections yet after %v, treating the tunnel as up.", name, s.Port, readyGrace)
+				}
+			}
+		}
+		poll.Stop()
+		grace.Stop()
+
+		state.Running = false
+		state.PID = 0
+		state.LastExit = fmt.Sprintf("%s: exit code %d", time.Now().Format(time.RFC3339), r.ExitCode())
+		if err != nil {
+			state.LastExit = fmt.Sprintf("%s: %v", time.Now().Format(time.RFC3339), err)
+		}
+
+		if time.Since(state.Since) >= runner.StableAfter(s.Policy) {
+			attempt = 0
+		}
+		attempt++
+		state.Since = time.Time{}
+		saveOrLog(state)
+		if maxAttempts := runner.MaxAttempts(s.Policy); attempt > maxAttempts {
+			log.Printf("Tunnel %s: gs-netcat exited (%s), giving up after %d failed restarts.", name, state.LastExit, maxAttempts)
+			return r.ExitCode(), fmt.Errorf("gs-netcat exited %d times in a row", attempt)
+		}
+		delay := runner.Backoff(s.Policy, attempt)
+		log.Printf("Tunnel %s: gs-netcat exited (%s), restarting in %v.", name, state.LastExit, delay)
+
+		select {
+		case sig := <-stop:
+			log.Printf("Tunnel %s: %v received, shutting down.", name, sig)
+			return r.ExitCode(), nil
+		case <-time.After(delay):
+		}
+		state.Restarts++
+	}
+}
+
+// portOpen reports whether something accepts connections on the local port.
+func portOpen(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), readyPoll)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func saveOrLog(state State) {
+	if err := Save(state); err != nil {
+		log.Printf("Tunnel %s: error saving state: %v", state.Name, err)
+	}
+}
@@ -0,0 +1,207 @@
+package tunnel
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+	"github.com/NumeXx/gsm/pkg/runner"
+)
+
+// useTunnelDir keeps tunnel state in a temporary config directory.
+func useTunnelDir(t *testing.T) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+}
+
+// freePort returns a local port nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// listenScript plays gs-netcat -p: it listens on the tunnel's port once
+// listen is closed and runs until it gets a signal.
+func listenScript(listen <-chan struct{}) runner.FakeScript {
+	return func(spec runner.Spec, signals <-chan os.Signal) int {
+		select {
+		case <-listen:
+		case <-signals:
+			return 0
+		}
+		port := spec.Args[len(spec.Args)-1]
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+		if err != nil {
+			return 1
+		}
+		defer l.Close()
+		<-signals
+		return 0
+	}
+}
+
+// runSupervisor runs sup until stop fires and returns Run's result.
+func runSupervisor(sup *Supervisor, stop chan os.Signal) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- sup.Run(stop) }()
+	return result
+}
+
+// waitState waits until the state of the tunnel called name satisfies ok.
+func waitState(t *testing.T, name string, ok func(*State) bool) *State {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, err := Load(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != nil && ok(state) {
+			return state
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tunnel state %+v did not reach the expected state", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSupervisorRunningOnceListening(t *testing.T) {
+	useTunnelDir(t)
+	listen := make(chan struct{})
+	sup := &Supervisor{
+		Backend: runner.NewFakeBackend(listenScript(listen)),
+		Conn:    config.Connection{Name: "proxy", Key: "k1"},
+		Port:    freePort(t),
+		Output:  io.Discard,
+	}
+	stop := make(chan os.Signal, 1)
+	result := runSupervisor(sup, stop)
+
+	state := waitState(t, "proxy", func(s *State) bool { return !s.Since.IsZero() })
+	if state.Running || state.Status() != StatusStarting {
+		t.Fatalf("state before the port is open = %+v (%s), want starting", state, state.Status())
+	}
+	time.Sleep(5 * readyPoll)
+	if state, _ := Load("proxy"); state.Running {
+		t.Fatal("tunnel marked running before its port accepts connections")
+	}
+
+	close(listen)
+	state = waitState(t, "proxy", func(s *State) bool { return s.Running })
+	if state.Status() != StatusUp || state.Port != sup.Port {
+		t.Errorf("state = %+v (%s), want up on port %d", state, state.Status(), sup.Port)
+	}
+
+	stop <- os.Interrupt
+	if err := <-result; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if state, _ := Load("proxy"); state != nil {
+		t.Errorf("state %+v left behind after shutdown", state)
+	}
+}
+
+func TestSupervisorRunningAfterGrace(t *testing.T) {
+	useTunnelDir(t)
+	saved := readyGrace
+	t.Cleanup(func() { readyGrace = saved })
+	readyGrace = 200 * time.Millisecond
+
+	// The script never listens; a gs-netcat still alive after the grace
+	// period counts as up.
+	sup := &Supervisor{
+		Backend: runner.NewFakeBackend(listenScript(nil)),
+		Conn:    config.Connection{Name: "proxy", Key: "k1"},
+		Port:    freePort(t),
+		Output:  io.Discard,
+	}
+	stop := make(chan os.Signal, 1)
+	result := runSupervisor(sup, stop)
+	waitState(t, "proxy", func(s *State) bool { return s.Running })
+
+	stop <- os.Interrupt
+	if err := <-result; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestSupervisorRestarts(t *testing.T) {
+	useTunnelDir(t)
+	listen := make(chan struct{})
+	close(listen)
+	var runs atomic.Int32
+	sup := &Supervisor{
+		Backend: runner.NewFakeBackend(func(spec runner.Spec, signals <-chan os.Signal) int {
+			if runs.Add(1) == 1 {
+				return 1
+			}
+			return listenScript(listen)(spec, signals)
+		}),
+		Conn:   config.Connection{Name: "proxy", Key: "k1"},
+		Port:   freePort(t),
+		Output: io.Discard,
+		Policy: config.ReconnectPolicy{InitialDelay: config.Duration(10 * time.Millisecond)},
+	}
+	stop := make(chan os.Signal, 1)
+	result := runSupervisor(sup, stop)
+
+	state := waitState(t, "proxy", func(s *State) bool { return s.Running })
+	if state.Restarts != 1 || !strings.HasSuffix(state.LastExit, "exit status 1") {
+		t.Errorf("state = %+v, want one restart after exit code 1", state)
+	}
+
+	stop <- os.Interrupt
+	if err := <-result; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	useTunnelDir(t)
+	backend := runner.NewFakeBackend(runner.FakeExit(1))
+	sup := &Supervisor{
+		Backend: backend,
+		Conn:    config.Connection{Name: "proxy", Key: "k1"},
+		Port:    freePort(t),
+		Output:  io.Discard,
+		Policy: config.ReconnectPolicy{
+			MaxAttempts:  2,
+			InitialDelay: config.Duration(time.Millisecond),
+		},
+	}
+	select {
+	case err := <-runSupervisor(sup, make(chan os.Signal)):
+		if err == nil {
+			t.Fatal("Run succeeded, want it to give up")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor kept restarting a failing gs-netcat")
+	}
+
+	specs := backend.Specs()
+	if len(specs) != 3 {
+		t.Errorf("gs-netcat started %d times, want 3 (the first run and 2 restarts)", len(specs))
+	}
+	wantArgs := []string{"-s", "k1", "-p", strconv.Itoa(sup.Port)}
+	if got := strings.Join(specs[0].Args, " "); got != strings.Join(wantArgs, " ") {
+		t.Errorf("gs-netcat args = %q, want %q", got, wantArgs)
+	}
+	if state, _ := Load("proxy"); state != nil {
+		t.Errorf("state %+v left behind after giving up", state)
+	}
+}
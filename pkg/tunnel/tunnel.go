@@ -0,0 +1,191 @@
+// Package tunnel keeps port-forward and SOCKS style connections running in
+// the background. Every tunnel has its own supervisor process that runs
+// gs-netcat listening on a local port, restarts it when it dies and keeps a
+// state file under ~/.gsm/tunnels up to date.
+package tunnel
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// DirName is the directory under the config dir holding tunnel state.
+const DirName = "tunnels"
+
+// Tunnel statuses as reported by State.Status.
+const (
+	StatusUp         = "up"
+	StatusStarting   = "starting"
+	StatusRestarting = "restarting"
+	StatusDead       = "dead"
+)
+
+// State describes a running tunnel. Its supervisor writes it whenever
+// something changes and removes it when the tunnel is brought down.
+type State struct {
+	Name          string `json:"name"`
+	Port          int    `json:"port"`
+	SupervisorPID int    `json:"supervisor_pid"`
+	// Running is set once gs-netcat accepts connections on Port. It is false
+	// while gs-netcat starts and while the supervisor waits to restart it.
+	Running bool `json:"running"`
+	// PID is the current gs-netcat process, if known.
+	PID     int       `json:"pid,omitempty"`
+	Started time.Time `json:"started"`
+	// Since is when the current gs-netcat process was started, zero while
+	// there is none.
+	Since    time.Time `json:"since,omitempty"`
+	Restarts int       `json:"restarts"`
+	LastExit string    `json:"last_exit,omitempty"`
+}
+
+// Status reports whether the tunnel is up, starting, waiting to be
+// restarted, or dead because its supervisor is gone.
+func (s State) Status() string {
+	switch {
+	case !processAlive(s.SupervisorPID):
+		return StatusDead
+	case !s.Running && !s.Since.IsZero():
+		return StatusStarting
+	case !s.Running:
+		return StatusRestarting
+	}
+	return StatusUp
+}
+
+// Uptime returns how long the current gs-netcat process has been running.
+func (s State) Uptime() time.Duration {
+	if !s.Running || s.Since.IsZero() {
+		return 0
+	}
+	return time.Since(s.Since)
+}
+
+// Dir returns the directory holding tunnel state and logs.
+func Dir() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigFilePath), DirName)
+}
+
+// StatePath returns the state file of the tunnel for the connection called name.
+func StatePath(name string) string {
+	return filepath.Join(Dir(), fileName(name)+".json")
+}
+
+// LogPath returns the log file of the tunnel for the connection called name.
+func LogPath(name string) string {
+	return filepath.Join(Dir(), fileName(name)+".log")
+}
+
+// fileName makes a connection name safe to use as a file name.
+func fileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+	if safe == "" || safe == "." || safe == ".." {
+		safe = "_"
+	}
+	return safe
+}
+
+// Load returns the state of the tunnel for the connection called name, or
+// nil if it isn't running.
+func Load(name string) (*State, error) {
+	data, err := os.ReadFile(StatePath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// List returns the state of all tunnels, sorted by name.
+func List() ([]State, error) {
+	paths, err := filepath.Glob(filepath.Join(Dir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var states []State
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // Removed by its supervisor meanwhile.
+		}
+		var s State
+		if err := json.Unmarshal(data, &s); err != nil {
+			continue
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states, nil
+}
+
+// Save writes s to its state file.
+func Save(s State) error {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := StatePath(s.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Remove deletes the state file of the tunnel for the connection called name.
+func Remove(name string) error {
+	err := os.Remove(StatePath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Stop asks the supervisor of the tunnel for the connection called name to
+// shut the tunnel down and waits for it to do so. A tunnel whose supervisor
+// is already gone just has its state removed.
+func Stop(name string, timeout time.Duration) error {
+	s, err := Load(name)
+	if err != nil || s == nil {
+		return err
+	}
+	if processAlive(s.SupervisorPID) {
+		if err := terminate(s.SupervisorPID); err != nil {
+			return err
+		}
+		deadline := time.Now().Add(timeout)
+		for processAlive(s.SupervisorPID) && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if processAlive(s.SupervisorPID) {
+			return errors.New("supervisor did not stop in time")
+		}
+	}
+	// A supervisor that exits cleanly stops gs-netcat and removes its state;
+	// if the state is still there, gs-netcat may have been left behind.
+	if left, err := Load(name); err == nil && left != nil && left.Running && processAlive(left.PID) {
+		terminate(left.PID) //nolint:errcheck
+	}
+	return Remove(name)
+}
@@ -0,0 +1,139 @@
+package uptime
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// A connection is flapping when its status changed at least FlapThreshold
+// times within FlapWindow.
+const (
+	FlapThreshold = 4
+	FlapWindow    = time.Hour
+)
+
+// Outage is a stretch of consecutive checks that did not find the listener
+// online.
+type Outage struct {
+	Start time.Time `json:"start"`
+	// End is the time of the first online check after the outage, or of the
+	// last failed check if the outage is ongoing.
+	End     time.Time          `json:"end"`
+	Ongoing bool               `json:"ongoing,omitempty"`
+	Status  config.ProbeStatus `json:"status"`
+	Checks  int                `json:"checks"`
+}
+
+// Duration returns how long the outage lasted as far as checks can tell.
+func (o Outage) Duration() time.Duration {
+	return o.End.Sub(o.Start)
+}
+
+// Report summarises the history of one connection over a time range.
+type Report struct {
+	Name   string    `json:"name"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Checks int       `json:"checks"`
+	Online int       `json:"online"`
+	// Availability is the percentage of checks that found the listener
+	// online. It is only meaningful if Checks is not zero.
+	Availability float64            `json:"availability"`
+	Outages      []Outage           `json:"outages,omitempty"`
+	Flaps        int                `json:"flaps"`
+	Flapping     bool               `json:"flapping"`
+	LastStatus   config.ProbeStatus `json:"last_status,omitempty"`
+	LastCheck    *time.Time         `json:"last_check,omitempty"`
+}
+
+// Analyze builds the report of the samples between from and to. Outages and
+// flaps are only known for single checks; compacted buckets count towards
+// availability only.
+func Analyze(name string, samples []Sample, from, to time.Time) Report {
+	r := Report{Name: name, From: from, To: to}
+	var outage *Outage
+	var changes []time.Time
+	prevOnline, havePrev := false, false
+	for _, s := range samples {
+		if s.At.Before(from) || s.At.After(to) {
+			continue
+		}
+		r.Checks += s.Checks()
+		r.Online += s.Online()
+		if s.Compacted() {
+			continue
+		}
+
+		at := s.At
+		r.LastCheck = &at
+		r.LastStatus = s.Status
+		online := s.Status == config.ProbeOnline
+		if havePrev && online != prevOnline {
+			changes = append(changes, s.At)
+		}
+		prevOnline, havePrev = online, true
+
+		switch {
+		case !online && outage == nil:
+			outage = &Outage{Start: s.At, End: s.At, Status: s.Status, Checks: 1}
+		case !online:
+			outage.End = s.At
+			outage.Checks++
+		case outage != nil:
+			outage.End = s.At
+			r.Outages = append(r.Outages, *outage)
+			outage = nil
+		}
+	}
+	if outage != nil {
+		outage.Ongoing = true
+		r.Outages = append(r.Outages, *outage)
+	}
+	if r.Checks > 0 {
+		r.Availability = 100 * float64(r.Online) / float64(r.Checks)
+	}
+
+	r.Flaps = len(changes)
+	for i := range changes {
+		if i+FlapThreshold-1 < len(changes) && changes[i+FlapThreshold-1].Sub(changes[i]) <= FlapWindow {
+			r.Flapping = true
+			break
+		}
+	}
+	return r
+}
+
+// sparkLevels are the characters of a sparkline, from 0% to 100% online.
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders the availability between from and to as width
+// characters, one per equal slot. Slots without checks are shown as '·'.
+func Sparkline(samples []Sample, from, to time.Time, width int) string {
+	if width < 1 || !to.After(from) {
+		return ""
+	}
+	checks := make([]int, width)
+	online := make([]int, width)
+	slot := to.Sub(from) / time.Duration(width)
+	for _, s := range samples {
+		if s.At.Before(from) || !s.At.Before(to) {
+			continue
+		}
+		i := min(int(s.At.Sub(from)/slot), width-1)
+		checks[i] += s.Checks()
+		online[i] += s.Online()
+	}
+
+	var b strings.Builder
+	for i := range checks {
+		if checks[i] == 0 {
+			b.WriteRune('·')
+			continue
+		}
+		level := online[i] * (len(sparkLevels) - 1) / checks[i]
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
@@ -0,0 +1,289 @@
+// Package uptime keeps a history of liveness checks per connection and
+// derives availability reports from it. Each connection's history is a JSON
+// lines file under ~/.gsm/uptime. Recent checks are kept as they are; older
+// ones are compacted into hourly buckets and eventually dropped.
+package uptime
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+// DirName is the directory under the config dir holding uptime histories.
+const DirName = "uptime"
+
+// FileExt is the extension used for history files.
+const FileExt = ".jsonl"
+
+// Retention of samples.
+const (
+	// RawRetention is how long individual checks are kept before they are
+	// compacted into buckets.
+	RawRetention = 7 * 24 * time.Hour
+	// BucketSpan is the width of a compacted bucket.
+	BucketSpan = time.Hour
+	// MaxAge is how long any history is kept.
+	MaxAge = 90 * 24 * time.Hour
+)
+
+// Record compacts a history once it grew past compactSize or compactEvery
+// passed since its last compaction.
+const (
+	compactSize  = 256 * 1024
+	compactEvery = BucketSpan
+)
+
+// Sample is either a single check or, when Total is set, a compacted bucket
+// of Total checks starting at At and spanning Span, of which Up were online.
+type Sample struct {
+	At      time.Time          `json:"at"`
+	Status  config.ProbeStatus `json:"status,omitempty"`
+	Latency config.Duration    `json:"latency,omitempty"`
+	Span    config.Duration    `json:"span,omitempty"`
+	Up      int                `json:"up,omitempty"`
+	Total   int                `json:"total,omitempty"`
+}
+
+// Compacted reports whether s is a bucket rather than a single check.
+func (s Sample) Compacted() bool { return s.Total > 0 }
+
+// Checks returns the number of checks s stands for.
+func (s Sample) Checks() int {
+	if s.Compacted() {
+		return s.Total
+	}
+	return 1
+}
+
+// Online returns the number of checks in s that found the listener online.
+func (s Sample) Online() int {
+	if s.Compacted() {
+		return s.Up
+	}
+	if s.Status == config.ProbeOnline {
+		return 1
+	}
+	return 0
+}
+
+// End returns the end of the time s covers.
+func (s Sample) End() time.Time {
+	return s.At.Add(time.Duration(s.Span))
+}
+
+// Dir returns the directory where uptime histories are stored.
+func Dir() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigFilePath), DirName)
+}
+
+// Path returns the history file of the connection called name.
+func Path(name string) string {
+	return filepath.Join(Dir(), fileName(name)+FileExt)
+}
+
+// fileName makes a connection name safe to use as a file name.
+func fileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+	if safe == "" || safe == "." || safe == ".." {
+		safe = "_"
+	}
+	return safe
+}
+
+// lock takes an exclusive lock on the history of the connection called name,
+// held by writers so that a Compact can't drop a sample appended while it
+// rewrites the file. It returns the function releasing the lock.
+func lock(name string) (func(), error) {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(lockPath(name), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() { f.Close() }, nil
+}
+
+// lockPath returns the lock file of the history of the connection called
+// name. Its modification time is when the history was last compacted.
+func lockPath(name string) string {
+	return Path(name) + ".lock"
+}
+
+// Record appends a check of the connection called name to its history and
+// compacts the history when it is big enough or its last compaction is old
+// enough.
+func Record(name string, rec config.ProbeRecord) error {
+	if err := appendSample(name, Sample{At: rec.At, Status: rec.Status, Latency: rec.Latency}); err != nil {
+		return err
+	}
+	if !compactDue(name, time.Now()) {
+		return nil
+	}
+	return Compact(name, time.Now())
+}
+
+// compactDue reports whether Record should compact the history of the
+// connection called name.
+func compactDue(name string, now time.Time) bool {
+	if info, err := os.Stat(Path(name)); err == nil && info.Size() >= compactSize {
+		return true
+	}
+	info, err := os.Stat(lockPath(name))
+	return err != nil || now.Sub(info.ModTime()) >= compactEvery
+}
+
+func appendSample(name string, s Sample) error {
+	unlock, err := lock(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(Path(name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load returns the history of the connection called name, oldest first. A
+// connection without history has no samples and no error.
+func Load(name string) ([]Sample, error) {
+	f, err := os.Open(Path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []Sample
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var s Sample
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", Path(name), line, err)
+		}
+		samples = append(samples, s)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].At.Before(samples[j].At) })
+	return samples, nil
+}
+
+// Compact rewrites the history of the connection called name so that checks
+// older than RawRetention are merged into BucketSpan buckets and anything
+// older than MaxAge is dropped. It leaves the file alone if nothing is due.
+// The new history replaces the old one with a rename, under the lock Record
+// takes, so readers and writers never see a partial file.
+func Compact(name string, now time.Time) error {
+	unlock, err := lock(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	samples, err := Load(name)
+	if err != nil || len(samples) == 0 {
+		return err
+	}
+	stamp := time.Now()
+	os.Chtimes(lockPath(name), stamp, stamp) //nolint:errcheck // Only delays the next compaction.
+	compacted, changed := compact(samples, now)
+	if !changed {
+		return nil
+	}
+
+	path := Path(name)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, s := range compacted {
+		if err := enc.Encode(s); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// compact returns samples with old checks merged into buckets and expired
+// samples removed, and whether anything changed.
+func compact(samples []Sample, now time.Time) ([]Sample, bool) {
+	expiry := now.Add(-MaxAge)
+	rawCutoff := now.Add(-RawRetention).Truncate(BucketSpan)
+
+	var out []Sample
+	buckets := map[time.Time]int{} // bucket start -> index in out
+	changed := false
+	for _, s := range samples {
+		switch {
+		case s.End().Before(expiry):
+			changed = true
+		case s.Compacted():
+			buckets[s.At] = len(out)
+			out = append(out, s)
+		case !s.At.Before(rawCutoff):
+			out = append(out, s)
+		default:
+			start := s.At.Truncate(BucketSpan)
+			i, ok := buckets[start]
+			if !ok {
+				i = len(out)
+				buckets[start] = i
+				out = append(out, Sample{At: start, Span: config.Duration(BucketSpan)})
+			}
+			out[i].Total++
+			out[i].Up += s.Online()
+			changed = true
+		}
+	}
+	return out, changed
+}
@@ -0,0 +1,281 @@
+package uptime
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NumeXx/gsm/pkg/config"
+)
+
+func useDir(t *testing.T) {
+	t.Helper()
+	saved := config.DefaultConfigFilePath
+	t.Cleanup(func() { config.DefaultConfigFilePath = saved })
+	config.DefaultConfigFilePath = filepath.Join(t.TempDir(), "config.json")
+}
+
+func TestRecordConcurrentWithCompaction(t *testing.T) {
+	useDir(t)
+	now := time.Now()
+
+	// Old checks make Compact rewrite the file.
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(Path("box"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	for i := range 50 {
+		enc.Encode(Sample{At: now.Add(-8*24*time.Hour - time.Duration(i)*time.Minute), Status: config.ProbeOnline}) //nolint:errcheck
+	}
+	f.Close()
+
+	const records = 40
+	var wg sync.WaitGroup
+	for i := range records {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			at := now.Add(-time.Duration(i) * time.Second)
+			if i%2 == 1 {
+				// Due for compaction right away.
+				at = at.Add(-8 * 24 * time.Hour)
+			}
+			rec := config.ProbeRecord{At: at, Status: config.ProbeNoListener}
+			if err := Record("box", rec); err != nil {
+				t.Error(err)
+			}
+			if err := Compact("box", time.Now()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := Compact("box", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := Load("box")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, checks := 0, 0
+	for _, s := range samples {
+		if !s.Compacted() {
+			raw++
+		}
+		checks += s.Checks()
+	}
+	if raw != records/2 {
+		t.Errorf("kept %d recent checks, want %d", raw, records/2)
+	}
+	if checks != records+50 {
+		t.Errorf("history holds %d checks, want %d", checks, records+50)
+	}
+}
+
+// rawChecks returns the number of single checks in the history of name.
+func rawChecks(t *testing.T, name string) int {
+	t.Helper()
+	samples, err := Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, s := range samples {
+		if !s.Compacted() {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRecordCompactsWhenDue(t *testing.T) {
+	useDir(t)
+	old := config.ProbeRecord{At: time.Now().Add(-8 * 24 * time.Hour), Status: config.ProbeOnline}
+
+	// A new history was just created, so the old check stays as it is.
+	if err := Record("box", old); err != nil {
+		t.Fatal(err)
+	}
+	if n := rawChecks(t, "box"); n != 1 {
+		t.Fatalf("history has %d single checks, want the old one kept until compaction is due", n)
+	}
+
+	// Once the last compaction is old enough, the next Record compacts.
+	stale := time.Now().Add(-2 * compactEvery)
+	if err := os.Chtimes(lockPath("box"), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record("box", config.ProbeRecord{At: time.Now(), Status: config.ProbeOnline}); err != nil {
+		t.Fatal(err)
+	}
+	if n := rawChecks(t, "box"); n != 1 {
+		t.Fatalf("history has %d single checks, want only the recent one", n)
+	}
+	if compactDue("box", time.Now()) {
+		t.Error("compaction still due right after compacting")
+	}
+
+	// So does a history that grew too big in the meantime.
+	if err := Record("box", old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(Path("box"), 0); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(Path("box"), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	enc.Encode(Sample{At: old.At, Status: config.ProbeOnline}) //nolint:errcheck
+	for size := 0; size < compactSize; size += 40 {
+		enc.Encode(Sample{At: time.Now(), Status: config.ProbeOnline, Latency: config.Duration(time.Second)}) //nolint:errcheck
+	}
+	f.Close()
+	if !compactDue("box", time.Now()) {
+		t.Fatal("compaction not due for a big history")
+	}
+	before := rawChecks(t, "box")
+	if err := Record("box", config.ProbeRecord{At: time.Now(), Status: config.ProbeOnline}); err != nil {
+		t.Fatal(err)
+	}
+	if n := rawChecks(t, "box"); n != before {
+		t.Errorf("history has %d single checks, want %d (the old one compacted, one recorded)", n, before)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-8 * 24 * time.Hour).Truncate(BucketSpan)
+	samples := []Sample{
+		{At: now.Add(-100 * 24 * time.Hour), Status: config.ProbeOnline},
+		{At: old.Add(time.Minute), Status: config.ProbeOnline},
+		{At: old.Add(2 * time.Minute), Status: config.ProbeTimeout},
+		{At: now.Add(-time.Hour), Status: config.ProbeOnline},
+	}
+	out, changed := compact(samples, now)
+	if !changed {
+		t.Fatal("compact reported no change")
+	}
+	if len(out) != 2 {
+		t.Fatalf("compact returned %d samples, want 2: %+v", len(out), out)
+	}
+	if b := out[0]; !b.At.Equal(old) || b.Total != 2 || b.Up != 1 {
+		t.Errorf("bucket = %+v, want 2 checks with 1 online at %v", b, old)
+	}
+	if out[1].Compacted() {
+		t.Errorf("recent check was compacted: %+v", out[1])
+	}
+	if _, changed := compact(out, now); changed {
+		t.Error("compacting a compacted history changed it")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	at := func(d time.Duration) time.Time { return from.Add(d) }
+	samples := []Sample{
+		{At: at(-time.Hour), Status: config.ProbeTimeout},
+		{At: at(0), Span: config.Duration(BucketSpan), Total: 4, Up: 3},
+		{At: at(time.Hour), Status: config.ProbeOnline},
+		{At: at(2 * time.Hour), Status: config.ProbeNoListener},
+		{At: at(150 * time.Minute), Status: config.ProbeTimeout},
+		{At: at(3 * time.Hour), Status: config.ProbeOnline},
+		{At: at(4 * time.Hour), Status: config.ProbeTimeout},
+		{At: at(11 * time.Hour), Status: config.ProbeOnline},
+	}
+	r := Analyze("box", samples, from, to)
+
+	if r.Name != "box" || r.Checks != 9 || r.Online != 5 {
+		t.Errorf("report = %+v, want 9 checks with 5 online", r)
+	}
+	if want := 100 * 5.0 / 9; r.Availability != want {
+		t.Errorf("availability = %v, want %v", r.Availability, want)
+	}
+	wantOutages := []Outage{
+		{Start: at(2 * time.Hour), End: at(3 * time.Hour), Status: config.ProbeNoListener, Checks: 2},
+		{Start: at(4 * time.Hour), End: at(4 * time.Hour), Ongoing: true, Status: config.ProbeTimeout, Checks: 1},
+	}
+	if !slices.Equal(r.Outages, wantOutages) {
+		t.Errorf("outages = %+v, want %+v", r.Outages, wantOutages)
+	}
+	if r.Outages[0].Duration() != time.Hour {
+		t.Errorf("first outage lasted %v, want 1h", r.Outages[0].Duration())
+	}
+	if r.Flaps != 3 || r.Flapping {
+		t.Errorf("flaps = %d, flapping %v; want 3 and not flapping", r.Flaps, r.Flapping)
+	}
+	if r.LastCheck == nil || !r.LastCheck.Equal(at(4*time.Hour)) || r.LastStatus != config.ProbeTimeout {
+		t.Errorf("last check = %v (%s), want the timeout at 04:00", r.LastCheck, r.LastStatus)
+	}
+}
+
+func TestAnalyzeFlapping(t *testing.T) {
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	alternating := func(step time.Duration) []Sample {
+		var samples []Sample
+		for i := range FlapThreshold + 1 {
+			status := config.ProbeOnline
+			if i%2 == 1 {
+				status = config.ProbeNoListener
+			}
+			samples = append(samples, Sample{At: from.Add(time.Duration(i) * step), Status: status})
+		}
+		return samples
+	}
+	to := from.Add(24 * time.Hour)
+
+	if r := Analyze("box", alternating(10*time.Minute), from, to); r.Flaps != FlapThreshold || !r.Flapping {
+		t.Errorf("%d changes within %v: flaps = %d, flapping %v; want flapping", FlapThreshold, FlapWindow, r.Flaps, r.Flapping)
+	}
+	if r := Analyze("box", alternating(time.Hour), from, to); r.Flaps != FlapThreshold || r.Flapping {
+		t.Errorf("%d changes an hour apart: flaps = %d, flapping %v; want not flapping", FlapThreshold, r.Flaps, r.Flapping)
+	}
+	if r := Analyze("box", nil, from, to); r.Checks != 0 || r.Availability != 0 || r.Outages != nil || r.LastCheck != nil {
+		t.Errorf("report without checks = %+v, want it empty", r)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	to := from.Add(5 * time.Hour)
+	at := func(d time.Duration) time.Time { return from.Add(d) }
+	samples := []Sample{
+		{At: at(-time.Minute), Status: config.ProbeTimeout},
+		{At: at(0), Status: config.ProbeOnline},
+		{At: at(30 * time.Minute), Status: config.ProbeOnline},
+		{At: at(2 * time.Hour), Span: config.Duration(BucketSpan), Total: 4, Up: 1},
+		{At: at(3 * time.Hour), Span: config.Duration(BucketSpan), Total: 4, Up: 3},
+		{At: at(4*time.Hour + time.Minute), Status: config.ProbeNoListener},
+		{At: to, Status: config.ProbeOnline},
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		width    int
+		want     string
+	}{
+		{"one slot per hour", from, to, 5, "█·▂▆▁"},
+		{"slots merged", from, to, 1, "▄"},
+		{"no checks", to.Add(time.Hour), to.Add(2 * time.Hour), 3, "···"},
+		{"no width", from, to, 0, ""},
+		{"empty range", to, from, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(samples, tt.from, tt.to, tt.width); got != tt.want {
+				t.Errorf("Sparkline = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// keyLineName starts the name field of a key file line.
+const keyLineName = "name="
+
+// FormatKeyLine returns the key file line for a connection, the format
+// gsm import -f reads: KEY[#tag1,tag2] name=NAME. A name with spaces or
+// quotes is double-quoted.
+func FormatKeyLine(key string, tags []string, name string) string {
+	line := key
+	if len(tags) > 0 {
+		line += "#" + strings.Join(tags, ",")
+	}
+	if name == "" {
+		return line
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == '"' }) {
+		name = strconv.Quote(name)
+	}
+	return line + " " + keyLineName + name
+}
+
+// ParseKeyLine splits a key file line into its KEY[#tag1,tag2] part and the
+// name given with name=, if any. Anything else after the key is a comment.
+func ParseKeyLine(line string) (keyAndTags, name string) {
+	line = strings.TrimSpace(line)
+	end := strings.IndexAny(line, " \t")
+	if end == -1 {
+		return line, ""
+	}
+	keyAndTags = line[:end]
+	rest, ok := strings.CutPrefix(strings.TrimLeft(line[end:], " \t"), keyLineName)
+	if !ok {
+		return keyAndTags, ""
+	}
+	if strings.HasPrefix(rest, `"`) {
+		if quoted, err := strconv.QuotedPrefix(rest); err == nil {
+			name, _ = strconv.Unquote(quoted)
+			return keyAndTags, name
+		}
+	}
+	if end := strings.IndexAny(rest, " \t"); end != -1 {
+		rest = rest[:end]
+	}
+	return keyAndTags, rest
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKeyLineRoundTrip(t *testing.T) {
+	tests := []struct {
+		key  string
+		tags []string
+		name string
+	}{
+		{"RG9DNqW4WrbiIDlrYJawxj", nil, "GatherChickenHumor"},
+		{"RG9DNqW4WrbiIDlrYJawxj", []string{"prod", "web"}, "web-1"},
+		{"RG9DNqW4WrbiIDlrYJawxj", []string{"lab"}, `my "lab" box`},
+		{"RG9DNqW4WrbiIDlrYJawxj", nil, ""},
+	}
+	for _, tt := range tests {
+		line := FormatKeyLine(tt.key, tt.tags, tt.name)
+		keyAndTags, name := ParseKeyLine(line)
+		if name != tt.name {
+			t.Errorf("ParseKeyLine(%q) name = %q, want %q", line, name, tt.name)
+		}
+		key, tags, _ := strings.Cut(keyAndTags, "#")
+		if key != tt.key {
+			t.Errorf("ParseKeyLine(%q) key = %q, want %q", line, key, tt.key)
+		}
+		var gotTags []string
+		if tags != "" {
+			gotTags = strings.Split(tags, ",")
+		}
+		if !slices.Equal(gotTags, tt.tags) {
+			t.Errorf("ParseKeyLine(%q) tags = %q, want %q", line, gotTags, tt.tags)
+		}
+	}
+}
+
+func TestParseKeyLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		keyAndTags string
+		name       string
+	}{
+		{"KEY", "KEY", ""},
+		{"KEY#a,b", "KEY#a,b", ""},
+		{"KEY#a some comment", "KEY#a", ""},
+		{"KEY\tname=box trailing comment", "KEY", "box"},
+		{`KEY name="two words" comment`, "KEY", "two words"},
+		{"  KEY#x   name=box  ", "KEY#x", "box"},
+		{"KEY comment name=box", "KEY", ""},
+	}
+	for _, tt := range tests {
+		keyAndTags, name := ParseKeyLine(tt.line)
+		if keyAndTags != tt.keyAndTags || name != tt.name {
+			t.Errorf("ParseKeyLine(%q) = %q, %q, want %q, %q", tt.line, keyAndTags, name, tt.keyAndTags, tt.name)
+		}
+	}
+}
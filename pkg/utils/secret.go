@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// SecretLength is the length of the secrets gs-netcat -g generates.
+const SecretLength = 22
+
+const secretAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GenerateSecret returns a random GSocket secret in the same format as
+// 'gs-netcat -g': SecretLength characters of [a-zA-Z0-9] from crypto/rand.
+func GenerateSecret() (string, error) {
+	max := big.NewInt(int64(len(secretAlphabet)))
+	b := make([]byte, SecretLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = secretAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}